@@ -1,6 +1,7 @@
 package alerts
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -81,32 +82,31 @@ func ListAlerts(cmd *alertCmd) {
 	}
 }
 
-func getAlertLevel(clusterID, alertLevel string) {
-	var alerts []Alert
-
-	listAlertCmd := []string{"amtool", "--alertmanager.url", silence.LocalHostUrl, "alert", "-o", "json"}
+// listAlertCmd lists the alerts of the cluster in its alertmanager pod
+var listAlertCmd = []string{"amtool", "--alertmanager.url", silence.LocalHostUrl, "alert", "-o", "json"}
 
+// FiringAlerts returns the alerts currently firing on the cluster. Like amtool, it leaves out the alerts which
+// are silenced or inhibited. The command run in the alertmanager pod is stopped once the context is done.
+func FiringAlerts(ctx context.Context, clusterID string) ([]Alert, error) {
 	_, kubeconfig, clientset, err := common.GetKubeConfigAndClient(clusterID)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-
-	output, err := silence.ExecInPod(kubeconfig, clientset, listAlertCmd)
+	output, err := silence.ExecInPodWithContext(ctx, kubeconfig, clientset, listAlertCmd)
 	if err != nil {
-		fmt.Println(err)
+		return nil, err
 	}
-
-	outputSlice := []byte(output)
-
-	err = json.Unmarshal(outputSlice, &alerts)
-	if err != nil {
-		fmt.Println("Error in unmarshaling the labels", err)
-		return
+	var alerts []Alert
+	if err := json.Unmarshal([]byte(output), &alerts); err != nil {
+		return nil, fmt.Errorf("error in unmarshaling the alerts: %w", err)
 	}
+	return alerts, nil
+}
 
-	err = json.Unmarshal(outputSlice, &alerts)
+func getAlertLevel(clusterID, alertLevel string) {
+	alerts, err := FiringAlerts(context.Background(), clusterID)
 	if err != nil {
-		fmt.Println("Error in unmarshaling the labels", err)
+		fmt.Println(err)
 		return
 	}
 
@@ -1,9 +1,10 @@
 package silence
 
 import (
+	"bytes"
 	"context"
 	"fmt"
-	"github.com/openshift/osdctl/cmd/cluster"
+
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -19,6 +20,11 @@ const (
 )
 
 func ExecInPod(kubeconfig *rest.Config, clientset *kubernetes.Clientset, cmd []string) (string, error) {
+	return ExecInPodWithContext(context.TODO(), kubeconfig, clientset, cmd)
+}
+
+// ExecInPodWithContext runs the command like ExecInPod, giving up once the context is done
+func ExecInPodWithContext(ctx context.Context, kubeconfig *rest.Config, clientset *kubernetes.Clientset, cmd []string) (string, error) {
 
 	req := clientset.CoreV1().RESTClient().Post().Resource("pods").Name(PodName).
 		Namespace(AccountNamespace).SubResource("exec")
@@ -39,10 +45,10 @@ func ExecInPod(kubeconfig *rest.Config, clientset *kubernetes.Clientset, cmd []s
 		return "", fmt.Errorf("failed to create SPDY executor: %w", err)
 	}
 
-	capture := &cluster.LogCapture{}
-	errorCapture := &cluster.LogCapture{}
+	capture := &bytes.Buffer{}
+	errorCapture := &bytes.Buffer{}
 
-	err = exec.StreamWithContext(context.TODO(), remotecommand.StreamOptions{
+	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{
 		Stdin:  nil,
 		Stdout: capture,
 		Stderr: errorCapture,
@@ -53,6 +59,6 @@ func ExecInPod(kubeconfig *rest.Config, clientset *kubernetes.Clientset, cmd []s
 		return "", fmt.Errorf("failed to stream with context: %w", err)
 	}
 
-	cmdOutput := capture.GetStdOut()
+	cmdOutput := capture.String()
 	return cmdOutput, nil
 }
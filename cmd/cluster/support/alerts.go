@@ -0,0 +1,83 @@
+package support
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/openshift/osdctl/cmd/alerts"
+	"github.com/openshift/osdctl/pkg/printer"
+)
+
+// alertSeverities orders the alerts shown by --show-alerts, the most severe first
+var alertSeverities = []string{"critical", "warning", "info"}
+
+// severityRank is the position of the severity in alertSeverities, unknown severities come last
+func severityRank(severity string) int {
+	if i := slices.Index(alertSeverities, strings.ToLower(severity)); i >= 0 {
+		return i
+	}
+	return len(alertSeverities)
+}
+
+// alertsLookupTimeout bounds the lookup of the alerts, so that an unresponsive backplane doesn't hold the post
+var alertsLookupTimeout = 30 * time.Second
+
+// printFiringAlerts shows the alerts firing on the cluster before the post is confirmed, for context. The
+// alerts are only informative: when they cannot be looked up in time, the post goes on with a warning.
+func (p *Post) printFiringAlerts(out io.Writer, clusterID string) {
+	firingAlerts := p.firingAlerts
+	if firingAlerts == nil {
+		firingAlerts = alerts.FiringAlerts
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), alertsLookupTimeout)
+	defer cancel()
+	type lookup struct {
+		alerts []alerts.Alert
+		err    error
+	}
+	// The backplane login doesn't take a context, the lookup is left behind when it doesn't return in time
+	done := make(chan lookup, 1)
+	go func() {
+		firing, err := firingAlerts(ctx, clusterID)
+		done <- lookup{alerts: firing, err: err}
+	}()
+	var firing []alerts.Alert
+	select {
+	case result := <-done:
+		if result.err != nil {
+			logger().Warn("Cannot look up the alerts of the cluster, continuing without them", "cluster", clusterID, "error", result.err)
+			return
+		}
+		firing = result.alerts
+	case <-ctx.Done():
+		logger().Warn("Looking up the alerts of the cluster timed out, continuing without them", "cluster", clusterID, "timeout", alertsLookupTimeout)
+		return
+	}
+	if len(firing) == 0 {
+		fmt.Fprintf(out, "No alerts are firing on cluster %s\n", clusterID)
+		return
+	}
+
+	slices.SortStableFunc(firing, func(a, b alerts.Alert) int {
+		if rank := severityRank(a.Labels.Severity) - severityRank(b.Labels.Severity); rank != 0 {
+			return rank
+		}
+		return strings.Compare(a.Labels.Alertname, b.Labels.Alertname)
+	})
+	fmt.Fprintf(out, "%d alert(s) are firing on cluster %s:\n", len(firing), clusterID)
+	table := printer.NewTablePrinter(out, 20, 1, 3, ' ')
+	table.AddRow([]string{"Alert", "Severity", "State", "Summary"})
+	for _, alert := range firing {
+		table.AddRow([]string{alert.Labels.Alertname, alert.Labels.Severity, alert.Status.State, alert.Annotations.Summary})
+	}
+	// Add empty row for readability
+	table.AddRow([]string{})
+	if err := table.Flush(); err != nil {
+		logger().Warn("Cannot print the alerts of the cluster", "cluster", clusterID, "error", err)
+	}
+}
@@ -0,0 +1,69 @@
+package support
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/osdctl/cmd/alerts"
+)
+
+func Test_printFiringAlerts(t *testing.T) {
+	alert := func(name, severity string) alerts.Alert {
+		return alerts.Alert{Labels: alerts.Labels{Alertname: name, Severity: severity}, Status: alerts.Status{State: "active"}}
+	}
+	p := &Post{firingAlerts: func(ctx context.Context, clusterID string) ([]alerts.Alert, error) {
+		return []alerts.Alert{alert("Watchdog", "none"), alert("KubeNodeNotReady", "warning"), alert("ClusterOperatorDown", "critical")}, nil
+	}}
+	out := &bytes.Buffer{}
+	p.printFiringAlerts(out, "cluster-id")
+	if !strings.HasPrefix(out.String(), "3 alert(s) are firing on cluster cluster-id") {
+		t.Errorf("printFiringAlerts() = %q", out.String())
+	}
+	critical, warning, none := strings.Index(out.String(), "ClusterOperatorDown"), strings.Index(out.String(), "KubeNodeNotReady"), strings.Index(out.String(), "Watchdog")
+	if critical < 0 || !(critical < warning && warning < none) {
+		t.Errorf("printFiringAlerts() = %q, want the most severe alerts first", out.String())
+	}
+
+	p.firingAlerts = func(ctx context.Context, clusterID string) ([]alerts.Alert, error) { return nil, nil }
+	out.Reset()
+	p.printFiringAlerts(out, "cluster-id")
+	if out.String() != "No alerts are firing on cluster cluster-id\n" {
+		t.Errorf("printFiringAlerts() without alerts = %q", out.String())
+	}
+
+	// A failed lookup doesn't block the post
+	p.firingAlerts = func(ctx context.Context, clusterID string) ([]alerts.Alert, error) {
+		return nil, errors.New("backplane is unreachable")
+	}
+	out.Reset()
+	p.printFiringAlerts(out, "cluster-id")
+	if out.Len() != 0 {
+		t.Errorf("printFiringAlerts() with a failed lookup = %q, want only a warning", out.String())
+	}
+
+	// Neither does a lookup which hangs
+	defer func(timeout time.Duration) { alertsLookupTimeout = timeout }(alertsLookupTimeout)
+	alertsLookupTimeout = 10 * time.Millisecond
+	release := make(chan struct{})
+	defer close(release)
+	p.firingAlerts = func(ctx context.Context, clusterID string) ([]alerts.Alert, error) {
+		<-release
+		return []alerts.Alert{alert("Watchdog", "none")}, nil
+	}
+	out.Reset()
+	p.printFiringAlerts(out, "cluster-id")
+	if out.Len() != 0 {
+		t.Errorf("printFiringAlerts() with a timed out lookup = %q, want only a warning", out.String())
+	}
+}
+
+func Test_showAlertsWithDryRun(t *testing.T) {
+	p := &Post{Template: "template.json", showAlerts: true, isDryRun: true}
+	if err := p.check(); err == nil {
+		t.Errorf("check() expected an error for --show-alerts together with --dry-run")
+	}
+}
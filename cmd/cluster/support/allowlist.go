@@ -0,0 +1,95 @@
+package support
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/spf13/viper"
+)
+
+// ReasonAllowlistKey can be set in the osdctl configuration file to the path of a file listing the limited support
+// reasons that may be posted, one summary or limited support reason template ID per line
+const ReasonAllowlistKey = "support_reason_allowlist"
+
+// reasonAllowlist holds the permitted summaries and limited support reason template IDs
+type reasonAllowlist struct {
+	path    string
+	entries map[string]bool
+}
+
+// readReasonAllowlist reads an allowlist file. Empty lines and lines starting with '#' are ignored.
+func readReasonAllowlist(path string) (*reasonAllowlist, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the reason allowlist %s: %w", path, err)
+	}
+	defer file.Close()
+
+	return parseReasonAllowlist(path, file)
+}
+
+func parseReasonAllowlist(path string, r io.Reader) (*reasonAllowlist, error) {
+	allowlist := &reasonAllowlist{path: path, entries: map[string]bool{}}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		allowlist.entries[line] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read the reason allowlist %s: %w", path, err)
+	}
+	if len(allowlist.entries) == 0 {
+		return nil, fmt.Errorf("the reason allowlist %s doesn't permit any reason", path)
+	}
+	return allowlist, nil
+}
+
+// loadReasonAllowlist reads the reason allowlist of the osdctl configuration, or --reason-allowlist. It is nil
+// when none is set, which permits every reason.
+func loadReasonAllowlist() (*reasonAllowlist, error) {
+	path := viper.GetString(ReasonAllowlistKey)
+	if path == "" {
+		return nil, nil
+	}
+	return readReasonAllowlist(path)
+}
+
+// check returns an error unless the summary, or the template ID, of the reason is on the allowlist.
+// A nil allowlist permits every reason.
+func (a *reasonAllowlist) check(reason *cmv1.LimitedSupportReason) error {
+	if a == nil {
+		return nil
+	}
+	if a.entries[strings.TrimSpace(reason.Summary())] {
+		return nil
+	}
+	if templateID := reason.Template().ID(); templateID != "" && a.entries[templateID] {
+		return nil
+	}
+	return fmt.Errorf("the limited support reason summary %q is not on the reason allowlist %s", reason.Summary(), a.path)
+}
+
+// checkAllowlist enforces the reason allowlist of the osdctl configuration, or --reason-allowlist, when set.
+// The allowlist is read once, so it applies to every cluster posted to.
+func (p *Post) checkAllowlist(reason *cmv1.LimitedSupportReason) error {
+	path := viper.GetString(ReasonAllowlistKey)
+	if path == "" {
+		return nil
+	}
+	if p.allowlist == nil || p.allowlist.path != path {
+		allowlist, err := readReasonAllowlist(path)
+		if err != nil {
+			return err
+		}
+		p.allowlist = allowlist
+	}
+	return p.allowlist.check(reason)
+}
@@ -0,0 +1,83 @@
+package support
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/spf13/viper"
+)
+
+func Test_reasonAllowlist(t *testing.T) {
+	allowlist, err := parseReasonAllowlist("allowlist.txt", strings.NewReader(`
+# Permitted summaries
+Cluster is in Limited Support due to unsupported cluster configuration
+
+lsrt-1234
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		reason  *cmv1.LimitedSupportReasonBuilder
+		wantErr bool
+	}{
+		{
+			name:   "Permitted summary",
+			reason: cmv1.NewLimitedSupportReason().Summary(LimitedSupportSummaryCluster),
+		},
+		{
+			name:   "Permitted template ID",
+			reason: cmv1.NewLimitedSupportReason().Summary("Ad-hoc summary").Template(cmv1.NewLimitedSupportReasonTemplate().ID("lsrt-1234")),
+		},
+		{
+			name:    "Rejected summary",
+			reason:  cmv1.NewLimitedSupportReason().Summary(LimitedSupportSummaryCloud),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, err := tt.reason.Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = allowlist.check(reason)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), LimitedSupportSummaryCloud) {
+				t.Errorf("check() error = %v, should name the rejected summary", err)
+			}
+		})
+	}
+
+	if _, err := parseReasonAllowlist("empty.txt", strings.NewReader("# nothing\n")); err == nil {
+		t.Errorf("parseReasonAllowlist() expected an error for an allowlist without entries")
+	}
+}
+
+func Test_checkDesiredAllowlist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist.txt")
+	if err := os.WriteFile(path, []byte(LimitedSupportSummaryCluster+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	viper.Set(ReasonAllowlistKey, path)
+	defer viper.Set(ReasonAllowlistKey, "")
+
+	state := &desiredState{Clusters: []desiredCluster{
+		{ClusterID: "cluster-a", Reasons: []desiredReason{{Summary: LimitedSupportSummaryCluster, Details: "Details"}}},
+	}}
+	if err := checkDesiredAllowlist(state); err != nil {
+		t.Errorf("checkDesiredAllowlist() error = %v, want the permitted reason to pass", err)
+	}
+
+	state.Clusters = append(state.Clusters, desiredCluster{ClusterID: "cluster-b", Reasons: []desiredReason{{Summary: LimitedSupportSummaryCloud, Details: "Details"}}})
+	if err := checkDesiredAllowlist(state); err == nil || !strings.Contains(err.Error(), "cluster-b") {
+		t.Errorf("checkDesiredAllowlist() error = %v, want the rejected reason of cluster-b", err)
+	}
+}
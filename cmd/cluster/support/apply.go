@@ -0,0 +1,317 @@
+package support
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/openshift/osdctl/internal/utils/globalflags"
+	"github.com/openshift/osdctl/pkg/printer"
+	ctlutil "github.com/openshift/osdctl/pkg/utils"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"sigs.k8s.io/yaml"
+)
+
+type applyOptions struct {
+	file     string
+	isDryRun bool
+
+	genericclioptions.IOStreams
+	GlobalOptions *globalflags.GlobalOptions
+}
+
+// desiredState is the content of the file given to apply, it lists the exact set of reasons each cluster should have
+type desiredState struct {
+	Clusters []desiredCluster `json:"clusters"`
+}
+
+type desiredCluster struct {
+	ClusterID string          `json:"cluster_id"`
+	Reasons   []desiredReason `json:"reasons"`
+}
+
+type desiredReason struct {
+	Summary string `json:"summary"`
+	Details string `json:"details"`
+	// DetectionType defaults to manual
+	DetectionType cmv1.DetectionType `json:"detection_type,omitempty"`
+}
+
+// clusterPlan holds the changes needed for a cluster to converge to its desired reasons
+type clusterPlan struct {
+	cluster  *cmv1.Cluster
+	toPost   []*cmv1.LimitedSupportReason
+	toDelete []*cmv1.LimitedSupportReason
+}
+
+// newCmdapply implements the apply command to converge the limited support reasons of clusters to a declarative file
+func newCmdapply(streams genericclioptions.IOStreams, globalOpts *globalflags.GlobalOptions) *cobra.Command {
+	ops := newApplyOptions(streams, globalOpts)
+	applyCmd := &cobra.Command{
+		Use:   "apply -f FILE",
+		Short: "Post and delete limited support reasons so that clusters match a declarative file",
+		Long: `Reconciles the limited support reasons of clusters with a YAML or JSON file listing the exact set of reasons each cluster should have.
+Reasons are matched on their summary, details and detection type. Missing reasons are posted and the ones that are not listed are deleted.
+Clusters which are not listed in the file are left untouched, a cluster listed without reasons has all of its reasons deleted.`,
+		Example: `  # desired.yaml
+  clusters:
+  - cluster_id: 1a2B3c4DefghIjkLMNOpQrSTUV5
+    reasons:
+    - summary: Cluster is in Limited Support due to unsupported cloud provider configuration
+      details: The cluster's security group was modified. Restore the original rules.
+      detection_type: manual
+
+  # Show the changes needed to converge
+  osdctl cluster support apply -f desired.yaml --dry-run`,
+		Args:              cobra.NoArgs,
+		DisableAutoGenTag: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(ops.complete(cmd, args))
+			cmdutil.CheckErr(ops.run())
+		},
+	}
+
+	applyCmd.Flags().StringVarP(&ops.file, "filename", "f", "", "YAML or JSON file listing the desired limited support reasons of each cluster")
+	applyCmd.Flags().BoolVarP(&ops.isDryRun, "dry-run", "d", false, "Dry-run - print the changes needed to converge but don't make them.")
+	_ = applyCmd.MarkFlagRequired("filename")
+
+	return applyCmd
+}
+
+func newApplyOptions(streams genericclioptions.IOStreams, globalOpts *globalflags.GlobalOptions) *applyOptions {
+	return &applyOptions{
+		IOStreams:     streams,
+		GlobalOptions: globalOpts,
+	}
+}
+
+func (o *applyOptions) complete(cmd *cobra.Command, _ []string) error {
+	if o.file == "" {
+		return cmdutil.UsageErrorf(cmd, "Provide the desired state file with -f")
+	}
+	return nil
+}
+
+func (o *applyOptions) run() error {
+	state, err := readDesiredState(o.file)
+	if err != nil {
+		return err
+	}
+	if err := checkDesiredAllowlist(state); err != nil {
+		return err
+	}
+
+	//create connection to sdk
+	connection, err := createConnection()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := connection.Close(); err != nil {
+			fmt.Printf("Cannot close the connection: %q\n", err)
+			os.Exit(1)
+		}
+	}()
+
+	plans := make([]clusterPlan, 0, len(state.Clusters))
+	for _, desired := range state.Clusters {
+		plan, err := planCluster(connection, desired)
+		if err != nil {
+			return fmt.Errorf("cluster %s: %w", desired.ClusterID, err)
+		}
+		plans = append(plans, plan)
+	}
+
+	// Nothing to do, don't prompt so that scheduled runs stay quiet in steady state
+	if plansEmpty(plans) {
+		fmt.Println("already converged")
+		return nil
+	}
+
+	if err := printPlans(plans); err != nil {
+		return err
+	}
+
+	// Stop here if dry-run
+	if o.isDryRun {
+		return nil
+	}
+
+	if ok, err := confirm(); !ok {
+		return err
+	}
+
+	return applyPlans(connection, plans)
+}
+
+// readDesiredState parses and validates the desired state file
+func readDesiredState(path string) (*desiredState, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read file %s: %w", path, err)
+	}
+
+	state := &desiredState{}
+	if err := yaml.UnmarshalStrict(data, state); err != nil {
+		return nil, fmt.Errorf("cannot parse file %s: %w", path, err)
+	}
+
+	seen := map[string]bool{}
+	for i, cluster := range state.Clusters {
+		// Check that the cluster key (name, identifier or external identifier) given by the user
+		// is reasonably safe so that there is no risk of SQL injection
+		if err := ctlutil.IsValidClusterKey(cluster.ClusterID); err != nil {
+			return nil, fmt.Errorf("%s: clusters[%d]: %w", path, i, err)
+		}
+		if seen[cluster.ClusterID] {
+			return nil, fmt.Errorf("%s: cluster %s is listed more than once", path, cluster.ClusterID)
+		}
+		seen[cluster.ClusterID] = true
+
+		for j, reason := range cluster.Reasons {
+			if reason.Summary == "" || reason.Details == "" {
+				return nil, fmt.Errorf("%s: clusters[%d].reasons[%d]: summary and details are required", path, i, j)
+			}
+		}
+	}
+	return state, nil
+}
+
+// checkDesiredAllowlist enforces the reason allowlist, if any, on every desired reason, before anything is posted
+func checkDesiredAllowlist(state *desiredState) error {
+	allowlist, err := loadReasonAllowlist()
+	if err != nil {
+		return err
+	}
+	for _, cluster := range state.Clusters {
+		for _, r := range cluster.Reasons {
+			reason, err := r.limitedSupportReason()
+			if err != nil {
+				return fmt.Errorf("cluster %s: %w", cluster.ClusterID, err)
+			}
+			if err := allowlist.check(reason); err != nil {
+				return fmt.Errorf("cluster %s: %w", cluster.ClusterID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r desiredReason) limitedSupportReason() (*cmv1.LimitedSupportReason, error) {
+	detectionType := r.DetectionType
+	if detectionType == "" {
+		detectionType = cmv1.DetectionTypeManual
+	}
+	return cmv1.NewLimitedSupportReason().Summary(r.Summary).Details(r.Details).DetectionType(detectionType).Build()
+}
+
+// planCluster compares the current reasons of the cluster with the desired ones
+func planCluster(connection *sdk.Connection, desired desiredCluster) (clusterPlan, error) {
+	cluster, current, err := listLimitedSupportReasons(connection, desired.ClusterID)
+	if err != nil {
+		return clusterPlan{}, err
+	}
+
+	reasons := make([]*cmv1.LimitedSupportReason, 0, len(desired.Reasons))
+	for _, r := range desired.Reasons {
+		reason, err := r.limitedSupportReason()
+		if err != nil {
+			return clusterPlan{}, err
+		}
+		reasons = append(reasons, reason)
+	}
+
+	toPost, toDelete := diffLimitedSupportReasons(current, reasons)
+	return clusterPlan{cluster: cluster, toPost: toPost, toDelete: toDelete}, nil
+}
+
+// diffLimitedSupportReasons returns the desired reasons which are missing from current, and the current
+// reasons which are not desired. Reasons are matched on their summary, details and detection type, and each current
+// reason can only satisfy one desired reason, so duplicates are reconciled too.
+func diffLimitedSupportReasons(current, desired []*cmv1.LimitedSupportReason) (toPost, toDelete []*cmv1.LimitedSupportReason) {
+	key := func(reason *cmv1.LimitedSupportReason) string {
+		return reason.Summary() + "\x00" + reason.Details() + "\x00" + string(reason.DetectionType())
+	}
+
+	unmatched := map[string][]*cmv1.LimitedSupportReason{}
+	for _, reason := range current {
+		unmatched[key(reason)] = append(unmatched[key(reason)], reason)
+	}
+
+	for _, reason := range desired {
+		if existing := unmatched[key(reason)]; len(existing) > 0 {
+			unmatched[key(reason)] = existing[1:]
+			continue
+		}
+		toPost = append(toPost, reason)
+	}
+
+	// Keep the order of current so the plan is stable
+	for _, reason := range current {
+		if existing := unmatched[key(reason)]; len(existing) > 0 && existing[0] == reason {
+			unmatched[key(reason)] = existing[1:]
+			toDelete = append(toDelete, reason)
+		}
+	}
+	return toPost, toDelete
+}
+
+func plansEmpty(plans []clusterPlan) bool {
+	for _, plan := range plans {
+		if len(plan.toPost) > 0 || len(plan.toDelete) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func printPlans(plans []clusterPlan) error {
+	table := printer.NewTablePrinter(os.Stdout, 20, 1, 3, ' ')
+	table.AddRow([]string{"Cluster ID", "Action", "Reason ID", "Summary"})
+	for _, plan := range plans {
+		for _, reason := range plan.toPost {
+			table.AddRow([]string{plan.cluster.ID(), "post", "", reason.Summary()})
+		}
+		for _, reason := range plan.toDelete {
+			table.AddRow([]string{plan.cluster.ID(), "delete", reason.ID(), reason.Summary()})
+		}
+	}
+	// Add empty row for readability
+	table.AddRow([]string{})
+	return table.Flush()
+}
+
+// applyPlans posts the missing reasons before deleting the extra ones, so a cluster which should stay in
+// limited support never leaves it. Failures are reported and the remaining changes are still applied.
+func applyPlans(connection *sdk.Connection, plans []clusterPlan) error {
+	var failed, total int
+	for _, plan := range plans {
+		for _, reason := range plan.toPost {
+			total++
+			response, err := sendLimitedSupportPostRequest(context.TODO(), connection, plan.cluster.ID(), reason)
+			if err != nil {
+				failed++
+				logger().Error("Failed to post reason", "summary", reason.Summary(), "cluster", plan.cluster.ID(), "error", err)
+				continue
+			}
+			fmt.Printf("Posted reason %s to %s\n", response.Body().ID(), plan.cluster.ID())
+			(&Post{}).recordHistory(plan.cluster.ID(), response.Body())
+		}
+		for _, reason := range plan.toDelete {
+			total++
+			if err := deleteLimitedSupportReason(connection, plan.cluster, reason.ID()); err != nil {
+				failed++
+				logger().Error("Failed to delete reason", "reason_id", reason.ID(), "cluster", plan.cluster.ID(), "error", err)
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to apply %d of %d change(s)", failed, total)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package support
+
+import (
+	"testing"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+)
+
+func Test_diffLimitedSupportReasons(t *testing.T) {
+	reason := func(id, summary string) *cmv1.LimitedSupportReason {
+		r, err := cmv1.NewLimitedSupportReason().ID(id).Summary(summary).Details(summary + " details").Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	withDetectionType := func(r *cmv1.LimitedSupportReason, detectionType cmv1.DetectionType) *cmv1.LimitedSupportReason {
+		r, err := cmv1.NewLimitedSupportReason().Copy(r).DetectionType(detectionType).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	tests := []struct {
+		name         string
+		current      []*cmv1.LimitedSupportReason
+		desired      []*cmv1.LimitedSupportReason
+		wantPost     []string
+		wantDeleteID []string
+	}{
+		{
+			name:    "Converged",
+			current: []*cmv1.LimitedSupportReason{reason("1", "a")},
+			desired: []*cmv1.LimitedSupportReason{reason("", "a")},
+		},
+		{
+			name:     "Missing reason",
+			current:  []*cmv1.LimitedSupportReason{reason("1", "a")},
+			desired:  []*cmv1.LimitedSupportReason{reason("", "a"), reason("", "b")},
+			wantPost: []string{"b"},
+		},
+		{
+			name:         "Extra reason",
+			current:      []*cmv1.LimitedSupportReason{reason("1", "a"), reason("2", "b")},
+			desired:      []*cmv1.LimitedSupportReason{reason("", "b")},
+			wantDeleteID: []string{"1"},
+		},
+		{
+			name:         "Duplicate reason",
+			current:      []*cmv1.LimitedSupportReason{reason("1", "a"), reason("2", "a")},
+			desired:      []*cmv1.LimitedSupportReason{reason("", "a")},
+			wantDeleteID: []string{"2"},
+		},
+		{
+			name:         "Different detection type",
+			current:      []*cmv1.LimitedSupportReason{withDetectionType(reason("1", "a"), cmv1.DetectionTypeAuto)},
+			desired:      []*cmv1.LimitedSupportReason{withDetectionType(reason("", "a"), cmv1.DetectionTypeManual)},
+			wantPost:     []string{"a"},
+			wantDeleteID: []string{"1"},
+		},
+		{
+			name:         "No desired reasons",
+			current:      []*cmv1.LimitedSupportReason{reason("1", "a")},
+			wantDeleteID: []string{"1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toPost, toDelete := diffLimitedSupportReasons(tt.current, tt.desired)
+			if len(toPost) != len(tt.wantPost) {
+				t.Fatalf("expected %d reason(s) to post, got %d", len(tt.wantPost), len(toPost))
+			}
+			for i, r := range toPost {
+				if r.Summary() != tt.wantPost[i] {
+					t.Errorf("expected to post %q, got %q", tt.wantPost[i], r.Summary())
+				}
+			}
+			if len(toDelete) != len(tt.wantDeleteID) {
+				t.Fatalf("expected %d reason(s) to delete, got %d", len(tt.wantDeleteID), len(toDelete))
+			}
+			for i, r := range toDelete {
+				if r.ID() != tt.wantDeleteID[i] {
+					t.Errorf("expected to delete %q, got %q", tt.wantDeleteID[i], r.ID())
+				}
+			}
+		})
+	}
+}
+
+func Test_plansEmpty(t *testing.T) {
+	reason, err := cmv1.NewLimitedSupportReason().Summary("a").Details("a details").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !plansEmpty(nil) || !plansEmpty([]clusterPlan{{}, {}}) {
+		t.Errorf("expected plans without changes to be empty")
+	}
+	if plansEmpty([]clusterPlan{{}, {toDelete: []*cmv1.LimitedSupportReason{reason}}}) {
+		t.Errorf("expected a plan deleting a reason not to be empty")
+	}
+}
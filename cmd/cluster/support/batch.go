@@ -0,0 +1,888 @@
+package support
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/openshift/osdctl/pkg/printer"
+	ctlutil "github.com/openshift/osdctl/pkg/utils"
+	"github.com/spf13/viper"
+)
+
+// clusterEntry is a single cluster of a batch, along with the parameters specific to it
+type clusterEntry struct {
+	ClusterID string            `json:"cluster_id"`
+	Params    map[string]string `json:"params"`
+}
+
+// clusterSource yields the clusters of a batch one at a time.
+// next returns io.EOF once all clusters were returned, any other error only affects the current entry.
+type clusterSource interface {
+	next() (*clusterEntry, error)
+	// position describes the entry last returned by next, to be used in error messages
+	position() string
+	// description describes the whole batch, to be used in the confirmation prompt
+	description() string
+}
+
+// jsonlSource reads the clusters from a JSON Lines file, one clusterEntry per line
+type jsonlSource struct {
+	path       string
+	scanner    *bufio.Scanner
+	lineNumber int
+	// trusted skips the cluster key safety check, see --trusted-input
+	trusted bool
+}
+
+func newJSONLSource(path string, r io.Reader) *jsonlSource {
+	return &jsonlSource{path: path, scanner: bufio.NewScanner(r)}
+}
+
+func (s *jsonlSource) next() (*clusterEntry, error) {
+	for s.scanner.Scan() {
+		s.lineNumber++
+		line := strings.TrimSpace(s.scanner.Text())
+		if line == "" {
+			continue
+		}
+		return parseClusterEntry(line, s.trusted)
+	}
+	if err := s.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read file %s: %w", s.path, err)
+	}
+	return nil, io.EOF
+}
+
+func (s *jsonlSource) position() string {
+	return fmt.Sprintf("%s:%d", s.path, s.lineNumber)
+}
+
+func (s *jsonlSource) description() string {
+	return fmt.Sprintf("every cluster listed in %s", s.path)
+}
+
+// idsFileSource reads the clusters from a text file, one 'CLUSTER_ID [KEY=VALUE ...]' line per cluster.
+// Empty lines and lines starting with '#' are ignored. It is the parser of every --cluster-ids-file and of
+// the cluster keys read from stdin, see readClusterIDs.
+type idsFileSource struct {
+	path       string
+	scanner    *bufio.Scanner
+	lineNumber int
+	// trusted skips the cluster key safety check, see --trusted-input
+	trusted bool
+}
+
+func newIDsFileSource(path string, r io.Reader) *idsFileSource {
+	return &idsFileSource{path: path, scanner: bufio.NewScanner(r)}
+}
+
+func (s *idsFileSource) next() (*clusterEntry, error) {
+	for s.scanner.Scan() {
+		s.lineNumber++
+		line := strings.TrimSpace(s.scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return parseClusterIDsLine(line, s.trusted)
+	}
+	if err := s.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read %s: %w", s.path, err)
+	}
+	return nil, io.EOF
+}
+
+func (s *idsFileSource) position() string {
+	return fmt.Sprintf("%s:%d", s.path, s.lineNumber)
+}
+
+func (s *idsFileSource) description() string {
+	return fmt.Sprintf("every cluster listed in %s", s.path)
+}
+
+// parseClusterIDsLine parses and validates one line of a cluster IDs file: the cluster key, optionally
+// followed by whitespace separated KEY=VALUE parameters specific to the cluster. Values cannot contain spaces.
+func parseClusterIDsLine(line string, trusted bool) (*clusterEntry, error) {
+	fields := strings.Fields(line)
+	entry := &clusterEntry{ClusterID: fields[0]}
+	if err := checkClusterKey(entry.ClusterID, trusted); err != nil {
+		return nil, err
+	}
+	for _, field := range fields[1:] {
+		key, value, found := strings.Cut(field, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("wrong syntax of parameter %q, use KEY=VALUE", field)
+		}
+		if entry.Params == nil {
+			entry.Params = map[string]string{}
+		}
+		entry.Params[key] = value
+	}
+	return entry, nil
+}
+
+// sliceSource yields clusters which were resolved beforehand, eg. from an OCM query
+type sliceSource struct {
+	origin  string
+	entries []*clusterEntry
+	index   int
+}
+
+func (s *sliceSource) next() (*clusterEntry, error) {
+	if s.index >= len(s.entries) {
+		return nil, io.EOF
+	}
+	s.index++
+	return s.entries[s.index-1], nil
+}
+
+func (s *sliceSource) position() string {
+	return s.entries[s.index-1].ClusterID
+}
+
+func (s *sliceSource) description() string {
+	return fmt.Sprintf("the %d cluster(s) %s", len(s.entries), s.origin)
+}
+
+// multiSource yields the clusters of several sources one after the other, eg. the clusters read from stdin
+// followed by those of --cluster-ids-file
+type multiSource struct {
+	sources []clusterSource
+	index   int
+}
+
+// combineSources returns a source yielding the clusters of every source, in order
+func combineSources(sources []clusterSource) clusterSource {
+	if len(sources) == 1 {
+		return sources[0]
+	}
+	return &multiSource{sources: sources}
+}
+
+func (s *multiSource) next() (*clusterEntry, error) {
+	for s.index < len(s.sources) {
+		entry, err := s.sources[s.index].next()
+		if errors.Is(err, io.EOF) {
+			s.index++
+			continue
+		}
+		return entry, err
+	}
+	return nil, io.EOF
+}
+
+func (s *multiSource) position() string {
+	return s.sources[min(s.index, len(s.sources)-1)].position()
+}
+
+func (s *multiSource) description() string {
+	descriptions := make([]string, 0, len(s.sources))
+	for _, source := range s.sources {
+		descriptions = append(descriptions, source.description())
+	}
+	return strings.Join(descriptions, " and ")
+}
+
+// errDuplicateCluster is wrapped by the errors of the clusters which were already posted to under another key
+var errDuplicateCluster = errors.New("the cluster was already selected")
+
+// dedupSource skips the clusters already returned by the wrapped source, so that combining sources
+// or listing a cluster twice doesn't post twice. Clusters are compared by the key given by the user,
+// the first entry of a cluster wins. A cluster can also be listed by its name, internal ID and external ID:
+// those are only known to be the same cluster once resolved, which the batch checks with resolved.
+type dedupSource struct {
+	clusterSource
+	seen map[string]bool
+	// clusterKeys are the keys of the resolved clusters, by cluster ID
+	clusterKeys map[string]string
+	duplicates  int
+}
+
+func newDedupSource(source clusterSource) *dedupSource {
+	return &dedupSource{clusterSource: source, seen: map[string]bool{}, clusterKeys: map[string]string{}}
+}
+
+// resolved records that the key identifies the cluster with the ID. It fails with errDuplicateCluster when the
+// cluster was already selected under another key, whose entry is the one kept.
+func (s *dedupSource) resolved(key string, clusterID string) error {
+	if first, ok := s.clusterKeys[clusterID]; ok && first != key {
+		s.duplicates++
+		return fmt.Errorf("%w as %s", errDuplicateCluster, first)
+	}
+	s.clusterKeys[clusterID] = key
+	return nil
+}
+
+func (s *dedupSource) next() (*clusterEntry, error) {
+	for {
+		entry, err := s.clusterSource.next()
+		if err != nil {
+			return entry, err
+		}
+		if s.seen[entry.ClusterID] {
+			s.duplicates++
+			continue
+		}
+		s.seen[entry.ClusterID] = true
+		return entry, nil
+	}
+}
+
+// batchReadAhead is the number of entries read before a batch is confirmed, to count and preview its clusters.
+// The entries past them are streamed from the source once the batch is confirmed.
+const batchReadAhead = 500
+
+// bufferedSource holds the first entries of the wrapped source, read before the batch is confirmed so that the
+// prompt counts the clusters which are actually posted to, once duplicates and the clusters already done are skipped.
+// The entries which failed to be read are kept, to be reported at their position during the batch.
+// Once they are used up, the entries are read from the wrapped source.
+type bufferedSource struct {
+	clusterSource
+	origin   string
+	items    []bufferedItem
+	clusters int
+	// more is true when the wrapped source has entries past the buffered ones
+	more      bool
+	index     int
+	streaming bool
+}
+
+type bufferedItem struct {
+	entry    *clusterEntry
+	err      error
+	position string
+}
+
+// bufferSource reads up to limit entries of the source
+func bufferSource(source clusterSource, limit int) *bufferedSource {
+	buffered := &bufferedSource{clusterSource: source, origin: source.description()}
+	for len(buffered.items) < limit {
+		entry, err := source.next()
+		if errors.Is(err, io.EOF) {
+			return buffered
+		}
+		if err == nil {
+			buffered.clusters++
+		}
+		buffered.items = append(buffered.items, bufferedItem{entry: entry, err: err, position: source.position()})
+	}
+	// The entries past the limit aren't counted, the batch is only known to have at least that many clusters
+	buffered.more = true
+	return buffered
+}
+
+// clusterKeys returns the keys of the clusters of the batch
+func (s *bufferedSource) clusterKeys() []string {
+	keys := make([]string, 0, s.clusters)
+	for _, item := range s.items {
+		if item.err == nil {
+			keys = append(keys, item.entry.ClusterID)
+		}
+	}
+	return keys
+}
+
+// exclude removes the clusters with the keys from the batch
+func (s *bufferedSource) exclude(keys map[string]bool) {
+	remaining := s.items[:0]
+	for _, item := range s.items {
+		if item.err == nil && keys[item.entry.ClusterID] {
+			s.clusters--
+			continue
+		}
+		remaining = append(remaining, item)
+	}
+	s.items = remaining
+}
+
+func (s *bufferedSource) next() (*clusterEntry, error) {
+	if s.index >= len(s.items) {
+		if !s.more {
+			return nil, io.EOF
+		}
+		s.streaming = true
+		return s.clusterSource.next()
+	}
+	s.index++
+	item := s.items[s.index-1]
+	return item.entry, item.err
+}
+
+func (s *bufferedSource) position() string {
+	if s.streaming {
+		return s.clusterSource.position()
+	}
+	return s.items[s.index-1].position
+}
+
+func (s *bufferedSource) description() string {
+	if s.more {
+		return fmt.Sprintf("the %d+ cluster(s) to post to out of %s", s.clusters, s.origin)
+	}
+	return fmt.Sprintf("the %d cluster(s) to post to out of %s", s.clusters, s.origin)
+}
+
+// clusterCount returns the number of clusters a batch is confirmed for, zero when the source doesn't know it
+func clusterCount(source clusterSource) int {
+	switch s := source.(type) {
+	case *bufferedSource:
+		if s.more {
+			return 0
+		}
+		return s.clusters
+	case *sliceSource:
+		return len(s.entries)
+	case *jobSource:
+		return s.job.remaining()
+	}
+	return 0
+}
+
+// parseClusterEntry parses and validates one line of a JSON Lines clusters file
+func parseClusterEntry(line string, trusted bool) (*clusterEntry, error) {
+	var entry clusterEntry
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		return nil, fmt.Errorf("cannot parse line: %w", err)
+	}
+	if entry.ClusterID == "" {
+		return nil, errors.New("missing 'cluster_id'")
+	}
+	if err := checkClusterKey(entry.ClusterID, trusted); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// checkClusterKey checks that the cluster key (name, identifier or external identifier) given by the user
+// is reasonably safe so that there is no risk of SQL injection.
+// SECURITY: trusted keys, from --trusted-input, skip ctlutil.IsValidClusterKey. As they are still embedded
+// in quoted OCM search values, quotes and backslashes are always rejected.
+func checkClusterKey(clusterKey string, trusted bool) error {
+	if !trusted {
+		return ctlutil.IsValidClusterKey(clusterKey)
+	}
+	if clusterKey == "" || strings.ContainsAny(clusterKey, `'"\`) {
+		return fmt.Errorf("cluster key %q isn't valid: even with --trusted-input it must not be empty or contain quotes or backslashes", clusterKey)
+	}
+	return nil
+}
+
+// RunBatch posts to every cluster selected by --clusters-jsonl, --cluster-ids-file, --subscription-search, --version-range or read from stdin.
+// A failing cluster is reported and does not stop the remaining ones.
+func (p *Post) RunBatch() error {
+	if err := p.Init(); err != nil {
+		return err
+	}
+
+	if p.Template == "" {
+		return errors.New("posting to several clusters requires a template given with '-t'")
+	}
+	if p.thenList {
+		return errors.New("--then-list can only be used when posting to a single cluster")
+	}
+	if p.showAlerts {
+		return errors.New("--show-alerts can only be used when posting to a single cluster")
+	}
+	if p.upsertReasonID != "" {
+		return errors.New("--upsert identifies the reason of a single cluster, it cannot be used when posting to several clusters")
+	}
+	if p.trustedInput {
+		if p.ClustersJSONL == "" && p.ClusterIDsFile == "" {
+			return errors.New("--trusted-input can only be used together with --clusters-jsonl or --cluster-ids-file")
+		}
+		logger().Warn("--trusted-input is set, the cluster keys of the file are not checked for injection")
+	}
+	if err := p.check(); err != nil {
+		return err
+	}
+	if p.retryBudget > 0 {
+		p.retries = &retryBudget{remaining: p.retryBudget}
+	}
+	selectors := 0
+	for _, selector := range []string{p.ClustersJSONL, p.ClusterIDsFile, p.SubscriptionSearch, p.VersionRange} {
+		if selector != "" {
+			selectors++
+		}
+	}
+	if p.JobFile != "" {
+		job, err := loadBatchJob(p.JobFile)
+		if err != nil {
+			return err
+		}
+		if job != nil {
+			if selectors > 0 || len(p.clusterIDs) > 0 {
+				return fmt.Errorf("the job %s already lists its clusters, resume it without selecting clusters", p.JobFile)
+			}
+			if job.Template != p.Template {
+				return fmt.Errorf("the job %s posts the template %s, resume it with the same template", p.JobFile, job.Template)
+			}
+			if job.Done {
+				logger().Info("The job is already done", "job", p.JobFile)
+				return nil
+			}
+			if err := job.checkNotRunning(p.JobFile); err != nil {
+				return err
+			}
+			p.job = job
+		}
+	}
+
+	// A plain dry-run only renders the template, there is no need to talk to OCM unless clusters are
+	// selected through an OCM query
+	var connection *sdk.Connection
+	if !p.isDryRun || p.checkCluster || p.summaryByOrg || p.paramFromAWS || p.paramFromLabels || p.validateSchema || p.onlyIfHealthy || p.excludeDeleting || p.emitOCMCommands || p.expectProvider != "" || p.SubscriptionSearch != "" || p.VersionRange != "" {
+		var err error
+		connection, err = createConnection()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err = connection.Close(); err != nil {
+				logger().Error("Cannot close the connection", "error", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	if p.job != nil {
+		logger().Info("Resuming the job", "job", p.JobFile, "remaining", p.job.remaining(), "clusters", len(p.job.Clusters))
+		return p.runBatch(connection, p.job.source(p.JobFile))
+	}
+
+	// The sources can be combined, their clusters are posted to in this order and the duplicates are collapsed
+	var sources []clusterSource
+	if len(p.clusterIDs) > 0 {
+		source := &sliceSource{origin: "read from stdin"}
+		for _, clusterID := range p.clusterIDs {
+			source.entries = append(source.entries, &clusterEntry{ClusterID: clusterID})
+		}
+		sources = append(sources, source)
+	}
+
+	if p.ClusterIDsFile != "" {
+		file, err := os.Open(filepath.Clean(p.ClusterIDsFile))
+		if err != nil {
+			return fmt.Errorf("cannot read file %s: %w", p.ClusterIDsFile, err)
+		}
+		defer file.Close()
+		source := newIDsFileSource(p.ClusterIDsFile, file)
+		source.trusted = p.trustedInput
+		sources = append(sources, source)
+	}
+
+	if p.ClustersJSONL != "" {
+		file, err := os.Open(filepath.Clean(p.ClustersJSONL))
+		if err != nil {
+			return fmt.Errorf("cannot read file %s: %w", p.ClustersJSONL, err)
+		}
+		defer file.Close()
+		source := newJSONLSource(p.ClustersJSONL, file)
+		source.trusted = p.trustedInput
+		sources = append(sources, source)
+	}
+
+	if p.SubscriptionSearch != "" {
+		source, err := p.subscriptionSource(connection)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, source)
+	}
+
+	if p.VersionRange != "" {
+		source, err := p.versionRangeSource(connection)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, source)
+	}
+
+	if len(sources) == 0 {
+		return errors.New("no clusters were selected")
+	}
+	return p.runBatch(connection, combineSources(sources))
+}
+
+// runBatch renders and posts the template to every cluster of the source
+func (p *Post) runBatch(connection *sdk.Connection, batch clusterSource) error {
+	deduped := newDedupSource(batch)
+	var source clusterSource = deduped
+	var resumed *stateSource
+	if p.StateFile != "" {
+		if p.state == nil {
+			state, err := loadBatchState(p.StateFile)
+			if err != nil {
+				return err
+			}
+			p.state = state
+		}
+		resumed = &stateSource{clusterSource: source, state: p.state}
+		source = resumed
+	}
+	// A job already lists the clusters it has left, and tracks the one being posted to while they are read.
+	// Its clusters were previewed when it was created. The other batches are streamed, only the clusters
+	// counted by the confirmation prompt are read ahead.
+	if p.job == nil && !p.isDryRun {
+		buffered := bufferSource(source, batchReadAhead)
+		// A plain dry-run doesn't talk to OCM
+		if connection != nil {
+			if err := p.previewPendingDeletion(connection, buffered); err != nil {
+				return err
+			}
+		}
+		source = buffered
+	}
+	if p.interactive {
+		reviewed, err := reviewBatch(source)
+		if err != nil {
+			return err
+		}
+		source = reviewed
+	}
+
+	if p.JobFile != "" && p.job == nil {
+		job, err := newBatchJob(p.Template, source)
+		if err != nil {
+			return err
+		}
+		p.job = job
+		source = job.source(p.JobFile)
+	}
+
+	if p.job != nil && p.job.Confirmed {
+		logger().Info("The clusters of the job were confirmed when it was created, resuming without asking again", "job", p.JobFile)
+	} else if !p.isDryRun {
+		fmt.Fprintf(p.previewOut(), "The template %s will be rendered and sent to %s\n", p.Template, source.description())
+		if err := p.printImpactSummary(1, clusterCount(source), "each of "+source.description()); err != nil {
+			return err
+		}
+		// The batch is confirmed once, for all of its clusters
+		data := confirmData{ClusterName: source.description()}
+		if t, err := p.readTemplate(); err == nil {
+			data.Summary = t.Summary
+		}
+		if ok, err := p.confirmPost(data); !ok {
+			return err
+		}
+		if p.job != nil {
+			p.job.Confirmed = true
+		}
+	}
+	if p.job != nil {
+		if p.async {
+			return p.startAsyncJob()
+		}
+		// The lock is held for the whole batch, a second process resuming the job at the same time stops here
+		unlock, err := lockBatchJob(p.JobFile)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		if err := p.job.checkNotRunning(p.JobFile); err != nil {
+			return err
+		}
+		p.job.PID = os.Getpid()
+		if err := p.job.save(p.JobFile); err != nil {
+			return err
+		}
+	}
+
+	var succeeded, failed, skipped, unprocessed int
+	var results []batchResult
+	var jobErr error
+	orgs := map[string]*orgSummary{}
+	for {
+		if p.deadlinePassed() {
+			unprocessed = reportUnprocessed(source)
+			break
+		}
+		entry, err := source.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var result *PostResult
+		// The cluster is resolved first, so that a cluster listed under several keys is only posted to once
+		if err == nil && connection != nil {
+			p.cluster = nil
+			err = p.prepareCluster(connection, entry.ClusterID)
+			if p.cluster != nil {
+				if dupErr := deduped.resolved(entry.ClusterID, p.cluster.ID()); dupErr != nil {
+					err = dupErr
+				}
+			}
+		}
+		if errors.Is(err, errDuplicateCluster) {
+			logger().Info("Skipped the cluster, it was already selected under another key", "cluster", entry.ClusterID, "reason", err)
+			results = append(results, p.newBatchResult(entry.ClusterID, nil, err))
+			if jobErr = p.recordProgress(entry, results); jobErr != nil {
+				break
+			}
+			continue
+		}
+		if err == nil {
+			p.clusterPrepared = connection != nil
+			result, err = p.postToCluster(connection, entry.ClusterID, entry.Params, false)
+			p.clusterPrepared = false
+			// The Markdown table is printed once the batch is over
+			if err == nil && result != nil && p.output != markdownOutput {
+				err = p.printResult(result)
+			}
+		}
+		if err == nil && p.summaryByOrg {
+			err = addToOrgSummary(connection, orgs, p.cluster.ID())
+		}
+		if skippedCluster(err) {
+			skipped++
+			logger().Info("Skipped the cluster", "cluster", entry.ClusterID, "reason", err)
+			results = append(results, p.newBatchResult(entry.ClusterID, nil, err))
+			if jobErr = p.recordProgress(entry, results); jobErr != nil {
+				break
+			}
+			continue
+		}
+		if err != nil {
+			failed++
+			// JSON errors and Markdown tables identify the cluster, plain text ones locate the entry in the batch
+			target := source.position()
+			if entry != nil && (p.output == "json" || p.output == markdownOutput) {
+				target = entry.ClusterID
+			}
+			reportError(p.output, target, err)
+			results = append(results, p.newBatchResult(target, nil, err))
+			if jobErr = p.recordProgress(entry, results); jobErr != nil {
+				break
+			}
+			if errors.Is(err, errRetryBudgetExhausted) {
+				logger().Warn("Stopping the batch, OCM is likely failing systemically", "retry_budget", p.retryBudget)
+				break
+			}
+			if p.failFast {
+				logger().Warn("Stopping at the first failure because of --fail-fast")
+				break
+			}
+			continue
+		}
+		succeeded++
+		results = append(results, p.newBatchResult(entry.ClusterID, result, nil))
+		if jobErr = p.recordProgress(entry, results); jobErr != nil {
+			break
+		}
+	}
+	if jobErr != nil {
+		logger().Error("Stopping the batch, its progress cannot be saved", "error", jobErr)
+		return jobErr
+	}
+	if p.job != nil {
+		p.job.Done = p.job.remaining() == 0
+		if err := p.job.save(p.JobFile); err != nil {
+			return err
+		}
+	}
+
+	if p.summaryByOrg {
+		if err := printOrgSummary(p.previewOut(), orgs); err != nil {
+			return fmt.Errorf("could not print the organization summary: %w", err)
+		}
+	}
+
+	if p.output == markdownOutput {
+		fmt.Print(markdownSummary(results))
+	}
+	if deduped.duplicates > 0 {
+		logger().Info("Collapsed duplicate clusters", "duplicates", deduped.duplicates)
+	}
+	if resumed != nil && resumed.resumed > 0 {
+		logger().Info("Skipped the clusters the state file records as done", "state_file", p.StateFile, "skipped", resumed.resumed)
+	}
+	switch {
+	case p.onlyIfHealthy && p.excludeDeleting:
+		fmt.Fprintf(p.previewOut(), "Success: %d, Failed: %d, Skipped (already in limited support or pending deletion): %d\n", succeeded, failed, skipped)
+	case p.onlyIfHealthy:
+		fmt.Fprintf(p.previewOut(), "Success: %d, Failed: %d, Skipped (already in limited support): %d\n", succeeded, failed, skipped)
+	case p.excludeDeleting:
+		fmt.Fprintf(p.previewOut(), "Success: %d, Failed: %d, Skipped (pending deletion): %d\n", succeeded, failed, skipped)
+	default:
+		fmt.Fprintf(p.previewOut(), "Success: %d, Failed: %d\n", succeeded, failed)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to post to %d cluster(s)", failed)
+	}
+	if unprocessed > 0 {
+		return fmt.Errorf("the deadline passed before %d cluster(s) could be processed", unprocessed)
+	}
+	return nil
+}
+
+// SavedQueriesKey can be set in the osdctl configuration file to a map of query names to OCM subscription
+// search queries, selected with --query
+const SavedQueriesKey = "support_saved_queries"
+
+// resolveSavedQuery returns the subscription search saved under the name in the osdctl configuration
+func resolveSavedQuery(name string) (string, error) {
+	queries := viper.GetStringMapString(SavedQueriesKey)
+	// Viper lowercases the keys of maps
+	if search, ok := queries[strings.ToLower(name)]; ok && search != "" {
+		return search, nil
+	}
+
+	names := make([]string, 0, len(queries))
+	for name := range queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return "", fmt.Errorf("unknown query %q, no query is saved under '%s' in the osdctl configuration file", name, SavedQueriesKey)
+	}
+	return "", fmt.Errorf("unknown query %q, the saved queries are: %s", name, strings.Join(names, ", "))
+}
+
+// subscriptionSource resolves the clusters of the subscriptions matching --subscription-search and
+// prints them so that they can be reviewed before posting
+func (p *Post) subscriptionSource(connection *sdk.Connection) (*sliceSource, error) {
+	subscriptions, truncated, err := ctlutil.SearchSubscriptionsLimit(connection, p.SubscriptionSearch, p.maxResults)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for subscriptions matching %q: %w", p.SubscriptionSearch, err)
+	}
+	if truncated {
+		warnTruncated(p.maxResults)
+	}
+
+	source := &sliceSource{origin: fmt.Sprintf("matching the subscription search %q", p.SubscriptionSearch)}
+	table := printer.NewTablePrinter(p.previewOut(), 20, 1, 3, ' ')
+	table.AddRow([]string{"Subscription ID", "Cluster ID", "Name", "Plan", "Status"})
+	for _, subscription := range subscriptions {
+		clusterID, ok := subscription.GetClusterID()
+		if !ok || clusterID == "" {
+			// Subscriptions for clusters which were never provisioned have no cluster
+			continue
+		}
+		source.entries = append(source.entries, &clusterEntry{ClusterID: clusterID})
+		table.AddRow([]string{subscription.ID(), clusterID, subscription.DisplayName(), subscription.Plan().ID(), subscription.Status()})
+	}
+	if len(source.entries) == 0 {
+		return nil, fmt.Errorf("no clusters match the subscription search %q", p.SubscriptionSearch)
+	}
+
+	fmt.Fprintln(p.previewOut(), "The following clusters match the subscription search:")
+	// Add empty row for readability
+	table.AddRow([]string{})
+	if err := table.Flush(); err != nil {
+		return nil, fmt.Errorf("could not print matching clusters: %w", err)
+	}
+	return source, nil
+}
+
+// versionRangeSource resolves the ready managed clusters whose OpenShift version is in --version-range and
+// prints them so that they can be reviewed before posting
+func (p *Post) versionRangeSource(connection *sdk.Connection) (*sliceSource, error) {
+	versionRange, err := semver.ParseRange(p.VersionRange)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --version-range %q: %w", p.VersionRange, err)
+	}
+	clusters, err := ctlutil.ApplyFilters(connection, []string{"managed = 'true'", "state = 'ready'"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for clusters: %w", err)
+	}
+
+	matched, unparsed := filterClustersByVersion(clusters, versionRange)
+	if unparsed > 0 {
+		logger().Warn("Skipped the clusters with a version which is not valid semver", "skipped", unparsed)
+	}
+	if p.maxResults > 0 && len(matched) > p.maxResults {
+		matched = matched[:p.maxResults]
+		warnTruncated(p.maxResults)
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("no ready managed cluster has a version in the range %q", p.VersionRange)
+	}
+
+	source := &sliceSource{origin: fmt.Sprintf("with a version in the range %q", p.VersionRange)}
+	table := printer.NewTablePrinter(p.previewOut(), 20, 1, 3, ' ')
+	table.AddRow([]string{"Cluster ID", "Name", "Version"})
+	for _, cluster := range matched {
+		source.entries = append(source.entries, &clusterEntry{ClusterID: cluster.ID()})
+		table.AddRow([]string{cluster.ID(), cluster.Name(), cluster.OpenshiftVersion()})
+	}
+
+	fmt.Fprintln(p.previewOut(), "The following clusters match the version range:")
+	// Add empty row for readability
+	table.AddRow([]string{})
+	if err := table.Flush(); err != nil {
+		return nil, fmt.Errorf("could not print matching clusters: %w", err)
+	}
+	return source, nil
+}
+
+// warnTruncated reports that the search matched more clusters than --max-results, and that some are left out
+func warnTruncated(maxResults int) {
+	logger().Warn("The search matches more results than --max-results, only the first ones are selected", "max_results", maxResults)
+}
+
+// filterClustersByVersion returns the clusters whose OpenShift version is in the range, along with the number
+// of clusters which were skipped as their version couldn't be parsed
+func filterClustersByVersion(clusters []*cmv1.Cluster, versionRange semver.Range) (matched []*cmv1.Cluster, unparsed int) {
+	for _, cluster := range clusters {
+		version, err := semver.ParseTolerant(cluster.OpenshiftVersion())
+		if err != nil {
+			unparsed++
+			continue
+		}
+		if versionRange(version) {
+			matched = append(matched, cluster)
+		}
+	}
+	return matched, unparsed
+}
+
+// orgSummary counts the clusters of a batch owned by an organization
+type orgSummary struct {
+	id       string
+	name     string
+	clusters int
+}
+
+func addToOrgSummary(connection *sdk.Connection, orgs map[string]*orgSummary, clusterID string) error {
+	org, err := ctlutil.GetOrganization(connection, clusterID)
+	if err != nil {
+		return fmt.Errorf("can't retrieve the organization owning the cluster: %w", err)
+	}
+	if _, ok := orgs[org.ID()]; !ok {
+		orgs[org.ID()] = &orgSummary{id: org.ID(), name: org.Name()}
+	}
+	orgs[org.ID()].clusters++
+	return nil
+}
+
+// printOrgSummary prints the number of targeted clusters per organization, most impacted first
+func printOrgSummary(out io.Writer, orgs map[string]*orgSummary) error {
+	summaries := make([]*orgSummary, 0, len(orgs))
+	for _, org := range orgs {
+		summaries = append(summaries, org)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].clusters != summaries[j].clusters {
+			return summaries[i].clusters > summaries[j].clusters
+		}
+		return summaries[i].id < summaries[j].id
+	})
+
+	fmt.Fprintf(out, "The targeted clusters belong to %d organization(s):\n", len(summaries))
+	table := printer.NewTablePrinter(out, 20, 1, 3, ' ')
+	table.AddRow([]string{"Organization ID", "Name", "Clusters"})
+	for _, org := range summaries {
+		table.AddRow([]string{org.id, org.name, strconv.Itoa(org.clusters)})
+	}
+	// Add empty row for readability
+	table.AddRow([]string{})
+	return table.Flush()
+}
@@ -0,0 +1,94 @@
+package support
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"golang.org/x/term"
+)
+
+// reviewPageSize is the number of clusters shown at once by --interactive
+const reviewPageSize = 15
+
+// reviewBatch lets the user page through the clusters of the batch and deselect the ones not to post to.
+// It returns a source yielding the selected clusters only.
+func reviewBatch(source clusterSource) (*sliceSource, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil, errors.New("--interactive requires a terminal")
+	}
+
+	var entries []*clusterEntry
+	for {
+		entry, err := source.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot review the batch, %s: %w", source.position(), err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("there are no clusters to review")
+	}
+
+	options := reviewOptions(entries)
+	defaults := make([]int, len(options))
+	for i := range defaults {
+		defaults[i] = i
+	}
+	var answers []string
+	prompt := &survey.MultiSelect{
+		Message:  fmt.Sprintf("Deselect the clusters not to post to, out of %s:", source.description()),
+		Options:  options,
+		Default:  defaults,
+		PageSize: reviewPageSize,
+	}
+	if err := survey.AskOne(prompt, &answers); err != nil {
+		return nil, fmt.Errorf("cannot review the batch: %w", err)
+	}
+
+	selected := selectedEntries(entries, options, answers)
+	if len(selected) == 0 {
+		return nil, errors.New("no cluster was selected")
+	}
+	return &sliceSource{origin: fmt.Sprintf("selected out of %s", source.description()), entries: selected}, nil
+}
+
+// reviewOptions returns a label for each entry, numbered so that the same cluster listed twice stays distinct
+func reviewOptions(entries []*clusterEntry) []string {
+	options := make([]string, 0, len(entries))
+	for i, entry := range entries {
+		label := fmt.Sprintf("%d) %s", i+1, entry.ClusterID)
+		if len(entry.Params) > 0 {
+			params := make([]string, 0, len(entry.Params))
+			for name, value := range entry.Params {
+				params = append(params, fmt.Sprintf("%s=%s", name, value))
+			}
+			sort.Strings(params)
+			label += fmt.Sprintf(" (%s)", strings.Join(params, ", "))
+		}
+		options = append(options, label)
+	}
+	return options
+}
+
+// selectedEntries returns the entries whose label was selected, in their original order
+func selectedEntries(entries []*clusterEntry, options []string, answers []string) []*clusterEntry {
+	chosen := map[string]bool{}
+	for _, answer := range answers {
+		chosen[answer] = true
+	}
+	var selected []*clusterEntry
+	for i, option := range options {
+		if chosen[option] {
+			selected = append(selected, entries[i])
+		}
+	}
+	return selected
+}
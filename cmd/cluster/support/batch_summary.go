@@ -0,0 +1,76 @@
+package support
+
+import (
+	"fmt"
+	"strings"
+)
+
+// markdownOutput is the '-o' value printing the results of a batch as a Markdown table, to be pasted in chats
+// and tickets once the batch is over
+const markdownOutput = "markdown"
+
+// Statuses of the clusters of a batch
+const (
+	batchPosted   = "posted"
+	batchRendered = "rendered (dry-run)"
+	batchFailed   = "failed"
+	// batchSkipped clusters already had a limited support reason with --only-if-healthy, or were pending
+	// deletion with --exclude-deleting
+	batchSkipped = "skipped"
+)
+
+// batchResult is the outcome of a batch for one of its clusters
+type batchResult struct {
+	ClusterID string `json:"cluster_id" yaml:"cluster_id"`
+	// Name is only known for clusters which were resolved in OCM
+	Name     string `json:"name,omitempty" yaml:"name,omitempty"`
+	Status   string `json:"status" yaml:"status"`
+	ReasonID string `json:"reason_id,omitempty" yaml:"reason_id,omitempty"`
+	// Error is why the post failed or was skipped, if it was
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// newBatchResult returns the outcome of posting to a cluster, result is nil when nothing was sent
+func (p *Post) newBatchResult(clusterID string, result *PostResult, err error) batchResult {
+	if err != nil && !skippedCluster(err) {
+		return batchResult{ClusterID: clusterID, Status: batchFailed, Error: err.Error()}
+	}
+	r := batchResult{ClusterID: clusterID, Status: batchRendered}
+	if p.cluster != nil {
+		r.ClusterID = p.cluster.ID()
+		r.Name = p.cluster.Name()
+	}
+	// Skipped clusters were resolved before their limited support reasons were checked
+	if err != nil {
+		r.Status = batchSkipped
+		r.Error = err.Error()
+		return r
+	}
+	if result != nil {
+		r.ClusterID = result.ClusterID
+		r.Status = batchPosted
+		r.ReasonID = result.ReasonID
+	}
+	return r
+}
+
+// markdownSummary returns a Markdown table of the results of a batch
+func markdownSummary(results []batchResult) string {
+	var out strings.Builder
+	out.WriteString("| Cluster ID | Name | Status | Reason ID |\n")
+	out.WriteString("|---|---|---|---|\n")
+	for _, r := range results {
+		status := r.Status
+		if r.Error != "" {
+			status = fmt.Sprintf("%s: %s", status, r.Error)
+		}
+		fmt.Fprintf(&out, "| %s | %s | %s | %s |\n", markdownCell(r.ClusterID), markdownCell(r.Name), markdownCell(status), markdownCell(r.ReasonID))
+	}
+	return out.String()
+}
+
+// markdownCell escapes the value so that it stays within its table cell
+func markdownCell(value string) string {
+	value = strings.ReplaceAll(value, "|", `\|`)
+	return strings.Join(strings.Fields(value), " ")
+}
@@ -0,0 +1,38 @@
+package support
+
+import (
+	"errors"
+	"testing"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+)
+
+func Test_markdownSummary(t *testing.T) {
+	cluster, err := cmv1.NewCluster().ID("abc").Name("my-cluster").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reason, err := cmv1.NewLimitedSupportReason().ID("r0").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Post{cluster: cluster}
+	results := []batchResult{
+		p.newBatchResult("my-cluster", &PostResult{ClusterID: "abc", ReasonID: "r1"}, nil),
+		p.newBatchResult("def", nil, errors.New("can't retrieve cluster:\nno cluster | found")),
+	}
+	results = append(results, p.newBatchResult("my-cluster", nil, healthCheck([]*cmv1.LimitedSupportReason{reason})))
+	p.cluster = nil
+	results = append(results, p.newBatchResult("ghi", nil, nil))
+
+	want := `| Cluster ID | Name | Status | Reason ID |
+|---|---|---|---|
+| abc | my-cluster | posted | r1 |
+| def |  | failed: can't retrieve cluster: no cluster \| found |  |
+| abc | my-cluster | skipped: the cluster is already in limited support with 1 reason(s): r0 |  |
+| ghi |  | rendered (dry-run) |  |
+`
+	if got := markdownSummary(results); got != want {
+		t.Errorf("markdownSummary() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,350 @@
+package support
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/blang/semver/v4"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/spf13/viper"
+)
+
+func Test_filterClustersByVersion(t *testing.T) {
+	var clusters []*cmv1.Cluster
+	for id, version := range map[string]string{
+		"old":     "4.11.40",
+		"low":     "4.12.0",
+		"high":    "4.12.45",
+		"new":     "4.13.0",
+		"invalid": "latest",
+	} {
+		cluster, err := cmv1.NewCluster().ID(id).OpenshiftVersion(version).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		clusters = append(clusters, cluster)
+	}
+
+	matched, unparsed := filterClustersByVersion(clusters, semver.MustParseRange(">=4.12.0 <4.13.0"))
+	got := map[string]bool{}
+	for _, cluster := range matched {
+		got[cluster.ID()] = true
+	}
+	if len(got) != 2 || !got["low"] || !got["high"] {
+		t.Errorf("filterClustersByVersion() matched %v, want low and high", got)
+	}
+	if unparsed != 1 {
+		t.Errorf("filterClustersByVersion() unparsed = %d, want 1", unparsed)
+	}
+}
+
+func Test_reviewSelection(t *testing.T) {
+	entries := []*clusterEntry{
+		{ClusterID: "a"},
+		{ClusterID: "b", Params: map[string]string{"ZONE": "us-east-1a", "NAME": "foo"}},
+		{ClusterID: "a"},
+	}
+	options := reviewOptions(entries)
+	wantOptions := []string{"1) a", "2) b (NAME=foo, ZONE=us-east-1a)", "3) a"}
+	if !reflect.DeepEqual(options, wantOptions) {
+		t.Fatalf("reviewOptions() = %q, want %q", options, wantOptions)
+	}
+
+	selected := selectedEntries(entries, options, []string{"3) a", "2) b (NAME=foo, ZONE=us-east-1a)"})
+	if len(selected) != 2 || selected[0] != entries[1] || selected[1] != entries[2] {
+		t.Errorf("selectedEntries() = %v, want the 2nd and 3rd entries in order", selected)
+	}
+}
+
+func Test_dedupSource(t *testing.T) {
+	source := newDedupSource(newJSONLSource("clusters.jsonl", strings.NewReader(`{"cluster_id":"a","params":{"FOO":"first"}}
+{"cluster_id":"b"}
+{"cluster_id":"a","params":{"FOO":"second"}}
+not json
+{"cluster_id":"b"}
+{"cluster_id":"c"}
+`)))
+
+	var ids []string
+	var errs int
+	for {
+		entry, err := source.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			errs++
+			continue
+		}
+		ids = append(ids, entry.ClusterID)
+		if entry.ClusterID == "a" && entry.Params["FOO"] != "first" {
+			t.Errorf("dedupSource kept params %v, want the first entry of the cluster", entry.Params)
+		}
+	}
+
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("dedupSource returned %v, want %v", ids, want)
+	}
+	if source.duplicates != 2 {
+		t.Errorf("dedupSource collapsed %d duplicates, want 2", source.duplicates)
+	}
+	if errs != 1 {
+		t.Errorf("dedupSource returned %d errors, want 1 for the invalid line", errs)
+	}
+}
+
+func Test_combineSources(t *testing.T) {
+	source := combineSources([]clusterSource{
+		&sliceSource{origin: "read from stdin", entries: []*clusterEntry{{ClusterID: "a"}, {ClusterID: "b"}}},
+		newIDsFileSource("clusters.txt", strings.NewReader("c\n\nd\n")),
+	})
+	if want := "the 2 cluster(s) read from stdin and every cluster listed in clusters.txt"; source.description() != want {
+		t.Errorf("description() = %q, want %q", source.description(), want)
+	}
+
+	var ids []string
+	for {
+		entry, err := source.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, entry.ClusterID)
+		if entry.ClusterID == "d" && source.position() != "clusters.txt:3" {
+			t.Errorf("position() = %q, want the line of the file being read", source.position())
+		}
+	}
+	if want := []string{"a", "b", "c", "d"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("combineSources() returned %v, want the clusters of every source in order", ids)
+	}
+}
+
+func Test_runBatchCollapsesClusterAliases(t *testing.T) {
+	// Every key resolves to the same cluster
+	_, connection := newFakeOCM(t, map[string]http.HandlerFunc{
+		"GET /api/accounts_mgmt/v1/subscriptions": respond(http.StatusOK, `{"kind":"SubscriptionList","page":1,"size":1,"total":1,"items":[
+			{"kind":"Subscription","id":"sub-id","cluster_id":"cluster-id"}]}`),
+		"GET /api/clusters_mgmt/v1/clusters/cluster-id": respond(http.StatusOK, `{"kind":"Cluster","id":"cluster-id","name":"cluster-name","external_id":"cluster-uuid","state":"ready"}`),
+		"GET /api/clusters_mgmt/v1/clusters":            respond(http.StatusOK, `{"kind":"ClusterList","page":1,"size":0,"total":0,"items":[]}`),
+	})
+	source := &sliceSource{origin: "for the test", entries: []*clusterEntry{
+		{ClusterID: "cluster-name"},
+		{ClusterID: "cluster-uuid"},
+		{ClusterID: "cluster-id"},
+	}}
+	dir := filepath.Join(t.TempDir(), "rendered")
+	p := &Post{
+		Template:      "template.json",
+		isDryRun:      true,
+		renderOnlyTo:  dir,
+		output:        "json",
+		templateBytes: []byte(`{"summary":"Summary","details":"Details","detection_type":"manual"}`),
+	}
+	if err := p.runBatch(connection, source); err != nil {
+		t.Fatal(err)
+	}
+
+	rendered, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rendered) != 1 || rendered[0].Name() != "cluster-id.json" {
+		t.Errorf("runBatch() rendered %v, want the cluster rendered once", rendered)
+	}
+}
+
+func Test_runBatchFailFast(t *testing.T) {
+	for _, failFast := range []bool{false, true} {
+		source := &sliceSource{origin: "for the test", entries: []*clusterEntry{
+			{ClusterID: "missing-param"},
+			{ClusterID: "with-param", Params: map[string]string{"NAME": "foo"}},
+		}}
+		dir := filepath.Join(t.TempDir(), "rendered")
+		p := &Post{
+			Template:      "template.json",
+			isDryRun:      true,
+			failFast:      failFast,
+			renderOnlyTo:  dir,
+			templateBytes: []byte(`{"summary":"Summary","details":"Remove ${NAME}","detection_type":"manual"}`),
+		}
+		if err := p.runBatch(nil, source); err == nil {
+			t.Errorf("runBatch() with failFast %v expected an error for the failing cluster", failFast)
+		}
+
+		// The second cluster is only rendered when the batch went on after the first one failed
+		_, err := os.Stat(filepath.Join(dir, "with-param.json"))
+		if rendered := err == nil; rendered == failFast {
+			t.Errorf("runBatch() with failFast %v rendered the second cluster: %v", failFast, rendered)
+		}
+	}
+}
+
+func Test_bufferSourceCountsClusters(t *testing.T) {
+	source := bufferSource(newDedupSource(newIDsFileSource("clusters.txt", strings.NewReader("cluster-a\ncluster-b\ncluster-a\nbad'key\n"))), batchReadAhead)
+	if want := "the 2 cluster(s) to post to out of every cluster listed in clusters.txt"; source.description() != want {
+		t.Errorf("description() = %q, want %q", source.description(), want)
+	}
+
+	// The entries which couldn't be read are still reported at their position
+	var positions []string
+	for {
+		_, err := source.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			positions = append(positions, source.position())
+		}
+	}
+	if want := []string{"clusters.txt:4"}; !reflect.DeepEqual(positions, want) {
+		t.Errorf("bufferedSource failed at %v, want %v", positions, want)
+	}
+}
+
+func Test_bufferSourceStreamsPastTheLimit(t *testing.T) {
+	source := bufferSource(newIDsFileSource("clusters.txt", strings.NewReader("cluster-a\ncluster-b\ncluster-c\n")), 2)
+	if want := "the 2+ cluster(s) to post to out of every cluster listed in clusters.txt"; source.description() != want {
+		t.Errorf("description() = %q, want %q", source.description(), want)
+	}
+	if n := clusterCount(source); n != 0 {
+		t.Errorf("clusterCount() = %d, want 0 as the total isn't known", n)
+	}
+
+	var ids []string
+	for {
+		entry, err := source.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, entry.ClusterID)
+	}
+	if want := []string{"cluster-a", "cluster-b", "cluster-c"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("bufferedSource returned %v, want the clusters past the limit to be streamed", ids)
+	}
+	if source.position() != "clusters.txt:3" {
+		t.Errorf("position() = %q, want the line of the streamed cluster", source.position())
+	}
+}
+
+func Test_runBatchRenderOnlyTo(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "rendered")
+	source := &sliceSource{origin: "for the test", entries: []*clusterEntry{
+		{ClusterID: "cluster-a", Params: map[string]string{"NAME": "foo"}},
+		{ClusterID: "cluster-b", Params: map[string]string{"NAME": "bar"}},
+	}}
+	p := &Post{
+		Template:      "template.json",
+		isDryRun:      true,
+		renderOnlyTo:  dir,
+		templateBytes: []byte(`{"summary":"Summary","details":"Remove ${NAME}","detection_type":"manual"}`),
+	}
+	if err := p.runBatch(nil, source); err != nil {
+		t.Fatal(err)
+	}
+
+	for clusterID, want := range map[string]string{"cluster-a": "Remove foo", "cluster-b": "Remove bar"} {
+		contents, err := os.ReadFile(filepath.Join(dir, clusterID+".json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		reason, err := cmv1.UnmarshalLimitedSupportReason(contents)
+		if err != nil {
+			t.Fatalf("%s.json is not a limited support reason: %v", clusterID, err)
+		}
+		if reason.Details() != want {
+			t.Errorf("%s.json details = %q, want %q", clusterID, reason.Details(), want)
+		}
+	}
+}
+
+func Test_resolveSavedQuery(t *testing.T) {
+	viper.Set(SavedQueriesKey, map[string]string{"fedramp-prod": "plan.id='OSD' and status='Active'", "staging": "status='Active'"})
+	defer viper.Set(SavedQueriesKey, nil)
+
+	search, err := resolveSavedQuery("FedRAMP-prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if search != "plan.id='OSD' and status='Active'" {
+		t.Errorf("resolveSavedQuery() = %q, want the saved search", search)
+	}
+
+	_, err = resolveSavedQuery("unknown")
+	if err == nil || !strings.Contains(err.Error(), "fedramp-prod, staging") {
+		t.Errorf("resolveSavedQuery() error = %v, want the saved query names listed", err)
+	}
+}
+
+func Test_idsFileSource(t *testing.T) {
+	source := newIDsFileSource("clusters.txt", strings.NewReader(`# clusters of the incident
+cluster-a NAME=foo REGION=us-east-1
+
+cluster-b
+cluster-c NAME
+cluster-d NAME=a=b
+`))
+
+	var entries []clusterEntry
+	var positions []string
+	for {
+		entry, err := source.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			positions = append(positions, source.position())
+			continue
+		}
+		entries = append(entries, *entry)
+	}
+
+	want := []clusterEntry{
+		{ClusterID: "cluster-a", Params: map[string]string{"NAME": "foo", "REGION": "us-east-1"}},
+		{ClusterID: "cluster-b"},
+		{ClusterID: "cluster-d", Params: map[string]string{"NAME": "a=b"}},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("idsFileSource returned %v, want %v", entries, want)
+	}
+	if want := []string{"clusters.txt:5"}; !reflect.DeepEqual(positions, want) {
+		t.Errorf("idsFileSource failed at %v, want %v", positions, want)
+	}
+}
+
+func Test_checkClusterKey(t *testing.T) {
+	tests := []struct {
+		key       string
+		trusted   bool
+		wantValid bool
+	}{
+		{key: "1a2b3c4d", wantValid: true},
+		{key: "my.cluster", wantValid: false},
+		{key: "my.cluster", trusted: true, wantValid: true},
+		{key: "a' or '1'='1", trusted: true, wantValid: false},
+		{key: `a\`, trusted: true, wantValid: false},
+		{key: "", trusted: true, wantValid: false},
+	}
+	for _, tt := range tests {
+		if err := checkClusterKey(tt.key, tt.trusted); (err == nil) != tt.wantValid {
+			t.Errorf("checkClusterKey(%q, %v) error = %v, want valid %v", tt.key, tt.trusted, err, tt.wantValid)
+		}
+	}
+
+	if _, err := parseClusterEntry(`{"cluster_id":"my.cluster"}`, false); err == nil {
+		t.Errorf("parseClusterEntry() expected an error for an unusual key of an untrusted file")
+	}
+	if entry, err := parseClusterIDsLine("my.cluster NAME=foo", true); err != nil || entry.ClusterID != "my.cluster" {
+		t.Errorf("parseClusterIDsLine() = %v, %v, want the unusual key of a trusted file", entry, err)
+	}
+}
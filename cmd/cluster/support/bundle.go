@@ -0,0 +1,89 @@
+package support
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+)
+
+// reasonBundle is a self-contained remediation given with --bundle: a template along with default values for its
+// parameters, in a single YAML file
+type reasonBundle struct {
+	Metadata bundleMetadata `json:"metadata"`
+	Template *TemplateFile  `json:"template"`
+	// Params are the default values of the parameters, '-p', --params-file and cluster entries override them
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// bundleMetadata describes a bundle for its reviewers, only the version is used when posting
+type bundleMetadata struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Version is recorded in the local history, as the '_template_version' of templates without one
+	Version string `json:"version,omitempty"`
+}
+
+// loadBundle reads the --bundle file and uses its template as if it was given with '-t'. The bundle is
+// only read once, so it can be rendered for many clusters.
+func (p *Post) loadBundle() error {
+	if p.Bundle == "" {
+		return nil
+	}
+	if p.Template != "" && p.Template != p.Bundle {
+		return errors.New("--bundle contains its template, it cannot be used together with '-t'")
+	}
+	if p.bundleTemplate == nil {
+		contents, err := p.accessFile(p.Bundle)
+		if err != nil {
+			return err
+		}
+		if p.bundleTemplate, p.bundleParams, err = parseBundle(p.Bundle, contents); err != nil {
+			return err
+		}
+	}
+	p.Template = p.Bundle
+	p.templateBytes = p.bundleTemplate
+	return nil
+}
+
+// parseBundle returns the JSON of the template of a bundle and its default parameters
+func parseBundle(path string, contents []byte) ([]byte, map[string]string, error) {
+	var bundle reasonBundle
+	// Misspelled fields would silently be ignored, eg. a 'param' section whose defaults are never used
+	if err := yaml.UnmarshalStrict(contents, &bundle); err != nil {
+		return nil, nil, fmt.Errorf("cannot parse the bundle %s: %w", path, err)
+	}
+	if bundle.Template == nil {
+		return nil, nil, fmt.Errorf("the bundle %s has no template", path)
+	}
+	if bundle.Template.TemplateVersion == "" {
+		bundle.Template.TemplateVersion = bundle.Metadata.Version
+	}
+	template, err := json.Marshal(bundle.Template)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot convert the template of the bundle %s: %w", path, err)
+	}
+	params, err := scalarParams(path, bundle.Params)
+	if err != nil {
+		return nil, nil, err
+	}
+	return template, params, nil
+}
+
+// bundleSource is the source of the parameters whose value is a default of the bundle
+func (p *Post) bundleSource() string {
+	return "the defaults of --bundle " + p.Bundle
+}
+
+// bundleParamNames returns the names of the default parameters of the bundle, sorted
+func (p *Post) bundleParamNames() []string {
+	names := make([]string, 0, len(p.bundleParams))
+	for name := range p.bundleParams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,106 @@
+package support
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testBundle = `metadata:
+  name: ingress-controller
+  description: A second ingress controller is failing
+  version: "1.2"
+template:
+  summary: Unsupported ingress controller
+  details: Remove the ${NAME} ingress controller of ${TEAM} in ${ZONE}
+  detection_type: manual
+params:
+  NAME: custom
+  TEAM: sre
+  ZONE: us-east-1a
+  UNUSED: value
+`
+
+func Test_bundlePrecedence(t *testing.T) {
+	dir := t.TempDir()
+	bundle := filepath.Join(dir, "bundle.yaml")
+	if err := os.WriteFile(bundle, []byte(testBundle), 0600); err != nil {
+		t.Fatal(err)
+	}
+	incident := filepath.Join(dir, "incident.yaml")
+	if err := os.WriteFile(incident, []byte("ZONE: us-west-2b\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Post{
+		Bundle:         bundle,
+		ParamsFiles:    []string{incident},
+		TemplateParams: []string{"NAME=second"},
+	}
+	if err := p.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if p.Template != bundle {
+		t.Errorf("Template = %q, want the bundle %q", p.Template, bundle)
+	}
+	reason, err := p.buildLimitedSupportTemplate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Remove the second ingress controller of sre in us-west-2b"; reason.Details() != want {
+		t.Errorf("details = %q, want %q", reason.Details(), want)
+	}
+	if p.templateVersion != "1.2" {
+		t.Errorf("templateVersion = %q, want the version of the bundle", p.templateVersion)
+	}
+
+	defaults := "the defaults of --bundle " + bundle
+	wantSources := map[string]string{
+		"${NAME}":   "--param, overriding " + defaults,
+		"${TEAM}":   defaults,
+		"${ZONE}":   "--params-file " + incident + ", overriding " + defaults,
+		"${UNUSED}": defaults,
+	}
+	for placeholder, want := range wantSources {
+		if got := p.paramSources[placeholder]; got != want {
+			t.Errorf("source of %s = %q, want %q", placeholder, got, want)
+		}
+	}
+
+	// The bundle is only read once
+	if err := os.Remove(bundle); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.buildLimitedSupportTemplate(nil); err != nil {
+		t.Errorf("buildLimitedSupportTemplate() after the bundle was read = %v", err)
+	}
+}
+
+func Test_bundleWithTemplate(t *testing.T) {
+	p := &Post{Bundle: "bundle.yaml", Template: "template.json"}
+	if err := p.Init(); err == nil {
+		t.Errorf("Init() expected an error for --bundle together with '-t'")
+	}
+}
+
+func Test_parseBundle(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+	}{
+		{name: "no template", contents: "params:\n  FOO: bar\n"},
+		{name: "unknown field", contents: "template:\n  summary: Summary\n  details: Details\nparam:\n  FOO: bar\n"},
+		{name: "nested parameter", contents: "template:\n  summary: Summary\n  details: ${FOO}\nparams:\n  FOO:\n    BAR: baz\n"},
+		{name: "empty parameter", contents: "template:\n  summary: Summary\n  details: ${FOO}\nparams:\n  FOO: \"\"\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := parseBundle("bundle.yaml", []byte(tt.contents)); err == nil {
+				t.Errorf("parseBundle() expected an error")
+			}
+		})
+	}
+}
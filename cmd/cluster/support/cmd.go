@@ -2,8 +2,11 @@ package support
 
 import (
 	"fmt"
+
 	"github.com/openshift/osdctl/internal/utils/globalflags"
+	ctlutil "github.com/openshift/osdctl/pkg/utils"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -21,9 +24,64 @@ func NewCmdSupport(streams genericclioptions.IOStreams, client client.Client, gl
 		Run:               help,
 	}
 
+	// The background process of an --async job gets the --client-id and --client-secret of the root command
+	// from its environment
+	_ = viper.BindEnv(ctlutil.OCMClientIDKey, asyncClientIDEnv)
+	_ = viper.BindEnv(ctlutil.OCMClientSecretKey, asyncClientSecretEnv)
+
+	// Mutual TLS, for restricted networks where the OCM gateway requires a client certificate
+	supportCmd.PersistentFlags().String("client-cert", "", "Client certificate file for mutual TLS with OCM. Requires --client-key")
+	supportCmd.PersistentFlags().String("client-key", "", "Client key file for mutual TLS with OCM. Requires --client-cert")
+	supportCmd.PersistentFlags().String("ca-cert", "", "CA certificate bundle used to verify the OCM gateway, on top of the system ones")
+	_ = viper.BindPFlag(ctlutil.OCMClientCertKey, supportCmd.PersistentFlags().Lookup("client-cert"))
+	_ = viper.BindPFlag(ctlutil.OCMClientKeyKey, supportCmd.PersistentFlags().Lookup("client-key"))
+	_ = viper.BindPFlag(ctlutil.OCMCACertKey, supportCmd.PersistentFlags().Lookup("ca-cert"))
+
+	// Advanced tuning of the connection reuse, which matters for large batches
+	supportCmd.PersistentFlags().Int("max-idle-conns", 0, "Advanced: number of idle connections to OCM kept open for reuse. Raising it avoids reconnecting during large batches. Defaults to the OCM SDK's 2")
+	supportCmd.PersistentFlags().Duration("idle-conn-timeout", 0, "Advanced: how long an idle connection to OCM is kept open for reuse (eg. 90s). Never closed by default")
+	_ = viper.BindPFlag(ctlutil.OCMMaxIdleConnsKey, supportCmd.PersistentFlags().Lookup("max-idle-conns"))
+	_ = viper.BindPFlag(ctlutil.OCMIdleConnTimeoutKey, supportCmd.PersistentFlags().Lookup("idle-conn-timeout"))
+
+	// Recordings of OCM responses, for deterministic tests and demos which don't need OCM
+	supportCmd.PersistentFlags().String("record", "", "Advanced: save every OCM API response to this directory, which must not contain a recording already. Credentials are never saved")
+	supportCmd.PersistentFlags().String("replay", "", "Advanced: serve the OCM API responses saved with --record from this directory, in order, instead of talking to OCM. No OCM login is needed")
+	_ = viper.BindPFlag(ctlutil.OCMRecordDirKey, supportCmd.PersistentFlags().Lookup("record"))
+	_ = viper.BindPFlag(ctlutil.OCMReplayDirKey, supportCmd.PersistentFlags().Lookup("replay"))
+
+	// The reason allowlist is a policy, it applies to every command writing limited support reasons
+	supportCmd.PersistentFlags().String("reason-allowlist", "", "File listing the permitted limited support reasons, one summary or reason template ID per line. Reasons which are not listed are rejected by post, update, apply and replay. Defaults to 'support_reason_allowlist' from the osdctl configuration file.")
+	_ = viper.BindPFlag(ReasonAllowlistKey, supportCmd.PersistentFlags().Lookup("reason-allowlist"))
+
+	supportCmd.PersistentFlags().Duration("confirm-timeout", 0, "Abort when a confirmation prompt isn't answered within this duration (eg. 5m). Waits forever by default")
+	_ = viper.BindPFlag(ConfirmTimeoutKey, supportCmd.PersistentFlags().Lookup("confirm-timeout"))
+
+	supportCmd.PersistentFlags().Float64("retry-jitter", 1, "Fraction, between 0 and 1, of every OCM retry backoff which is randomized so that concurrent runs don't retry in lockstep, and of a Retry-After pause over which the requests it held back resume. 1 is full jitter, 0 disables it")
+	_ = viper.BindPFlag(RetryJitterKey, supportCmd.PersistentFlags().Lookup("retry-jitter"))
+
+	// Logs are written to stderr, results to stdout
+	supportCmd.PersistentFlags().String("log-format", "text", "Format of the logs written to stderr, 'text' or 'json'")
+	supportCmd.PersistentFlags().String("log-level", "info", "Minimum level of the logs written to stderr, 'debug', 'info', 'warn' or 'error'")
+	_ = viper.BindPFlag(LogFormatKey, supportCmd.PersistentFlags().Lookup("log-format"))
+	_ = viper.BindPFlag(LogLevelKey, supportCmd.PersistentFlags().Lookup("log-level"))
+
+	// Named OCM environment from the 'ocm_profiles' section of the osdctl configuration file
+	supportCmd.PersistentFlags().String("profile", "", "OCM profile to connect with, as defined under 'ocm_profiles' in the osdctl configuration file. Defaults to 'ocm_profile' from the osdctl configuration file")
+	_ = viper.BindPFlag(ctlutil.OCMProfileKey, supportCmd.PersistentFlags().Lookup("profile"))
+
 	supportCmd.AddCommand(newCmdstatus(streams, globalOpts))
-	supportCmd.AddCommand(newCmdpost())
+	supportCmd.AddCommand(newCmdpost(client, globalOpts))
 	supportCmd.AddCommand(newCmddelete(streams, globalOpts))
+	supportCmd.AddCommand(newCmdreplay(streams, globalOpts))
+	supportCmd.AddCommand(newCmdexport(streams, globalOpts))
+	supportCmd.AddCommand(newCmdapply(streams, globalOpts))
+	supportCmd.AddCommand(newCmdstats(streams, globalOpts))
+	supportCmd.AddCommand(newCmdupdate(streams, globalOpts))
+	supportCmd.AddCommand(newCmdping(streams, globalOpts))
+	supportCmd.AddCommand(newCmdsweep(streams, globalOpts))
+	supportCmd.AddCommand(newCmddiff(streams, globalOpts))
+	supportCmd.AddCommand(newCmdvalidateAll(streams, globalOpts))
+	supportCmd.AddCommand(newCmdjob(streams, globalOpts))
 
 	return supportCmd
 }
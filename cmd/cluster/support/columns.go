@@ -0,0 +1,126 @@
+package support
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/openshift/osdctl/pkg/printer"
+)
+
+// tableOutput is the '-o' value selecting a table, whose columns can be chosen with --columns
+const tableOutput = "table"
+
+// column is a field of the rows of a command which can be selected with --columns
+type column[T any] struct {
+	name   string
+	header string
+	value  func(T) string
+}
+
+// columnSet lists the columns available for the rows of a command, in their default order
+type columnSet[T any] []column[T]
+
+// names returns the names of the columns, to be listed in help and error messages
+func (s columnSet[T]) names() string {
+	names := make([]string, 0, len(s))
+	for _, c := range s {
+		names = append(names, c.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// pick returns the columns with the given names, in that order, or the default ones when no name is given
+func (s columnSet[T]) pick(names []string, defaults ...string) (columnSet[T], error) {
+	if len(names) == 0 {
+		names = defaults
+	}
+	picked := make(columnSet[T], 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		found := false
+		for _, c := range s {
+			if c.name == name {
+				picked = append(picked, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown column %q, the available columns are: %s", name, s.names())
+		}
+	}
+	return picked, nil
+}
+
+// printTable prints a header row followed by a row for each of the rows, with the columns of the set
+func (s columnSet[T]) printTable(out io.Writer, rows []T) error {
+	table := printer.NewTablePrinter(out, 20, 1, 3, ' ')
+	header := make([]string, 0, len(s))
+	for _, c := range s {
+		header = append(header, c.header)
+	}
+	table.AddRow(header)
+	for _, row := range rows {
+		values := make([]string, 0, len(s))
+		for _, c := range s {
+			values = append(values, c.value(row))
+		}
+		table.AddRow(values)
+	}
+	// Add empty row for readability
+	table.AddRow([]string{})
+	return table.Flush()
+}
+
+// checkColumnsOutput rejects --columns for the outputs which aren't tables
+func checkColumnsOutput(output string, columns []string) error {
+	if len(columns) > 0 && output != "" && output != tableOutput {
+		return fmt.Errorf("--columns can only be used with the '%s' output", tableOutput)
+	}
+	return nil
+}
+
+// reasonRow is a limited support reason of a cluster, as listed by the status command
+type reasonRow struct {
+	clusterID string
+	reason    *cmv1.LimitedSupportReason
+}
+
+var reasonColumns = columnSet[reasonRow]{
+	{name: "cluster_id", header: "Cluster ID", value: func(r reasonRow) string { return r.clusterID }},
+	{name: "id", header: "Reason ID", value: func(r reasonRow) string { return r.reason.ID() }},
+	{name: "summary", header: "Summary", value: func(r reasonRow) string { return r.reason.Summary() }},
+	{name: "details", header: "Details", value: func(r reasonRow) string { return r.reason.Details() }},
+	{name: "detection_type", header: "Detection Type", value: func(r reasonRow) string { return string(r.reason.DetectionType()) }},
+	{name: "created_at", header: "Created At", value: func(r reasonRow) string { return formatTimestamp(r.reason.CreationTimestamp()) }},
+}
+
+// statsRow is the number of clusters with a detection type or a summary, as counted by the stats command
+type statsRow struct {
+	group string
+	statsCount
+	// inLimitedSupport is the number of clusters in limited support the count is a share of
+	inLimitedSupport int
+}
+
+var statsColumns = columnSet[statsRow]{
+	{name: "group", header: "Group", value: func(r statsRow) string { return r.group }},
+	{name: "key", header: "Key", value: func(r statsRow) string { return r.Key }},
+	{name: "clusters", header: "Clusters", value: func(r statsRow) string { return fmt.Sprintf("%d", r.Clusters) }},
+	{name: "percent", header: "Percent", value: func(r statsRow) string {
+		if r.inLimitedSupport == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.1f%%", 100*float64(r.Clusters)/float64(r.inLimitedSupport))
+	}},
+}
+
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
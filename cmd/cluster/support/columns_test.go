@@ -0,0 +1,72 @@
+package support
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+)
+
+func Test_reasonColumns(t *testing.T) {
+	reason, err := cmv1.NewLimitedSupportReason().ID("r1").Summary("Summary").Details("Details").
+		DetectionType(cmv1.DetectionTypeManual).CreationTimestamp(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	columns, err := reasonColumns.pick([]string{"created_at", " ID ", "detection_type"}, "id", "summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err := columns.printTable(out, []reasonRow{{clusterID: "cluster-a", reason: reason}}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	if fields := strings.Fields(lines[0]); strings.Join(fields, " ") != "Created At Reason ID Detection Type" {
+		t.Errorf("header = %q", lines[0])
+	}
+	if fields := strings.Fields(lines[1]); strings.Join(fields, " ") != "2024-05-01T12:00:00Z r1 manual" {
+		t.Errorf("row = %q", lines[1])
+	}
+
+	// The defaults are used without --columns
+	columns, err = reasonColumns.pick(nil, "id", "summary")
+	if err != nil || len(columns) != 2 || columns[1].name != "summary" {
+		t.Errorf("pick() = %v, %v, want the default columns", columns, err)
+	}
+	if _, err := reasonColumns.pick([]string{"severity"}); err == nil || !strings.Contains(err.Error(), "cluster_id, id, summary") {
+		t.Errorf("pick() error = %v, want the available columns to be listed", err)
+	}
+
+	if err := checkColumnsOutput("json", []string{"id"}); err == nil {
+		t.Error("checkColumnsOutput() should reject --columns with '-o json'")
+	}
+	if err := checkColumnsOutput(tableOutput, []string{"id"}); err != nil {
+		t.Errorf("checkColumnsOutput() error = %v", err)
+	}
+}
+
+func Test_statsRows(t *testing.T) {
+	rows := statsRows(fleetStats{
+		ClustersInLimitedSupport: 4,
+		ByDetectionType:          []statsCount{{Key: "manual", Clusters: 3}},
+		BySummary:                []statsCount{{Key: "cloud", Clusters: 1}},
+	})
+	columns, err := statsColumns.pick([]string{"group", "key", "percent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err := columns.printTable(out, rows); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	for i, want := range []string{"Group Key Percent", "detection_type manual 75.0%", "summary cloud 25.0%"} {
+		if got := strings.Join(strings.Fields(lines[i]), " "); got != want {
+			t.Errorf("line %d = %q, want %q", i, got, want)
+		}
+	}
+}
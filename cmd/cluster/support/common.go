@@ -1,26 +1,49 @@
 package support
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
+	"time"
 
+	"github.com/openshift-online/ocm-cli/pkg/arguments"
+	sdk "github.com/openshift-online/ocm-sdk-go"
 	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	ocmerrors "github.com/openshift-online/ocm-sdk-go/errors"
 	ctlutil "github.com/openshift/osdctl/pkg/utils"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
 )
 
-func getLimitedSupportReasons(clusterId string) ([]*cmv1.LimitedSupportReason, error) {
+// ConfirmTimeoutKey holds how long confirmation prompts wait for an answer, zero waits forever
+const ConfirmTimeoutKey = "support_confirm_timeout"
+
+// confirm prompts the user to continue. An unanswered prompt is treated as "no" and returns an error once
+// the --confirm-timeout is over.
+func confirm() (bool, error) {
+	return ctlutil.ConfirmPromptWithTimeout(viper.GetDuration(ConfirmTimeoutKey))
+}
+
+// getLimitedSupportReasons resolves the cluster and returns it along with all of its limited support reasons
+func getLimitedSupportReasons(clusterId string) (*cmv1.Cluster, []*cmv1.LimitedSupportReason, error) {
 	// Check that the cluster key (name, identifier or external identifier) given by the user
 	// is reasonably safe so that there is no risk of SQL injection
 	err := ctlutil.IsValidClusterKey(clusterId)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	//create connection to sdk
-	connection, err := ctlutil.CreateConnection()
+	connection, err := createConnection()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer func() {
 		if err := connection.Close(); err != nil {
@@ -29,17 +52,272 @@ func getLimitedSupportReasons(clusterId string) ([]*cmv1.LimitedSupportReason, e
 		}
 	}()
 
+	return listLimitedSupportReasons(connection, clusterId)
+}
+
+// listLimitedSupportReasons is getLimitedSupportReasons using an existing connection
+func listLimitedSupportReasons(connection *sdk.Connection, clusterId string) (*cmv1.Cluster, []*cmv1.LimitedSupportReason, error) {
 	//getting the cluster
-	cluster, err := ctlutil.GetCluster(connection, clusterId)
+	cluster, err := resolveCluster(connection, clusterId, nil)
 	if err != nil {
-		return nil, errors.New(fmt.Sprintf("Can't retrieve cluster: %v\n", err))
+		return nil, nil, errors.New(fmt.Sprintf("Can't retrieve cluster: %v\n", err))
 	}
 
 	//getting the limited support reasons for the cluster
-	clusterLimitedSupportReasons, err := ctlutil.GetClusterLimitedSupportReasons(connection, cluster.ID())
+	var clusterLimitedSupportReasons []*cmv1.LimitedSupportReason
+	err = retryOCM(nil, func() error {
+		var err error
+		clusterLimitedSupportReasons, err = ctlutil.GetClusterLimitedSupportReasons(connection, cluster.ID())
+		return err
+	})
 	if err != nil {
-		return nil, errors.New(fmt.Sprintf("Can't retrieve cluster limited support reasons: %v\n", err))
+		return nil, nil, errors.New(fmt.Sprintf("Can't retrieve cluster limited support reasons: %v\n", err))
 	}
 
-	return clusterLimitedSupportReasons, nil
+	return cluster, clusterLimitedSupportReasons, nil
+}
+
+// OCM requests are retried with an exponential backoff when OCM fails transiently, eg. when it rate-limits
+// them, so that a batch doesn't lose a cluster to a blip
+const (
+	ocmRetryAttempts = 4
+	ocmRetryBackoff  = 2 * time.Second
+)
+
+// createConnection connects to OCM for the support commands. Their requests are retried by retryOCM only, the
+// retries of the OCM SDK are disabled so that the attempts of both don't multiply. The requests held back by
+// a Retry-After are jittered like the retries.
+func createConnection() (*sdk.Connection, error) {
+	return ctlutil.CreateConnection(ctlutil.WithoutSDKRetries(), ctlutil.WithRetryAfterJitter(viper.GetFloat64(RetryJitterKey)))
+}
+
+// RetryJitterKey holds the fraction of every retry backoff which is randomized, so that many osdctl instances
+// retrying at once spread out. 1 is full jitter, each wait is then random between zero and the backoff.
+const RetryJitterKey = "support_retry_jitter"
+
+// jitterRandom returns a random number in [0, 1), it is replaced in tests
+var jitterRandom = rand.Float64
+
+// retrySleep waits between two retries, it is replaced in tests
+var retrySleep = time.Sleep
+
+// errRetryBudgetExhausted is wrapped by the errors of the retries refused because the batch used up its budget
+var errRetryBudgetExhausted = errors.New("the retry budget of the batch is exhausted")
+
+// retryBudget bounds the number of retries of a whole batch. Retrying every cluster independently multiplies the
+// delays when OCM fails systemically, a batch with a budget fails fast once it is used up instead.
+type retryBudget struct {
+	remaining int
+}
+
+// take consumes a retry, it returns false when the budget is used up. A nil budget is unlimited.
+func (b *retryBudget) take() bool {
+	if b == nil {
+		return true
+	}
+	if b.remaining <= 0 {
+		return false
+	}
+	b.remaining--
+	return true
+}
+
+// resolveCluster is ctlutil.GetCluster, retried on transient failures within the budget, if any
+func resolveCluster(connection *sdk.Connection, clusterKey string, budget *retryBudget) (*cmv1.Cluster, error) {
+	var cluster *cmv1.Cluster
+	err := retryOCM(budget, func() error {
+		var err error
+		cluster, err = ctlutil.GetCluster(connection, clusterKey)
+		return err
+	})
+	return cluster, err
+}
+
+// retryOCM calls fn, which sends OCM requests, until it succeeds or fails with an error which is not
+// transient. Every retry is taken from the budget, if any.
+func retryOCM(budget *retryBudget, fn func() error) error {
+	return retryTransient(ocmRetryAttempts, ocmRetryBackoff, viper.GetFloat64(RetryJitterKey), budget, retrySleep, isTransient, fn)
+}
+
+// retryOCMCreate is retryOCM for fn creating an OCM resource. It is only retried when OCM refused the request,
+// as a server failure may have happened after the resource was created and retrying would duplicate it.
+func retryOCMCreate(budget *retryBudget, fn func() error) error {
+	return retryTransient(ocmRetryAttempts, ocmRetryBackoff, viper.GetFloat64(RetryJitterKey), budget, retrySleep, isRefused, fn)
+}
+
+// retryTransient calls fn until it succeeds, fails with an error which is not retryable, or was called
+// the given number of times. The backoff between two calls starts at backoff and doubles every time,
+// the jitter fraction of it is randomized. Every retry is taken from the budget, it fails once the budget is used up.
+func retryTransient(attempts int, backoff time.Duration, jitter float64, budget *retryBudget, sleep func(time.Duration), retryable func(error) bool, fn func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || !retryable(err) || attempt >= attempts {
+			return err
+		}
+		if !budget.take() {
+			return fmt.Errorf("%w, not retrying: %w", errRetryBudgetExhausted, err)
+		}
+		wait := jittered(backoff, jitter, jitterRandom())
+		logger().Warn("Transient OCM failure, retrying", "in", wait.Round(time.Millisecond), "attempt", attempt+1, "attempts", attempts, "error", err)
+		sleep(wait)
+		backoff *= 2
+	}
+}
+
+// jittered returns the wait for the backoff: its jitter fraction is scaled by random, in [0, 1), and the rest
+// is kept. A jitter of 1 is the full jitter algorithm, a jitter of 0 waits exactly the backoff.
+func jittered(backoff time.Duration, jitter float64, random float64) time.Duration {
+	jitter = math.Min(math.Max(jitter, 0), 1)
+	fixed := float64(backoff) * (1 - jitter)
+	return time.Duration(fixed + float64(backoff)*jitter*random)
+}
+
+// isTransient reports whether err is worth retrying: OCM rate limiting or server side failures, and network errors
+func isTransient(err error) bool {
+	var ocmErr *ocmerrors.Error
+	if errors.As(err, &ocmErr) {
+		return ocmErr.Status() == http.StatusTooManyRequests || ocmErr.Status() >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// isRefused reports whether OCM refused the request without processing it: it was rate limited, or OCM was unavailable
+func isRefused(err error) bool {
+	var ocmErr *ocmerrors.Error
+	if errors.As(err, &ocmErr) {
+		return ocmErr.Status() == http.StatusTooManyRequests || ocmErr.Status() == http.StatusServiceUnavailable
+	}
+	return false
+}
+
+// readClusterIDsFile reads a file listing one cluster key per line. Empty lines and lines starting with '#' are ignored.
+func readClusterIDsFile(path string) ([]string, error) {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	return readClusterIDs(path, file)
+}
+
+// readClusterIDs reads one cluster key per line, skipping duplicates, with the parser of 'post --cluster-ids-file'
+// so that a file is read the same way by every command. Lines starting with '#' are ignored. The per-cluster
+// parameters of post are refused, they would be silently dropped. origin names the reader in error messages.
+func readClusterIDs(origin string, r io.Reader) ([]string, error) {
+	var clusterIDs []string
+	seen := map[string]bool{}
+	source := newIDsFileSource(origin, r)
+	for {
+		entry, err := source.next()
+		if errors.Is(err, io.EOF) {
+			return clusterIDs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source.position(), err)
+		}
+		if len(entry.Params) > 0 {
+			return nil, fmt.Errorf("%s: per-cluster parameters are only supported by 'post --cluster-ids-file'", source.position())
+		}
+		if seen[entry.ClusterID] {
+			continue
+		}
+		seen[entry.ClusterID] = true
+		clusterIDs = append(clusterIDs, entry.ClusterID)
+	}
+}
+
+// clusterIDArgs returns the cluster keys given as arguments. They are read from stdin instead when the
+// only argument is '-', or when there are no arguments and stdin is not a terminal, eg.
+//
+//	oc get ... | osdctl cluster support status -
+func clusterIDArgs(args []string, stdin *os.File) ([]string, error) {
+	if !(len(args) == 1 && args[0] == "-") && !(len(args) == 0 && !term.IsTerminal(int(stdin.Fd()))) {
+		return args, nil
+	}
+
+	clusterIDs, err := readClusterIDs("stdin", stdin)
+	if err != nil {
+		return nil, err
+	}
+	if len(clusterIDs) == 0 {
+		return nil, errors.New("no cluster IDs were given on stdin")
+	}
+
+	// stdin was consumed, read the answers to the confirmation prompts from the terminal, if any
+	if tty, err := os.Open("/dev/tty"); err == nil {
+		os.Stdin = tty
+	}
+	return clusterIDs, nil
+}
+
+// reasonRequestOptions describes a raw request to the limited support reasons API of a cluster
+type reasonRequestOptions struct {
+	// method is the HTTP method of the request, eg. http.MethodDelete
+	method    string
+	clusterID string
+	// reasonID targets a single reason, the collection of reasons of the cluster is targeted when it is empty
+	reasonID string
+}
+
+// createReasonRequest builds the request described by opts, so that the HTTP method is chosen by the
+// caller rather than hardcoded
+func createReasonRequest(ocmClient SDKConnection, opts reasonRequestOptions) (*sdk.Request, error) {
+	var request *sdk.Request
+	switch opts.method {
+	case http.MethodPost:
+		request = ocmClient.Post()
+	case http.MethodPatch:
+		request = ocmClient.Patch()
+	case http.MethodDelete:
+		request = ocmClient.Delete()
+	default:
+		return nil, fmt.Errorf("unsupported method %q for the limited support reasons API", opts.method)
+	}
+
+	targetAPIPath := reasonsAPIPath(opts.clusterID, opts.reasonID)
+	if err := arguments.ApplyPathArg(request, targetAPIPath); err != nil {
+		return nil, fmt.Errorf("cannot parse API path '%s': %v", targetAPIPath, err)
+	}
+	return request, nil
+}
+
+// reasonsAPIPath returns the path of the limited support reasons of the cluster, or of one of them
+func reasonsAPIPath(clusterID, reasonID string) string {
+	path := "/api/clusters_mgmt/v1/clusters/" + clusterID + "/limited_support_reasons"
+	if reasonID != "" {
+		path += "/" + reasonID
+	}
+	return path
+}
+
+// errorOutput is printed on stdout for every failing action with '-o json', so that automation can parse
+// failures the same way as successes
+type errorOutput struct {
+	Cluster string `json:"cluster"`
+	Error   string `json:"error"`
+	// Code is the OCM error code (eg. CLUSTERS-MGMT-404) when the failure comes from OCM
+	Code string `json:"code"`
+}
+
+func newErrorOutput(clusterID string, err error) errorOutput {
+	output := errorOutput{Cluster: clusterID, Error: err.Error(), Code: "error"}
+	var ocmErr *ocmerrors.Error
+	if errors.As(err, &ocmErr) && ocmErr.Code() != "" {
+		output.Code = ocmErr.Code()
+	}
+	return output
+}
+
+// reportError prints the error of an action on a cluster, as an errorOutput JSON line on stdout with
+// '-o json', or logs it otherwise
+func reportError(output string, clusterID string, err error) {
+	if output == "json" {
+		if encodeErr := json.NewEncoder(os.Stdout).Encode(newErrorOutput(clusterID, err)); encodeErr == nil {
+			return
+		}
+	}
+	logger().Error("Failed", "cluster", clusterID, "error", err)
 }
@@ -0,0 +1,243 @@
+package support
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	ocmerrors "github.com/openshift-online/ocm-sdk-go/errors"
+	"github.com/spf13/viper"
+)
+
+func Test_createReasonRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     reasonRequestOptions
+		wantPath string
+		wantErr  bool
+	}{
+		{
+			name:     "Delete a reason",
+			opts:     reasonRequestOptions{method: http.MethodDelete, clusterID: "abc", reasonID: "123"},
+			wantPath: "/api/clusters_mgmt/v1/clusters/abc/limited_support_reasons/123",
+		},
+		{
+			name:     "Post to the collection",
+			opts:     reasonRequestOptions{method: http.MethodPost, clusterID: "abc"},
+			wantPath: "/api/clusters_mgmt/v1/clusters/abc/limited_support_reasons",
+		},
+		{
+			name:     "Patch a reason",
+			opts:     reasonRequestOptions{method: http.MethodPatch, clusterID: "abc", reasonID: "123"},
+			wantPath: "/api/clusters_mgmt/v1/clusters/abc/limited_support_reasons/123",
+		},
+		{
+			name:    "Unsupported method",
+			opts:    reasonRequestOptions{method: http.MethodTrace, clusterID: "abc"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := createReasonRequest(&MockClient{}, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("createReasonRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && request.GetPath() != tt.wantPath {
+				t.Errorf("createReasonRequest() path = %q, want %q", request.GetPath(), tt.wantPath)
+			}
+		})
+	}
+}
+
+func Test_readClusterIDs(t *testing.T) {
+	got, err := readClusterIDs("stdin", strings.NewReader("# clusters\nabc\ndef\n\nabc\nghi\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"abc", "def", "ghi"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("readClusterIDs() = %v, want %v", got, want)
+	}
+
+	if _, err := readClusterIDs("stdin", strings.NewReader("abc\nbad'id\n")); err == nil || !strings.Contains(err.Error(), "stdin:2") {
+		t.Errorf("expected an error locating the invalid cluster ID, got %v", err)
+	}
+	// The lines are parsed like those of 'post --cluster-ids-file', whose parameters the other commands don't take
+	if _, err := readClusterIDs("clusters.txt", strings.NewReader("abc NAME=foo\n")); err == nil || !strings.Contains(err.Error(), "clusters.txt:1") {
+		t.Errorf("expected an error locating the per-cluster parameters, got %v", err)
+	}
+}
+
+func Test_newErrorOutput(t *testing.T) {
+	ocmErr, err := ocmerrors.NewError().Status(http.StatusNotFound).Code("CLUSTERS-MGMT-404").Reason("Cluster not found").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := newErrorOutput("abc", fmt.Errorf("can't retrieve cluster: %w", ocmErr))
+	if got.Cluster != "abc" || got.Code != "CLUSTERS-MGMT-404" || !strings.Contains(got.Error, "Cluster not found") {
+		t.Errorf("newErrorOutput() = %+v", got)
+	}
+
+	got = newErrorOutput("abc", errors.New("local failure"))
+	if got.Code != "error" || got.Error != "local failure" {
+		t.Errorf("newErrorOutput() = %+v", got)
+	}
+}
+
+func Test_retryTransient(t *testing.T) {
+	rateLimited, err := ocmerrors.NewError().Status(http.StatusTooManyRequests).Reason("Too many requests").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	notFound, err := ocmerrors.NewError().Status(http.StatusNotFound).Reason("Cluster not found").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantWaits []time.Duration
+		wantErr   bool
+	}{
+		{
+			name:      "Succeeds after rate limiting",
+			errs:      []error{fmt.Errorf("Can't retrieve subscription for key 'foo': %w", rateLimited), nil},
+			wantCalls: 2,
+			wantWaits: []time.Duration{time.Second},
+		},
+		{
+			name:      "Gives up after the last attempt",
+			errs:      []error{rateLimited, rateLimited, rateLimited},
+			wantCalls: 3,
+			wantWaits: []time.Duration{time.Second, 2 * time.Second},
+			wantErr:   true,
+		},
+		{
+			name:      "Doesn't retry errors which are not transient",
+			errs:      []error{notFound},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			var waits []time.Duration
+			err := retryTransient(3, time.Second, 0, nil, func(d time.Duration) { waits = append(waits, d) }, isTransient, func() error {
+				calls++
+				return tt.errs[calls-1]
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("retryTransient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("retryTransient() called fn %d times, want %d", calls, tt.wantCalls)
+			}
+			if !reflect.DeepEqual(waits, tt.wantWaits) {
+				t.Errorf("retryTransient() waited %v, want %v", waits, tt.wantWaits)
+			}
+		})
+	}
+}
+
+func Test_jittered(t *testing.T) {
+	tests := []struct {
+		jitter float64
+		random float64
+		want   time.Duration
+	}{
+		{jitter: 0, random: 0.5, want: 4 * time.Second},
+		{jitter: 1, random: 0, want: 0},
+		{jitter: 1, random: 0.25, want: time.Second},
+		{jitter: 0.5, random: 0.5, want: 3 * time.Second},
+		{jitter: 2, random: 0.5, want: 2 * time.Second},
+		{jitter: -1, random: 0.5, want: 4 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := jittered(4*time.Second, tt.jitter, tt.random); got != tt.want {
+			t.Errorf("jittered(4s, %v, %v) = %v, want %v", tt.jitter, tt.random, got, tt.want)
+		}
+	}
+}
+
+func Test_retryTransientBudget(t *testing.T) {
+	rateLimited, err := ocmerrors.NewError().Status(http.StatusTooManyRequests).Reason("Too many requests").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	budget := &retryBudget{remaining: 3}
+	var calls int
+	fail := func() error {
+		calls++
+		return rateLimited
+	}
+	noSleep := func(time.Duration) {}
+
+	// The first cluster uses 2 retries of its 3 attempts, the second one only gets the last retry of the batch
+	if err := retryTransient(3, time.Second, 0, budget, noSleep, isTransient, fail); err == nil || errors.Is(err, errRetryBudgetExhausted) {
+		t.Fatalf("retryTransient() error = %v, want the OCM error", err)
+	}
+	err = retryTransient(3, time.Second, 0, budget, noSleep, isTransient, fail)
+	if !errors.Is(err, errRetryBudgetExhausted) {
+		t.Fatalf("retryTransient() error = %v, want %v", err, errRetryBudgetExhausted)
+	}
+	if calls != 5 {
+		t.Errorf("retryTransient() called fn %d times, want 5", calls)
+	}
+	if budget.take() {
+		t.Errorf("the budget should be used up")
+	}
+}
+
+func Test_postRetriesTakenFromBudget(t *testing.T) {
+	viper.Set(HistoryFileKey, filepath.Join(t.TempDir(), "history.jsonl"))
+	defer viper.Set(HistoryFileKey, "")
+	retrySleep = func(time.Duration) {}
+	defer func() { retrySleep = time.Sleep }()
+
+	const reasons = "POST /api/clusters_mgmt/v1/clusters/cluster-id/limited_support_reasons"
+	fake, connection := newFakeOCM(t, map[string]http.HandlerFunc{
+		reasons: respond(http.StatusServiceUnavailable, `{"kind":"Error","reason":"Unavailable"}`),
+	})
+	cluster, err := cmv1.NewCluster().ID("cluster-id").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Post{
+		Template:        "template.json",
+		templateBytes:   []byte(`{"summary":"Summary","details":"Details","detection_type":"manual"}`),
+		cluster:         cluster,
+		clusterPrepared: true,
+		retries:         &retryBudget{remaining: 2},
+	}
+
+	// The post is retried twice, not the 3 times of its attempts, as the batch is out of retries by then
+	_, err = p.postToCluster(connection, "cluster-id", nil, false)
+	if !errors.Is(err, errRetryBudgetExhausted) {
+		t.Fatalf("postToCluster() error = %v, want %v", err, errRetryBudgetExhausted)
+	}
+	if n := fake.count(reasons); n != 3 {
+		t.Errorf("the reason was posted %d time(s), want 3", n)
+	}
+
+	// A server failure may happen after the reason was created, it is not retried
+	fake.routes[reasons] = respond(http.StatusInternalServerError, `{"kind":"Error","reason":"Internal error"}`)
+	p.retries = &retryBudget{remaining: 2}
+	if _, err := p.postToCluster(connection, "cluster-id", nil, false); err == nil {
+		t.Fatal("postToCluster() succeeded, want the OCM error")
+	}
+	if n := fake.count(reasons); n != 4 {
+		t.Errorf("the reason was posted %d time(s), want 4", n)
+	}
+}
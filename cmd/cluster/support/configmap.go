@@ -0,0 +1,45 @@
+package support
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// configMapScheme prefixes templates read from a ConfigMap, eg. 'configmap://namespace/name/key'
+const configMapScheme = "configmap://"
+
+// parseConfigMapURI splits a 'configmap://namespace/name/key' URI into its parts
+func parseConfigMapURI(uri string) (namespace, name, key string, err error) {
+	parts := strings.Split(strings.TrimPrefix(uri, configMapScheme), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", fmt.Errorf("invalid ConfigMap template %q, expected %snamespace/name/key", uri, configMapScheme)
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+// readConfigMap returns the value of a ConfigMap key, using the current kubeconfig context
+func (p *Post) readConfigMap(uri string) ([]byte, error) {
+	namespace, name, key, err := parseConfigMapURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	if p.kubeCli == nil {
+		return nil, fmt.Errorf("cannot read %q, no Kubernetes client available", uri)
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := p.kubeCli.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, configMap); err != nil {
+		return nil, fmt.Errorf("cannot get the ConfigMap %s/%s: %w", namespace, name, err)
+	}
+	if value, ok := configMap.Data[key]; ok {
+		return []byte(value), nil
+	}
+	if value, ok := configMap.BinaryData[key]; ok {
+		return value, nil
+	}
+	return nil, fmt.Errorf("the ConfigMap %s/%s has no key %q", namespace, name, key)
+}
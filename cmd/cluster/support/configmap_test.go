@@ -0,0 +1,49 @@
+package support
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_readConfigMap(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("could not add corev1 to scheme: %v", err)
+	}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "sre", Name: "templates"},
+		Data:       map[string]string{"ingress.json": `{"summary":"foo"}`},
+		BinaryData: map[string][]byte{"egress.json": []byte(`{"summary":"bar"}`)},
+	}
+	p := &Post{kubeCli: fake.NewClientBuilder().WithScheme(scheme).WithObjects(configMap).Build()}
+
+	tests := []struct {
+		name    string
+		uri     string
+		want    string
+		wantErr bool
+	}{
+		{name: "data key", uri: "configmap://sre/templates/ingress.json", want: `{"summary":"foo"}`},
+		{name: "binary data key", uri: "configmap://sre/templates/egress.json", want: `{"summary":"bar"}`},
+		{name: "missing key", uri: "configmap://sre/templates/other.json", wantErr: true},
+		{name: "missing ConfigMap", uri: "configmap://sre/other/ingress.json", wantErr: true},
+		{name: "missing namespace", uri: "configmap://templates/ingress.json", wantErr: true},
+		{name: "empty part", uri: "configmap://sre//ingress.json", wantErr: true},
+		{name: "too many parts", uri: "configmap://sre/templates/ingress.json/extra", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.accessFile(tt.uri)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("accessFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("accessFile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package support
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	ctlutil "github.com/openshift/osdctl/pkg/utils"
+	"github.com/spf13/viper"
+)
+
+// confirmData holds the variables --confirm-message can use, eg. '{{.ClusterName}}'
+type confirmData struct {
+	ClusterID   string
+	ClusterName string
+	Summary     string
+}
+
+// renderConfirmMessage executes the --confirm-message template with the data of the post being confirmed
+func renderConfirmMessage(text string, data confirmData) (string, error) {
+	tmpl, err := template.New("confirm-message").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid --confirm-message: %w", err)
+	}
+	var message strings.Builder
+	if err := tmpl.Execute(&message, data); err != nil {
+		return "", fmt.Errorf("invalid --confirm-message: %w", err)
+	}
+	return message.String(), nil
+}
+
+// confirmPost prompts the user to continue with --confirm-message, or with the default question when unset
+func (p *Post) confirmPost(data confirmData) (bool, error) {
+	// A batch is confirmed once for all of its clusters, it has no cluster ID
+	if p.showAlerts && data.ClusterID != "" {
+		p.printFiringAlerts(p.previewOut(), data.ClusterID)
+	}
+	var message string
+	if p.confirmTemplate != "" {
+		var err error
+		if message, err = renderConfirmMessage(p.confirmTemplate, data); err != nil {
+			return false, err
+		}
+	}
+	return ctlutil.ConfirmMessagePromptWithTimeoutTo(p.previewOut(), message, viper.GetDuration(ConfirmTimeoutKey))
+}
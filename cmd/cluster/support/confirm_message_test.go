@@ -0,0 +1,28 @@
+package support
+
+import "testing"
+
+func Test_renderConfirmMessage(t *testing.T) {
+	data := confirmData{ClusterID: "1a2b3c", ClusterName: "my-cluster", Summary: "Cloud misconfiguration"}
+	tests := []struct {
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{text: "Place {{.ClusterName}} ({{.ClusterID}}) in limited support for {{.Summary}}?", want: "Place my-cluster (1a2b3c) in limited support for Cloud misconfiguration?"},
+		{text: "Proceed?", want: "Proceed?"},
+		{text: "Post to {{.Cluster}}?", wantErr: true},
+		{text: "Post to {{.ClusterName?", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := renderConfirmMessage(tt.text, data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderConfirmMessage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("renderConfirmMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
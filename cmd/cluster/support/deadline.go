@@ -0,0 +1,54 @@
+package support
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// parseDeadline parses the value of --deadline, either a duration from now (eg. '2m30s') or an
+// absolute RFC3339 time (eg. '2024-05-01T22:00:00Z')
+func parseDeadline(value string, now time.Time) (time.Time, error) {
+	if duration, err := time.ParseDuration(value); err == nil {
+		if duration <= 0 {
+			return time.Time{}, fmt.Errorf("--deadline %q must be a positive duration", value)
+		}
+		return now.Add(duration), nil
+	}
+	deadline, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("--deadline %q is neither a duration (eg. '2m30s') nor an RFC3339 time (eg. '2024-05-01T22:00:00Z')", value)
+	}
+	if !deadline.After(now) {
+		return time.Time{}, fmt.Errorf("--deadline %s has already passed", deadline.Format(time.RFC3339))
+	}
+	return deadline, nil
+}
+
+// deadlinePassed is true once --deadline has passed. It is only checked between clusters: the requests of a
+// cluster which was started all go through, so that its reason is never posted without its service log.
+func (p *Post) deadlinePassed() bool {
+	return p.ctx != nil && p.ctx.Err() != nil
+}
+
+// reportUnprocessed drains the source and prints the clusters that were not processed because the deadline passed
+func reportUnprocessed(source clusterSource) int {
+	var unprocessed []string
+	for {
+		entry, err := source.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			unprocessed = append(unprocessed, source.position())
+			continue
+		}
+		unprocessed = append(unprocessed, entry.ClusterID)
+	}
+	if len(unprocessed) > 0 {
+		logger().Warn("The deadline passed before every cluster was processed", "unprocessed", len(unprocessed), "clusters", strings.Join(unprocessed, ", "))
+	}
+	return len(unprocessed)
+}
@@ -0,0 +1,96 @@
+package support
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/spf13/viper"
+)
+
+func Test_parseDeadline(t *testing.T) {
+	now := time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "2m30s", want: now.Add(150 * time.Second)},
+		{value: "2024-05-01T22:00:00Z", want: time.Date(2024, 5, 1, 22, 0, 0, 0, time.UTC)},
+		{value: "0s", wantErr: true},
+		{value: "2024-05-01T19:00:00Z", wantErr: true},
+		{value: "tonight", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseDeadline(tt.value, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDeadline() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseDeadline() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_runBatchDeadline(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	source := &sliceSource{origin: "for the test", entries: []*clusterEntry{{ClusterID: "cluster-a"}, {ClusterID: "cluster-b"}}}
+	p := &Post{
+		Template:      "template.json",
+		isDryRun:      true,
+		ctx:           ctx,
+		templateBytes: []byte(`{"summary":"Summary","details":"Details","detection_type":"manual"}`),
+	}
+	if err := p.runBatch(nil, source); err == nil || !strings.Contains(err.Error(), "deadline") {
+		t.Errorf("runBatch() error = %v, want the deadline to be reported", err)
+	}
+	if source.index != len(source.entries) {
+		t.Errorf("runBatch() went through %d cluster(s), want every cluster reported as unprocessed", source.index)
+	}
+}
+
+func Test_deadlinePassesDuringCluster(t *testing.T) {
+	viper.Set(HistoryFileKey, filepath.Join(t.TempDir(), "history.jsonl"))
+	defer viper.Set(HistoryFileKey, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	const serviceLogs = "POST /api/service_logs/v1/cluster_logs"
+	fake, connection := newFakeOCM(t, map[string]http.HandlerFunc{
+		"POST /api/clusters_mgmt/v1/clusters/cluster-id/limited_support_reasons": func(w http.ResponseWriter, r *http.Request) {
+			// The deadline passes once the reason is posted
+			cancel()
+			respond(http.StatusCreated, `{"kind":"LimitedSupportReason","id":"reason-id","summary":"Summary","details":"Details"}`)(w, r)
+		},
+		serviceLogs: respond(http.StatusCreated, `{"kind":"ClusterLog","id":"log-id"}`),
+	})
+	cluster, err := cmv1.NewCluster().ID("cluster-id").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Post{
+		Template:        "template.json",
+		templateBytes:   []byte(`{"summary":"Summary","details":"Details","detection_type":"manual"}`),
+		Evidence:        "See OHSS-1234",
+		ctx:             ctx,
+		cluster:         cluster,
+		clusterPrepared: true,
+	}
+	if _, err := p.postToCluster(connection, "cluster-id", nil, false); err != nil {
+		t.Errorf("postToCluster() = %v, want the cluster to be finished once started", err)
+	}
+	if n := fake.count(serviceLogs); n != 1 {
+		t.Errorf("%d internal service log(s) were sent, want the evidence of the posted reason", n)
+	}
+	if !p.deadlinePassed() {
+		t.Errorf("deadlinePassed() = false after the deadline passed")
+	}
+}
@@ -2,11 +2,11 @@ package support
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 
-	"github.com/openshift-online/ocm-cli/pkg/arguments"
 	sdk "github.com/openshift-online/ocm-sdk-go"
 	v1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
 	"github.com/openshift/osdctl/internal/support"
@@ -22,6 +22,7 @@ type deleteOptions struct {
 	output                 string
 	verbose                bool
 	clusterID              string
+	clusterIDs             []string
 	limitedSupportReasonID string
 	removeAll              bool
 	isDryRun               bool
@@ -34,9 +35,10 @@ func newCmddelete(streams genericclioptions.IOStreams, globalOpts *globalflags.G
 
 	ops := newDeleteOptions(streams, globalOpts)
 	deleteCmd := &cobra.Command{
-		Use:               "delete CLUSTER_ID",
+		Use:               "delete [CLUSTER_ID|-]",
 		Short:             "Delete specified limited support reason for a given cluster",
-		Args:              cobra.ExactArgs(1),
+		Long:              "Delete specified limited support reason for a given cluster. Cluster IDs can also be piped on stdin, one or more per line, by passing '-' or no cluster ID.",
+		Args:              cobra.MaximumNArgs(1),
 		DisableAutoGenTag: true,
 		Run: func(cmd *cobra.Command, args []string) {
 			cmdutil.CheckErr(ops.complete(cmd, args))
@@ -63,21 +65,45 @@ func newDeleteOptions(streams genericclioptions.IOStreams, globalOpts *globalfla
 
 func (o *deleteOptions) complete(cmd *cobra.Command, args []string) error {
 
-	if len(args) != 1 {
+	clusterIDs, err := clusterIDArgs(args, os.Stdin)
+	if err != nil {
+		return err
+	}
+	if len(clusterIDs) == 0 {
 		return cmdutil.UsageErrorf(cmd, "Provide exactly one internal cluster ID")
 	}
 
 	if o.limitedSupportReasonID != "" && o.removeAll {
 		return cmdutil.UsageErrorf(cmd, "Cannot provide a reason ID with the `all` flag. Please provide one or the other.")
 	}
+	if o.limitedSupportReasonID != "" && len(clusterIDs) > 1 {
+		return cmdutil.UsageErrorf(cmd, "Cannot provide a reason ID when deleting from several clusters. Use the `all` flag instead.")
+	}
 
-	o.clusterID = args[0]
+	o.clusterIDs = clusterIDs
 	o.output = o.GlobalOptions.Output
 
 	return nil
 }
 
 func (o *deleteOptions) run() error {
+	var errs []error
+	// runCluster picks the reason to delete when a cluster has a single one, start afresh for every cluster
+	limitedSupportReasonID := o.limitedSupportReasonID
+	for _, clusterID := range o.clusterIDs {
+		o.clusterID = clusterID
+		o.limitedSupportReasonID = limitedSupportReasonID
+		if err := o.runCluster(); err != nil {
+			if o.output == "json" {
+				reportError(o.output, clusterID, err)
+			}
+			errs = append(errs, fmt.Errorf("cluster %s: %w", clusterID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (o *deleteOptions) runCluster() error {
 
 	// Check that the cluster key (name, identifier or external identifier) given by the user
 	// is reasonably safe so that there is no risk of SQL injection
@@ -87,7 +113,7 @@ func (o *deleteOptions) run() error {
 	}
 
 	// Create an OCM client to talk to the cluster API
-	connection, err := ctlutil.CreateConnection()
+	connection, err := createConnection()
 	if err != nil {
 		return err
 	}
@@ -104,12 +130,12 @@ func (o *deleteOptions) run() error {
 	}
 
 	// confirmSend prompt to confirm
-	if !utils.ConfirmPrompt() {
-		return nil
+	if ok, err := confirm(); !ok {
+		return err
 	}
 
 	//getting the cluster
-	cluster, err := ctlutil.GetCluster(connection, o.clusterID)
+	cluster, err := resolveCluster(connection, o.clusterID, nil)
 	if err != nil {
 		return fmt.Errorf("Can't retrieve cluster: %v\n", err)
 	}
@@ -117,7 +143,10 @@ func (o *deleteOptions) run() error {
 	/*conditions to check the presence of --all & -i flags;
 	also, checking if there is one or more limited support resonID before deleting the same */
 	var limitedSupportReasonIds []string
-	limitedSupportReasons, err := getLimitedSupportReasons(o.clusterID)
+	_, limitedSupportReasons, err := getLimitedSupportReasons(o.clusterID)
+	if err != nil {
+		return err
+	}
 
 	if len(limitedSupportReasons) == 0 {
 		return fmt.Errorf("Cluster is not in limited support. \n")
@@ -127,19 +156,30 @@ func (o *deleteOptions) run() error {
 		if !o.removeAll && o.limitedSupportReasonID == "" {
 			return fmt.Errorf("This cluster has multiple limited support reason IDs.\nPlease specify the exact reason ID or the `all` flag \n")
 		}
-		for _, limitedSupportReason := range limitedSupportReasons {
-			err = deleteLimitedSupportReason(connection, cluster, limitedSupportReason.ID())
-		}
+		limitedSupportReasonIds = limitedSupportReasonsIDs(limitedSupportReasons)
 	} else {
 		if len(limitedSupportReasons) == 1 {
 			o.limitedSupportReasonID = limitedSupportReasons[0].ID()
 		}
 		limitedSupportReasonIds = append(limitedSupportReasonIds, o.limitedSupportReasonID)
-		for _, limitedSupportReasonId := range limitedSupportReasonIds {
-			err = deleteLimitedSupportReason(connection, cluster, limitedSupportReasonId)
+	}
+
+	// Keep deleting the remaining reasons when one fails, but report every failure
+	var errs []error
+	for _, limitedSupportReasonId := range limitedSupportReasonIds {
+		if err := deleteLimitedSupportReason(connection, cluster, limitedSupportReasonId); err != nil {
+			errs = append(errs, fmt.Errorf("reason %s: %w", limitedSupportReasonId, err))
 		}
 	}
-	return err
+	return errors.Join(errs...)
+}
+
+func limitedSupportReasonsIDs(limitedSupportReasons []*v1.LimitedSupportReason) []string {
+	ids := make([]string, 0, len(limitedSupportReasons))
+	for _, limitedSupportReason := range limitedSupportReasons {
+		ids = append(ids, limitedSupportReason.ID())
+	}
+	return ids
 }
 
 func deleteLimitedSupportReason(connection SDKConnection, cluster *v1.Cluster, reasonID string) (err error) {
@@ -163,15 +203,11 @@ func deleteLimitedSupportReason(connection SDKConnection, cluster *v1.Cluster, r
 // SDKConnection is an interface that is satisfied by the sdk.Connection and by our mock connection
 // this facilitates unit test and allow us to mock Post() and Delete() api calls
 func createDeleteRequest(ocmClient SDKConnection, cluster *v1.Cluster, reasonID string) (request *sdk.Request, err error) {
-
-	targetAPIPath := "/api/clusters_mgmt/v1/clusters/" + cluster.ID() + "/limited_support_reasons/" + reasonID
-
-	request = ocmClient.Delete()
-	err = arguments.ApplyPathArg(request, targetAPIPath)
-	if err != nil {
-		return nil, fmt.Errorf("cannot parse API path '%s': %v", targetAPIPath, err)
-	}
-	return request, nil
+	return createReasonRequest(ocmClient, reasonRequestOptions{
+		method:    http.MethodDelete,
+		clusterID: cluster.ID(),
+		reasonID:  reasonID,
+	})
 }
 
 // checkDelete checks the response from delete API call
@@ -185,12 +221,12 @@ func checkDelete(response *sdk.Response) error {
 		return nil
 	}
 
-	if ok := json.Valid(body); !ok {
-		return fmt.Errorf("server returned invalid JSON")
+	if err := utils.CheckJSONResponse(response); err != nil {
+		return err
 	}
 
 	if err := json.Unmarshal(body, &badReply); err != nil {
 		return fmt.Errorf("cannot parse the error JSON meessage: %q", err)
 	}
-	return nil
+	return fmt.Errorf("server returned status %d: %s", response.Status(), badReply.Reason)
 }
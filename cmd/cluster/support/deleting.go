@@ -0,0 +1,115 @@
+package support
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/openshift/osdctl/pkg/printer"
+	ctlutil "github.com/openshift/osdctl/pkg/utils"
+)
+
+// errPendingDeletion is wrapped by the errors of the clusters skipped by --exclude-deleting
+var errPendingDeletion = errors.New("the cluster is pending deletion")
+
+// deletionLookupSize is the number of clusters whose deletion state is looked up with a single search
+const deletionLookupSize = 50
+
+// skippedCluster is true for the errors of the clusters which were skipped rather than failed
+func skippedCluster(err error) bool {
+	return errors.Is(err, errAlreadyInLimitedSupport) || errors.Is(err, errPendingDeletion) || errors.Is(err, errDuplicateCluster)
+}
+
+// pendingDeletion describes why the cluster is about to be deleted, it is empty when it isn't: the cluster is
+// uninstalling, or it has an expiration timestamp after which OCM deletes it
+func pendingDeletion(cluster *cmv1.Cluster) string {
+	if cluster.State() == cmv1.ClusterStateUninstalling {
+		return "is uninstalling"
+	}
+	if expiration, ok := cluster.GetExpirationTimestamp(); ok && !expiration.IsZero() {
+		return "is scheduled for deletion at " + expiration.UTC().Format(time.RFC3339)
+	}
+	return ""
+}
+
+// checkDeletion warns about clusters pending deletion, posting to them is wasteful. They are skipped with
+// --exclude-deleting.
+func (p *Post) checkDeletion(cluster *cmv1.Cluster) error {
+	deletion := pendingDeletion(cluster)
+	if deletion == "" {
+		return nil
+	}
+	if p.excludeDeleting {
+		return fmt.Errorf("%w: cluster %s %s", errPendingDeletion, cluster.ID(), deletion)
+	}
+	logger().Warn("The cluster is pending deletion, use --exclude-deleting to skip it", "cluster", cluster.ID(), "deletion", deletion)
+	return nil
+}
+
+// previewPendingDeletion flags the clusters of the batch which are pending deletion, so that they can be reviewed
+// before posting. With --exclude-deleting they are removed from the batch. The clusters are looked up by the
+// keys of the batch, which can be their names and external IDs too. Only the clusters read ahead of the
+// confirmation are looked up, those streamed afterwards are checked when posting to them.
+func (p *Post) previewPendingDeletion(connection *sdk.Connection, source *bufferedSource) error {
+	keys := source.clusterKeys()
+	var deleting []*cmv1.Cluster
+	for start := 0; start < len(keys); start += deletionLookupSize {
+		end := start + deletionLookupSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		quoted := make([]string, 0, end-start)
+		for _, key := range keys[start:end] {
+			quoted = append(quoted, "'"+key+"'")
+		}
+		list := strings.Join(quoted, ", ")
+		var clusters []*cmv1.Cluster
+		err := retryOCM(p.retries, func() error {
+			var err error
+			clusters, err = ctlutil.ApplyFilters(connection, []string{fmt.Sprintf("id in (%[1]s) or external_id in (%[1]s) or name in (%[1]s)", list)})
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("cannot look up whether the clusters are pending deletion: %w", err)
+		}
+		for _, cluster := range clusters {
+			if pendingDeletion(cluster) != "" {
+				deleting = append(deleting, cluster)
+			}
+		}
+	}
+	if len(deleting) == 0 {
+		return nil
+	}
+
+	if p.excludeDeleting {
+		fmt.Fprintln(p.previewOut(), "The following clusters are pending deletion and are excluded because of --exclude-deleting:")
+	} else {
+		fmt.Fprintln(p.previewOut(), "The following clusters are pending deletion, use --exclude-deleting to skip them:")
+	}
+	table := printer.NewTablePrinter(p.previewOut(), 20, 1, 3, ' ')
+	table.AddRow([]string{"Cluster ID", "Name", "Deletion"})
+	excluded := map[string]bool{}
+	for _, cluster := range deleting {
+		table.AddRow([]string{cluster.ID(), cluster.Name(), pendingDeletion(cluster)})
+		excluded[cluster.ID()] = true
+		excluded[cluster.ExternalID()] = true
+		excluded[cluster.Name()] = true
+	}
+	// Add empty row for readability
+	table.AddRow([]string{})
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("could not print the clusters pending deletion: %w", err)
+	}
+
+	if p.excludeDeleting {
+		source.exclude(excluded)
+		if source.clusters == 0 && !source.more {
+			return errors.New("every selected cluster is pending deletion, there is nothing to post to")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package support
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+)
+
+func Test_checkDeletion(t *testing.T) {
+	expiration := time.Date(2026, 10, 20, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		cluster  *cmv1.ClusterBuilder
+		deletion string
+	}{
+		{name: "ready", cluster: cmv1.NewCluster().ID("c1").State(cmv1.ClusterStateReady)},
+		{name: "uninstalling", cluster: cmv1.NewCluster().ID("c1").State(cmv1.ClusterStateUninstalling), deletion: "is uninstalling"},
+		{name: "expiring", cluster: cmv1.NewCluster().ID("c1").State(cmv1.ClusterStateReady).ExpirationTimestamp(expiration), deletion: "is scheduled for deletion at 2026-10-20T12:00:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster, err := tt.cluster.Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := pendingDeletion(cluster); got != tt.deletion {
+				t.Errorf("pendingDeletion() = %q, want %q", got, tt.deletion)
+			}
+			// Without --exclude-deleting the cluster is only warned about
+			if err := (&Post{}).checkDeletion(cluster); err != nil {
+				t.Errorf("checkDeletion() = %v, want nil without --exclude-deleting", err)
+			}
+			err = (&Post{excludeDeleting: true}).checkDeletion(cluster)
+			if skipped := errors.Is(err, errPendingDeletion); skipped != (tt.deletion != "") {
+				t.Errorf("checkDeletion() with --exclude-deleting = %v", err)
+			}
+			if err != nil && !skippedCluster(err) {
+				t.Errorf("skippedCluster(%v) = false, want the cluster to be skipped rather than failed", err)
+			}
+		})
+	}
+}
+
+func Test_previewPendingDeletionByName(t *testing.T) {
+	// The cluster listed by name is uninstalling, the search matches it by any of its keys
+	_, connection := newFakeOCM(t, map[string]http.HandlerFunc{
+		"GET /api/clusters_mgmt/v1/clusters": respond(http.StatusOK, `{"kind":"ClusterList","page":1,"size":1,"total":1,"items":[
+			{"kind":"Cluster","id":"cluster-b-id","name":"cluster-b","external_id":"cluster-b-uuid","state":"uninstalling"}]}`),
+	})
+	source := bufferSource(newIDsFileSource("clusters.txt", strings.NewReader("cluster-a\ncluster-b\n")), batchReadAhead)
+	p := &Post{excludeDeleting: true, output: "json"}
+	if err := p.previewPendingDeletion(connection, source); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"cluster-a"}; !reflect.DeepEqual(source.clusterKeys(), want) {
+		t.Errorf("previewPendingDeletion() left %v in the batch, want %v", source.clusterKeys(), want)
+	}
+}
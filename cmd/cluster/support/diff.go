@@ -0,0 +1,187 @@
+package support
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/openshift/osdctl/internal/utils/globalflags"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+type diffOptions struct {
+	oldTemplate     string
+	newTemplate     string
+	templateParams  []string
+	paramsFiles     []string
+	allowUnresolved bool
+
+	genericclioptions.IOStreams
+	GlobalOptions *globalflags.GlobalOptions
+}
+
+// newCmddiff implements the diff command to compare two templates once rendered, without talking to OCM
+func newCmddiff(streams genericclioptions.IOStreams, globalOpts *globalflags.GlobalOptions) *cobra.Command {
+	ops := newDiffOptions(streams, globalOpts)
+	diffCmd := &cobra.Command{
+		Use:   "diff OLD_TEMPLATE NEW_TEMPLATE",
+		Short: "Show how two templates differ once rendered",
+		Long: `Renders two templates with the same parameters, exactly like 'post' does, and prints the difference of each field of the resulting limited support reasons.
+This is an authoring aid to review changes to templates, it never talks to OCM.`,
+		Example: `  # Review a change to a template
+  osdctl cluster support diff old.json new.json -p FOO=BAR`,
+		Args:              cobra.ExactArgs(2),
+		DisableAutoGenTag: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(ops.complete(cmd, args))
+			cmdutil.CheckErr(ops.run())
+		},
+	}
+
+	diffCmd.Flags().StringArrayVarP(&ops.templateParams, "param", "p", nil, "Specify a key-value pair (eg. -p FOO=BAR) to set/override a parameter value in both templates.")
+	diffCmd.Flags().StringArrayVar(&ops.paramsFiles, "params-file", nil, "(optional) YAML, JSON, TOML (.toml) or HCL (.hcl, .tfvars) file of parameters (eg. 'FOO: BAR') shared by both templates. Can be repeated, '-p' takes precedence. Also accepted as --"+templateVarFileFlag+".")
+	diffCmd.Flags().SetNormalizeFunc(paramsFileAlias)
+	diffCmd.Flags().BoolVar(&ops.allowUnresolved, "allow-unresolved", false, "Allow '${...}' sequences to remain in the rendered reasons, to compare templates without setting all of their parameters.")
+
+	return diffCmd
+}
+
+func newDiffOptions(streams genericclioptions.IOStreams, globalOpts *globalflags.GlobalOptions) *diffOptions {
+	return &diffOptions{
+		IOStreams:     streams,
+		GlobalOptions: globalOpts,
+	}
+}
+
+func (o *diffOptions) complete(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return cmdutil.UsageErrorf(cmd, "Provide the old and the new template")
+	}
+	o.oldTemplate, o.newTemplate = args[0], args[1]
+	return nil
+}
+
+func (o *diffOptions) run() error {
+	oldReason, err := o.render(o.oldTemplate)
+	if err != nil {
+		return err
+	}
+	newReason, err := o.render(o.newTemplate)
+	if err != nil {
+		return err
+	}
+
+	diff, err := diffReasons(o.oldTemplate, o.newTemplate, oldReason, newReason)
+	if err != nil {
+		return err
+	}
+	if diff == "" {
+		fmt.Fprintln(o.Out, "The rendered limited support reasons are identical")
+		return nil
+	}
+	fmt.Fprint(o.Out, diff)
+	return nil
+}
+
+// render renders the template exactly like 'post' does
+func (o *diffOptions) render(template string) (*cmv1.LimitedSupportReason, error) {
+	p := &Post{
+		Template:        template,
+		TemplateParams:  o.templateParams,
+		ParamsFiles:     o.paramsFiles,
+		allowUnresolved: o.allowUnresolved,
+		// The parameters are shared, a parameter may only be used by one of the templates
+		ignoreUnusedParams: true,
+	}
+	reason, err := p.buildLimitedSupportTemplate(nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot render %s: %w", template, err)
+	}
+	return reason, nil
+}
+
+// diffReasons returns a unified diff of every field which differs between the reasons, in the order of their
+// names. It is empty when the reasons are identical.
+func diffReasons(oldName string, newName string, oldReason *cmv1.LimitedSupportReason, newReason *cmv1.LimitedSupportReason) (string, error) {
+	oldFields, err := reasonFields(oldReason)
+	if err != nil {
+		return "", err
+	}
+	newFields, err := reasonFields(newReason)
+	if err != nil {
+		return "", err
+	}
+
+	names := map[string]bool{}
+	for name := range oldFields {
+		names[name] = true
+	}
+	for name := range newFields {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var out strings.Builder
+	for _, name := range sorted {
+		if oldFields[name] == newFields[name] {
+			continue
+		}
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        fieldLines(oldFields[name]),
+			B:        fieldLines(newFields[name]),
+			FromFile: fmt.Sprintf("%s (%s)", oldName, name),
+			ToFile:   fmt.Sprintf("%s (%s)", newName, name),
+			Context:  3,
+		})
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(diff)
+	}
+	return out.String(), nil
+}
+
+// reasonFields returns the fields of the reason as they are sent to OCM. Strings are kept as is so that
+// multi-line details are compared line by line, other values are indented JSON.
+func reasonFields(reason *cmv1.LimitedSupportReason) (map[string]string, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(marshalReason(reason), &raw); err != nil {
+		return nil, fmt.Errorf("failed to marshal limited support reason: %w", err)
+	}
+
+	fields := map[string]string{}
+	for name, value := range raw {
+		// The kind is the same for every reason
+		if name == "kind" {
+			continue
+		}
+		var text string
+		if err := json.Unmarshal(value, &text); err == nil {
+			fields[name] = text
+			continue
+		}
+		indented := bytes.Buffer{}
+		if err := json.Indent(&indented, value, "", "  "); err != nil {
+			return nil, err
+		}
+		fields[name] = indented.String()
+	}
+	return fields, nil
+}
+
+func fieldLines(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return difflib.SplitLines(value)
+}
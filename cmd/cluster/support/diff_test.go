@@ -0,0 +1,65 @@
+package support
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+func Test_diffTemplates(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	oldTemplate := write("old.json", `{"summary":"Summary","details":"Restore ${RULE} please","detection_type":"manual"}`)
+	newTemplate := write("new.json", `{"summary":"Summary","details":"Restore ${RULE} to continue","detection_type":"auto"}`)
+
+	out := &bytes.Buffer{}
+	o := &diffOptions{
+		oldTemplate:    oldTemplate,
+		newTemplate:    newTemplate,
+		templateParams: []string{"RULE=the rule"},
+		IOStreams:      genericclioptions.IOStreams{Out: out},
+	}
+	if err := o.run(); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"--- " + oldTemplate + " (details)",
+		"-Restore the rule please",
+		"+Restore the rule to continue",
+		"--- " + oldTemplate + " (detection_type)",
+		"-manual",
+		"+auto",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("diff output is missing %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "(summary)") {
+		t.Errorf("the unchanged summary should not be part of the diff:\n%s", out.String())
+	}
+
+	out.Reset()
+	o.newTemplate = oldTemplate
+	if err := o.run(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "The rendered limited support reasons are identical\n" {
+		t.Errorf("diff of identical templates = %q", out.String())
+	}
+
+	// Both templates still have to render
+	o.templateParams = nil
+	if err := o.run(); err == nil {
+		t.Error("run() should fail when a parameter is not set")
+	}
+}
@@ -0,0 +1,52 @@
+package support
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	ctlutil "github.com/openshift/osdctl/pkg/utils"
+)
+
+// execFilterTimeout bounds how long the --exec-filter command may run for a single reason
+const execFilterTimeout = time.Minute
+
+// execFilter pipes the JSON of the rendered reason through the shell command given with --exec-filter,
+// and returns the reason the command printed on its standard output
+func (p *Post) execFilter(reason *cmv1.LimitedSupportReason) (*cmv1.LimitedSupportReason, error) {
+	var input bytes.Buffer
+	if err := cmv1.MarshalLimitedSupportReason(reason, &input); err != nil {
+		return nil, fmt.Errorf("cannot marshal limited support reason for --exec-filter: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), execFilterTimeout)
+	defer cancel()
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", p.ExecFilter) //#nosec G204 -- the filter is explicitly given by the user
+	cmd.Stdin = &input
+	cmd.Stdout = &output
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("--exec-filter %q failed: %w", p.ExecFilter, err)
+	}
+
+	if !json.Valid(output.Bytes()) {
+		return nil, fmt.Errorf("--exec-filter %q did not print valid JSON: %q", p.ExecFilter, ctlutil.Truncate(output.String(), 200))
+	}
+	filtered, err := cmv1.UnmarshalLimitedSupportReason(output.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("--exec-filter %q did not print a limited support reason: %w", p.ExecFilter, err)
+	}
+	if filtered.Summary() == "" || filtered.Details() == "" {
+		return nil, errors.New("the limited support reason printed by --exec-filter must have a summary and details")
+	}
+
+	return filtered, nil
+}
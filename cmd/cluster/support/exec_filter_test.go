@@ -0,0 +1,60 @@
+package support
+
+import (
+	"testing"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+)
+
+func Test_execFilter(t *testing.T) {
+	reason, err := cmv1.NewLimitedSupportReason().Summary("Summary").Details("Old details").DetectionType(cmv1.DetectionTypeManual).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		filter      string
+		wantDetails string
+		wantErr     bool
+	}{
+		{
+			name:        "Passes the reason through",
+			filter:      "cat",
+			wantDetails: "Old details",
+		},
+		{
+			name:        "Transforms the reason",
+			filter:      "sed 's/Old/New/'",
+			wantDetails: "New details",
+		},
+		{
+			name:    "Rejects invalid JSON",
+			filter:  "echo not-json",
+			wantErr: true,
+		},
+		{
+			name:    "Rejects a reason without details",
+			filter:  `echo '{"summary":"Summary"}'`,
+			wantErr: true,
+		},
+		{
+			name:    "Fails when the command fails",
+			filter:  "exit 1",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Post{ExecFilter: tt.filter}
+			got, err := p.execFilter(reason)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("execFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.Details() != tt.wantDetails {
+				t.Errorf("execFilter() details = %q, want %q", got.Details(), tt.wantDetails)
+			}
+		})
+	}
+}
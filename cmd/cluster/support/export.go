@@ -0,0 +1,192 @@
+package support
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/openshift/osdctl/internal/utils/globalflags"
+	"github.com/openshift/osdctl/pkg/printer"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+const defaultExportConcurrency = 5
+
+type exportOptions struct {
+	output         string
+	clusterIDsFile string
+	concurrency    int
+
+	genericclioptions.IOStreams
+	GlobalOptions *globalflags.GlobalOptions
+}
+
+// fleetExport is the document produced by the export command
+type fleetExport struct {
+	ExportedAt time.Time       `json:"exported_at"`
+	Clusters   []clusterExport `json:"clusters"`
+}
+
+// clusterExport holds the limited support reasons of a single cluster, or the error encountered while listing them
+type clusterExport struct {
+	ClusterKey string            `json:"cluster_key"`
+	ClusterID  string            `json:"cluster_id,omitempty"`
+	Reasons    []json.RawMessage `json:"limited_support_reasons"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// newCmdexport implements the export command to snapshot the limited support reasons of a fleet of clusters
+func newCmdexport(streams genericclioptions.IOStreams, globalOpts *globalflags.GlobalOptions) *cobra.Command {
+	ops := newExportOptions(streams, globalOpts)
+	exportCmd := &cobra.Command{
+		Use:   "export --cluster-ids-file FILE",
+		Short: "Export the limited support reasons of many clusters into a single document",
+		Example: `  # Snapshot the limited support reasons of every cluster listed in clusters.txt
+  osdctl cluster support export --cluster-ids-file clusters.txt -o json > snapshot.json`,
+		Args:              cobra.NoArgs,
+		DisableAutoGenTag: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(ops.complete(cmd, args))
+			cmdutil.CheckErr(ops.run())
+		},
+	}
+
+	exportCmd.Flags().StringVar(&ops.clusterIDsFile, "cluster-ids-file", "", "File listing one cluster ID, name or external ID per line. Empty lines and lines starting with '#' are ignored")
+	exportCmd.Flags().IntVar(&ops.concurrency, "concurrency", defaultExportConcurrency, "Number of clusters to query in parallel")
+	_ = exportCmd.MarkFlagRequired("cluster-ids-file")
+
+	return exportCmd
+}
+
+func newExportOptions(streams genericclioptions.IOStreams, globalOpts *globalflags.GlobalOptions) *exportOptions {
+	return &exportOptions{
+		IOStreams:     streams,
+		GlobalOptions: globalOpts,
+	}
+}
+
+func (o *exportOptions) complete(cmd *cobra.Command, _ []string) error {
+	if o.concurrency < 1 {
+		return cmdutil.UsageErrorf(cmd, "--concurrency must be at least 1")
+	}
+
+	o.output = o.GlobalOptions.Output
+
+	return nil
+}
+
+func (o *exportOptions) run() error {
+	clusterKeys, err := readClusterIDsFile(o.clusterIDsFile)
+	if err != nil {
+		return err
+	}
+	if len(clusterKeys) == 0 {
+		return fmt.Errorf("no clusters found in %s", o.clusterIDsFile)
+	}
+
+	//create connection to sdk
+	connection, err := createConnection()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := connection.Close(); err != nil {
+			fmt.Printf("Cannot close the connection: %q\n", err)
+			os.Exit(1)
+		}
+	}()
+
+	export := fleetExport{
+		ExportedAt: time.Now().UTC(),
+		Clusters:   exportClusters(connection, clusterKeys, o.concurrency),
+	}
+
+	if o.output == "json" {
+		encoder := json.NewEncoder(o.Out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(export); err != nil {
+			return err
+		}
+	} else if err := printExport(export); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, cluster := range export.Clusters {
+		if cluster.Error != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to export the limited support reasons of %d out of %d clusters", failed, len(export.Clusters))
+	}
+	return nil
+}
+
+// exportClusters lists the limited support reasons of every cluster using at most concurrency parallel requests.
+// Results are returned in the same order as clusterKeys.
+func exportClusters(connection *sdk.Connection, clusterKeys []string, concurrency int) []clusterExport {
+	results := make([]clusterExport, len(clusterKeys))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency && i < len(clusterKeys); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				results[index] = exportCluster(connection, clusterKeys[index])
+			}
+		}()
+	}
+	for index := range clusterKeys {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+func exportCluster(connection *sdk.Connection, clusterKey string) clusterExport {
+	result := clusterExport{ClusterKey: clusterKey, Reasons: []json.RawMessage{}}
+
+	cluster, reasons, err := listLimitedSupportReasons(connection, clusterKey)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.ClusterID = cluster.ID()
+
+	for _, reason := range reasons {
+		var buf bytes.Buffer
+		if err := cmv1.MarshalLimitedSupportReason(reason, &buf); err != nil {
+			result.Error = fmt.Sprintf("cannot marshal limited support reason %s: %v", reason.ID(), err)
+			return result
+		}
+		result.Reasons = append(result.Reasons, json.RawMessage(bytes.TrimSpace(buf.Bytes())))
+	}
+	return result
+}
+
+func printExport(export fleetExport) error {
+	table := printer.NewTablePrinter(os.Stdout, 20, 1, 3, ' ')
+	table.AddRow([]string{"Cluster", "Limited Support Reasons", "Error"})
+	for _, cluster := range export.Clusters {
+		name := cluster.ClusterID
+		if name == "" {
+			name = cluster.ClusterKey
+		}
+		table.AddRow([]string{name, fmt.Sprintf("%d", len(cluster.Reasons)), cluster.Error})
+	}
+	// Add empty row for readability
+	table.AddRow([]string{})
+	return table.Flush()
+}
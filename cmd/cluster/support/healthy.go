@@ -0,0 +1,43 @@
+package support
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	ctlutil "github.com/openshift/osdctl/pkg/utils"
+)
+
+// errAlreadyInLimitedSupport is wrapped by the errors of the clusters skipped by --only-if-healthy
+var errAlreadyInLimitedSupport = errors.New("the cluster is already in limited support")
+
+// checkHealthy skips, for --only-if-healthy, the clusters which already have any limited support reason
+func (p *Post) checkHealthy(connection *sdk.Connection, cluster *cmv1.Cluster) error {
+	if !p.onlyIfHealthy {
+		return nil
+	}
+	var reasons []*cmv1.LimitedSupportReason
+	err := retryOCM(p.retries, func() error {
+		var err error
+		reasons, err = ctlutil.GetClusterLimitedSupportReasons(connection, cluster.ID())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("cannot check whether the cluster is already in limited support: %w", err)
+	}
+	return healthCheck(reasons)
+}
+
+// healthCheck returns errAlreadyInLimitedSupport, along with the reasons, when there is any reason
+func healthCheck(reasons []*cmv1.LimitedSupportReason) error {
+	if len(reasons) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(reasons))
+	for _, reason := range reasons {
+		ids = append(ids, reason.ID())
+	}
+	return fmt.Errorf("%w with %d reason(s): %s", errAlreadyInLimitedSupport, len(reasons), strings.Join(ids, ", "))
+}
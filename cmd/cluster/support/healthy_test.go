@@ -0,0 +1,21 @@
+package support
+
+import (
+	"errors"
+	"testing"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+)
+
+func Test_healthCheck(t *testing.T) {
+	if err := healthCheck(nil); err != nil {
+		t.Errorf("healthCheck() = %v, want nil for a fully supported cluster", err)
+	}
+	reason, err := cmv1.NewLimitedSupportReason().ID("r1").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := healthCheck([]*cmv1.LimitedSupportReason{reason}); !errors.Is(err, errAlreadyInLimitedSupport) {
+		t.Errorf("healthCheck() = %v, want %v", err, errAlreadyInLimitedSupport)
+	}
+}
@@ -0,0 +1,197 @@
+package support
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/viper"
+)
+
+const (
+	// HistoryFileKey can be set in the osdctl configuration file to change where posted reasons are recorded
+	HistoryFileKey = "support_history_file"
+
+	historyFileName = "osdctl-support-history.jsonl"
+)
+
+// historyEntry records a limited support reason which was posted to a cluster
+type historyEntry struct {
+	Timestamp     time.Time `json:"timestamp"`
+	ClusterID     string    `json:"cluster_id"`
+	ReasonID      string    `json:"reason_id"`
+	Summary       string    `json:"summary"`
+	Details       string    `json:"details"`
+	DetectionType string    `json:"detection_type"`
+	IncidentID    string    `json:"incident_id,omitempty"`
+	Template      string    `json:"template,omitempty"`
+	// TemplateVersion is the '_template_version' declared by the template
+	TemplateVersion string `json:"template_version,omitempty"`
+}
+
+// historyFilePath returns the path of the local history of posted reasons
+func historyFilePath() (string, error) {
+	if path := viper.GetString(HistoryFileKey); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", historyFileName), nil
+}
+
+// recordHistory appends the posted reason to the local history.
+// Failing to record is not fatal as the reason was already posted, a warning is printed instead.
+func (p *Post) recordHistory(clusterID string, reason *cmv1.LimitedSupportReason) {
+	entry := historyEntry{
+		Timestamp:       time.Now().UTC(),
+		ClusterID:       clusterID,
+		ReasonID:        reason.ID(),
+		Summary:         reason.Summary(),
+		Details:         reason.Details(),
+		DetectionType:   string(reason.DetectionType()),
+		IncidentID:      p.IncidentID,
+		Template:        p.Template,
+		TemplateVersion: p.templateVersion,
+	}
+	if err := appendHistory(entry); err != nil {
+		logger().Warn("Could not record the reason in the local history", "error", err)
+	}
+}
+
+func appendHistory(entry historyEntry) error {
+	path, err := historyFilePath()
+	if err != nil {
+		return err
+	}
+	return appendHistoryFile(path, entry)
+}
+
+// appendHistoryFile appends the entry to the history file as a single line. Parallel runs of osdctl can share the
+// history: the file is locked while the line is written, so that an append waits for any other writer holding
+// the lock rather than relying on the atomicity of a single write.
+func appendHistoryFile(path string, entry historyEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filepath.Clean(path), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("cannot lock %s: %w", path, err)
+	}
+	// Closing the file releases the lock too, the explicit unlock only releases it as early as possible
+	defer func() { _ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN) }()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// readHistory parses every entry of a history file
+func readHistory(r io.Reader) ([]historyEntry, error) {
+	var entries []historyEntry
+	scanner := bufio.NewScanner(r)
+	// Details can be long, allow lines up to 1MiB
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry historyEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// limitedSupportReason builds the reason recorded by the entry, without its ID
+func (e historyEntry) limitedSupportReason() (*cmv1.LimitedSupportReason, error) {
+	return cmv1.NewLimitedSupportReason().
+		Summary(e.Summary).
+		Details(e.Details).
+		DetectionType(cmv1.DetectionType(e.DetectionType)).
+		Build()
+}
+
+// lastHistoryEntry returns the most recent reason recorded for the cluster in the local history,
+// or nil when there is none
+func lastHistoryEntry(clusterID string) (*historyEntry, error) {
+	path, err := historyFilePath()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filepath.Clean(path))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries, err := readHistory(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].ClusterID == clusterID {
+			return &entries[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// historyDiff returns a unified diff from the reason recorded by the entry to the given one,
+// it is empty when they are identical
+func historyDiff(previous historyEntry, reason *cmv1.LimitedSupportReason) (string, error) {
+	lines := func(summary, detectionType, details string) []string {
+		return difflib.SplitLines(fmt.Sprintf("summary: %s\ndetection_type: %s\ndetails:\n%s\n", summary, detectionType, details))
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        lines(previous.Summary, previous.DetectionType, previous.Details),
+		B:        lines(reason.Summary(), string(reason.DetectionType()), reason.Details()),
+		FromFile: fmt.Sprintf("last posted (%s, %s)", previous.ReasonID, previous.Timestamp.Format(time.RFC3339)),
+		ToFile:   "about to be posted",
+		Context:  3,
+	})
+}
+
+// printHistoryDiff shows how the reason differs from the last one posted to the cluster according to the
+// local history, so that unintended changes between incidents stand out before confirming
+func printHistoryDiff(out io.Writer, clusterID string, reason *cmv1.LimitedSupportReason) {
+	previous, err := lastHistoryEntry(clusterID)
+	if err != nil {
+		logger().Warn("Could not read the local history", "error", err)
+		return
+	}
+	if previous == nil {
+		return
+	}
+	diff, err := historyDiff(*previous, reason)
+	if err != nil {
+		logger().Warn("Could not compare with the last posted reason", "error", err)
+		return
+	}
+	if diff == "" {
+		fmt.Fprintf(out, "The reason is identical to the last one posted to %s (%s)\n", clusterID, previous.ReasonID)
+		return
+	}
+	fmt.Fprintf(out, "Changes since the last reason posted to %s:\n%s", clusterID, diff)
+}
@@ -0,0 +1,196 @@
+package support
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/spf13/viper"
+)
+
+func Test_readHistory(t *testing.T) {
+	history := `{"timestamp":"2024-01-02T03:04:05Z","cluster_id":"abc","reason_id":"r1","summary":"s1","details":"d1","detection_type":"manual","incident_id":"OHSS-1"}
+
+{"timestamp":"2024-01-03T03:04:05Z","cluster_id":"def","reason_id":"r2","summary":"s2","details":"d2","detection_type":"manual"}
+`
+	entries, err := readHistory(strings.NewReader(history))
+	if err != nil {
+		t.Fatalf("readHistory() error = %v, wantErr %v", err, false)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("readHistory() got %d entries, want 2", len(entries))
+	}
+
+	o := &replayOptions{incidentID: "OHSS-1"}
+	selected := o.selectEntries(entries)
+	if len(selected) != 1 || selected[0].ReasonID != "r1" {
+		t.Errorf("selectEntries() got %v, want only reason r1", selected)
+	}
+
+	if _, err := readHistory(strings.NewReader("not json\n")); err == nil {
+		t.Errorf("readHistory() expected an error for an invalid line")
+	}
+}
+
+func Test_lastHistoryEntryAndDiff(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	viper.Set(HistoryFileKey, path)
+	defer viper.Set(HistoryFileKey, "")
+
+	if entry, err := lastHistoryEntry("abc"); err != nil || entry != nil {
+		t.Fatalf("lastHistoryEntry() = %v, %v, want nothing without a history file", entry, err)
+	}
+
+	history := `{"timestamp":"2024-01-02T03:04:05Z","cluster_id":"abc","reason_id":"r1","summary":"s1","details":"old details","detection_type":"manual"}
+{"timestamp":"2024-01-03T03:04:05Z","cluster_id":"def","reason_id":"r2","summary":"s2","details":"d2","detection_type":"manual"}
+{"timestamp":"2024-01-04T03:04:05Z","cluster_id":"abc","reason_id":"r3","summary":"s1","details":"latest details","detection_type":"manual"}
+`
+	if err := os.WriteFile(path, []byte(history), 0600); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := lastHistoryEntry("abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil || entry.ReasonID != "r3" {
+		t.Fatalf("lastHistoryEntry() = %v, want reason r3", entry)
+	}
+
+	same, err := entry.limitedSupportReason()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff, err := historyDiff(*entry, same); err != nil || diff != "" {
+		t.Errorf("historyDiff() = %q, %v, want no diff for the same reason", diff, err)
+	}
+
+	changed, err := cmv1.NewLimitedSupportReason().Summary("s1").Details("new details").DetectionType(cmv1.DetectionTypeManual).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff, err := historyDiff(*entry, changed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(diff, "-latest details") || !strings.Contains(diff, "+new details") || strings.Contains(diff, "-summary") {
+		t.Errorf("historyDiff() = %q, want only the details to change", diff)
+	}
+}
+
+func Test_appendHistoryFileConcurrentWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	const writers, entriesPerWriter = 8, 25
+	// Details larger than a pipe buffer
+	details := strings.Repeat("x", 128*1024)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*entriesPerWriter)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < entriesPerWriter; i++ {
+				entry := historyEntry{ClusterID: fmt.Sprintf("writer-%d", w), ReasonID: fmt.Sprintf("%d", i), Details: details}
+				if err := appendHistoryFile(path, entry); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	entries, err := readHistory(file)
+	if err != nil {
+		t.Fatalf("the history is corrupted: %v", err)
+	}
+	if len(entries) != writers*entriesPerWriter {
+		t.Fatalf("history has %d entries, want %d", len(entries), writers*entriesPerWriter)
+	}
+	for _, entry := range entries {
+		if entry.Details != details {
+			t.Fatalf("entry %s/%s has interleaved details", entry.ClusterID, entry.ReasonID)
+		}
+	}
+}
+
+func Test_appendHistoryFileWaitsForTheLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	if err := appendHistoryFile(path, historyEntry{ClusterID: "first"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another writer reads the history and rewrites it while holding the lock
+	other, err := os.OpenFile(path, os.O_RDWR, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if err := syscall.Flock(int(other.Fd()), syscall.LOCK_EX); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := readHistory(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- appendHistoryFile(path, historyEntry{ClusterID: "second"}) }()
+	select {
+	case err := <-done:
+		t.Fatalf("appendHistoryFile() = %v while the history is locked, want it to wait for the lock", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	entries = append(entries, historyEntry{ClusterID: "rewritten"})
+	if err := other.Truncate(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if err := json.NewEncoder(other).Encode(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := syscall.Flock(int(other.Fd()), syscall.LOCK_UN); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// The append happened after the rewrite, nothing was lost
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	entries, err = readHistory(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var clusters []string
+	for _, entry := range entries {
+		clusters = append(clusters, entry.ClusterID)
+	}
+	if want := []string{"first", "rewritten", "second"}; strings.Join(clusters, ",") != strings.Join(want, ",") {
+		t.Errorf("history = %v, want %v", clusters, want)
+	}
+}
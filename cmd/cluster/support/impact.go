@@ -0,0 +1,107 @@
+package support
+
+import (
+	"fmt"
+	"io"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+)
+
+// limitedSupportRestrictions apply to every cluster with at least one limited support reason
+var limitedSupportRestrictions = []string{
+	"The cluster's SLA doesn't apply while it is in limited support",
+	"Support is limited to helping the customer resolve the reason",
+}
+
+// detectionTypeImpacts describes how a reason is lifted, depending on its detection type
+var detectionTypeImpacts = map[cmv1.DetectionType]string{
+	cmv1.DetectionTypeManual: "Lifted only when an SRE deletes the reason, after the customer fixed the issue",
+	cmv1.DetectionTypeAuto:   "Lifted automatically once the service no longer detects the issue",
+}
+
+// summaryImpacts describes who is expected to act for the standard summaries
+var summaryImpacts = map[string]string{
+	LimitedSupportSummaryCluster: "Caused by the cluster configuration, the customer has to change the cluster",
+	LimitedSupportSummaryCloud:   "Caused by the cloud provider configuration, the customer has to change their cloud account",
+}
+
+// reasonImpact returns the lines describing the customer impact of posting the reason, derived from its fields
+func reasonImpact(reason *cmv1.LimitedSupportReason, machinePool string) []string {
+	impact := []string{"Customer visible: yes, the summary and details are shown to the customer in OCM"}
+	impact = append(impact, limitedSupportRestrictions...)
+
+	if lifted, ok := detectionTypeImpacts[reason.DetectionType()]; ok {
+		impact = append(impact, lifted)
+	} else {
+		impact = append(impact, fmt.Sprintf("Unknown detection_type %q, how the reason is lifted can't be determined", reason.DetectionType()))
+	}
+	if cause, ok := summaryImpacts[reason.Summary()]; ok {
+		impact = append(impact, cause)
+	}
+	if machinePool != "" {
+		impact = append(impact, fmt.Sprintf("Scoped to the machine pool %s in the details, the whole cluster is still placed in limited support", machinePool))
+	}
+	return impact
+}
+
+// postedItem is one of the objects posted to every cluster
+type postedItem struct {
+	kind            string
+	customerVisible bool
+}
+
+// postedItems lists what is posted to every cluster for the given number of reasons, each reason being sent
+// along with its evidence. The visibility of each item is derived from the object itself: limited support
+// reasons are always shown to the customer, service logs unless they are internal only.
+func (p *Post) postedItems(reasons int) ([]postedItem, error) {
+	var items []postedItem
+	for i := 0; i < reasons; i++ {
+		items = append(items, postedItem{kind: "limited support reason", customerVisible: true})
+		if p.Evidence != "" {
+			log, err := p.buildInternalServiceLog("", "")
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, postedItem{kind: "service log", customerVisible: !log.InternalOnly()})
+		}
+	}
+	return items, nil
+}
+
+// impactSummary returns the line stating how many customer visible and internal items are about to be posted
+// to each of the targeted clusters, for the confirmation step. A line totaling them across the clusters
+// follows when there are several.
+func impactSummary(items []postedItem, clusters int, target string) string {
+	var visible, internal int
+	for _, item := range items {
+		if item.customerVisible {
+			visible++
+		} else {
+			internal++
+		}
+	}
+	summary := fmt.Sprintf("Impact: %d customer-visible and %d internal item(s) will be posted to %s", visible, internal, target)
+	if clusters > 1 {
+		summary += fmt.Sprintf("\nTotal: %d customer-visible and %d internal item(s) across %d cluster(s)", visible*clusters, internal*clusters, clusters)
+	}
+	return summary
+}
+
+// printImpactSummary prints the impact summary of posting the number of reasons to each of the clusters
+// before confirming
+func (p *Post) printImpactSummary(reasons int, clusters int, target string) error {
+	items, err := p.postedItems(reasons)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(p.previewOut(), impactSummary(items, clusters, target))
+	return nil
+}
+
+// printImpact prints the customer impact of posting the reason, for dry-runs
+func printImpact(out io.Writer, reason *cmv1.LimitedSupportReason, machinePool string) {
+	fmt.Fprintln(out, "Customer impact:")
+	for _, line := range reasonImpact(reason, machinePool) {
+		fmt.Fprintf(out, "  - %s\n", line)
+	}
+}
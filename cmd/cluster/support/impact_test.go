@@ -0,0 +1,82 @@
+package support
+
+import (
+	"strings"
+	"testing"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+)
+
+func Test_reasonImpact(t *testing.T) {
+	tests := []struct {
+		name        string
+		reason      *cmv1.LimitedSupportReasonBuilder
+		machinePool string
+		want        []string
+	}{
+		{
+			name:   "Manual cluster misconfiguration",
+			reason: cmv1.NewLimitedSupportReason().Summary(LimitedSupportSummaryCluster).DetectionType(cmv1.DetectionTypeManual),
+			want:   []string{"Customer visible: yes", detectionTypeImpacts[cmv1.DetectionTypeManual], summaryImpacts[LimitedSupportSummaryCluster]},
+		},
+		{
+			name:        "Automatic reason scoped to a machine pool",
+			reason:      cmv1.NewLimitedSupportReason().Summary("Custom summary").DetectionType(cmv1.DetectionTypeAuto),
+			machinePool: "workers",
+			want:        []string{detectionTypeImpacts[cmv1.DetectionTypeAuto], "machine pool workers"},
+		},
+		{
+			name:   "Unknown detection type",
+			reason: cmv1.NewLimitedSupportReason().Summary("Custom summary").DetectionType("other"),
+			want:   []string{`Unknown detection_type "other"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, err := tt.reason.Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			impact := strings.Join(reasonImpact(reason, tt.machinePool), "\n")
+			for _, want := range append(tt.want, limitedSupportRestrictions...) {
+				if !strings.Contains(impact, want) {
+					t.Errorf("reasonImpact() = %q, missing %q", impact, want)
+				}
+			}
+		})
+	}
+}
+
+func Test_impactSummary(t *testing.T) {
+	p := &Post{Template: "template.json"}
+	items, err := p.postedItems(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := impactSummary(items, 3, "each of the 3 cluster(s)"), "Impact: 1 customer-visible and 0 internal item(s) will be posted to each of the 3 cluster(s)\nTotal: 3 customer-visible and 0 internal item(s) across 3 cluster(s)"; got != want {
+		t.Errorf("impactSummary() = %q, want %q", got, want)
+	}
+
+	// The evidence is posted as an internal only service log
+	cluster, err := cmv1.NewCluster().ID("cluster-id").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p = &Post{Evidence: "evidence", cluster: cluster}
+	items, err = p.postedItems(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := impactSummary(items, 1, "cluster cluster-id"), "Impact: 1 customer-visible and 1 internal item(s) will be posted to cluster cluster-id"; got != want {
+		t.Errorf("impactSummary() = %q, want %q", got, want)
+	}
+
+	// Every template of --reason-file-glob is posted with its evidence
+	items, err = p.postedItems(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := impactSummary(items, 1, "cluster cluster-id"), "Impact: 3 customer-visible and 3 internal item(s) will be posted to cluster cluster-id"; got != want {
+		t.Errorf("impactSummary() = %q, want %q", got, want)
+	}
+}
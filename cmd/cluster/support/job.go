@@ -0,0 +1,420 @@
+package support
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/openshift/osdctl/cmd/getoutput"
+	"github.com/openshift/osdctl/internal/utils/globalflags"
+	"github.com/openshift/osdctl/pkg/printer"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+// jobPending is the status of the clusters of a job which weren't processed yet
+const jobPending = "pending"
+
+// batchJob is a batch persisted to the --job-file, so that it can be resumed after an interruption and its
+// progress checked by 'job status'. It lists every cluster of the batch along with its outcome so far.
+type batchJob struct {
+	Template string    `json:"template"`
+	Started  time.Time `json:"started"`
+	Updated  time.Time `json:"updated"`
+	// PID is the process running the job, or which ran it last
+	PID int `json:"pid,omitempty"`
+	// Log is the file the output of an --async job is written to
+	Log string `json:"log,omitempty"`
+	// Confirmed jobs are resumed without asking again, their clusters were confirmed when the job was created
+	Confirmed bool          `json:"confirmed"`
+	Done      bool          `json:"done"`
+	Clusters  []*jobCluster `json:"clusters"`
+
+	// current is the cluster being processed
+	current *jobCluster
+}
+
+// jobCluster is a cluster of a job, along with its outcome once it was processed
+type jobCluster struct {
+	// Key is the cluster as selected for the batch, which may be a name or an external ID
+	Key         string            `json:"key" yaml:"key"`
+	Params      map[string]string `json:"params,omitempty" yaml:"params,omitempty"`
+	batchResult `yaml:",inline"`
+}
+
+// done is true for the clusters which mustn't be posted to again when the job is resumed
+func (c *jobCluster) done() bool {
+	return batchCompleted(c.Status)
+}
+
+// newBatchJob lists every cluster of the source in a new job. The whole source is read upfront, so that the
+// job can be resumed without selecting its clusters again.
+func newBatchJob(template string, source clusterSource) (*batchJob, error) {
+	job := &batchJob{Template: template, Started: time.Now().UTC()}
+	for {
+		entry, err := source.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot create the job, %s: %w", source.position(), err)
+		}
+		job.Clusters = append(job.Clusters, &jobCluster{
+			Key:         entry.ClusterID,
+			Params:      entry.Params,
+			batchResult: batchResult{ClusterID: entry.ClusterID, Status: jobPending},
+		})
+	}
+	if len(job.Clusters) == 0 {
+		return nil, errors.New("cannot create the job, there are no clusters to post to")
+	}
+	return job, nil
+}
+
+// loadBatchJob reads the job of the file, it returns nil when the file doesn't exist
+func loadBatchJob(path string) (*batchJob, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the job file %s: %w", path, err)
+	}
+	var job batchJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("cannot parse the job file %s: %w", path, err)
+	}
+	return &job, nil
+}
+
+// save writes the job to the file. The file is replaced at once, so that an interruption never leaves a
+// partial job behind.
+func (j *batchJob) save(path string) error {
+	j.Updated = time.Now().UTC()
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("cannot save the job file %s: %w", path, err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("cannot save the job file %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("cannot save the job file %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("cannot save the job file %s: %w", path, err)
+	}
+	return nil
+}
+
+// processRunning is true when the process exists, signal 0 only checks that it does
+func processRunning(pid int) bool {
+	return pid != 0 && syscall.Kill(pid, 0) == nil
+}
+
+// checkNotRunning refuses to resume a job which another process is still working on, eg. its --async
+// process. Both would post to the same remaining clusters.
+func (j *batchJob) checkNotRunning(path string) error {
+	if j.PID != os.Getpid() && processRunning(j.PID) {
+		return fmt.Errorf("the job %s is still being run by process %d, follow it with 'osdctl cluster support job status %s'", path, j.PID, path)
+	}
+	return nil
+}
+
+// lockBatchJob takes an exclusive lock for running the job, until the returned function releases it. The job
+// file is replaced on every save, so the lock is taken on a '.lock' file next to it.
+func lockBatchJob(path string) (func(), error) {
+	lock, err := os.OpenFile(path+".lock", os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("cannot lock the job file %s: %w", path, err)
+	}
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		_ = lock.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, fmt.Errorf("the job %s is being run by another process", path)
+		}
+		return nil, fmt.Errorf("cannot lock the job file %s: %w", path, err)
+	}
+	return func() {
+		_ = syscall.Flock(int(lock.Fd()), syscall.LOCK_UN)
+		_ = lock.Close()
+	}, nil
+}
+
+// remaining counts the clusters which are still to be posted to
+func (j *batchJob) remaining() int {
+	remaining := 0
+	for _, c := range j.Clusters {
+		if !c.done() {
+			remaining++
+		}
+	}
+	return remaining
+}
+
+// record saves the outcome of the cluster being processed to the job file
+func (j *batchJob) record(path string, result batchResult) error {
+	if j.current == nil {
+		return nil
+	}
+	// Failed clusters are identified by their position in text outputs, the job keeps their key
+	if result.Status == batchFailed {
+		result.ClusterID = j.current.Key
+	}
+	j.current.batchResult = result
+	return j.save(path)
+}
+
+// source returns the clusters of the job which are still to be posted to
+func (j *batchJob) source(path string) *jobSource {
+	return &jobSource{job: j, path: path}
+}
+
+// jobSource yields the clusters of a job which aren't done
+type jobSource struct {
+	job   *batchJob
+	path  string
+	index int
+}
+
+func (s *jobSource) next() (*clusterEntry, error) {
+	for s.index < len(s.job.Clusters) {
+		c := s.job.Clusters[s.index]
+		s.index++
+		if c.done() {
+			continue
+		}
+		s.job.current = c
+		return &clusterEntry{ClusterID: c.Key, Params: c.Params}, nil
+	}
+	s.job.current = nil
+	return nil, io.EOF
+}
+
+func (s *jobSource) position() string {
+	return fmt.Sprintf("%s:%d", s.path, s.index)
+}
+
+func (s *jobSource) description() string {
+	return fmt.Sprintf("the %d remaining cluster(s) of the job %s", s.job.remaining(), s.path)
+}
+
+// asyncExcludedFlags aren't passed to the background process of an --async job, which posts to the clusters
+// listed in the job file
+var asyncExcludedFlags = map[string]struct{}{
+	"async":               {},
+	"clusters-jsonl":      {},
+	"cluster-ids-file":    {},
+	"subscription-search": {},
+	"version-range":       {},
+	"query":               {},
+	"interactive":         {},
+	"trusted-input":       {},
+}
+
+// The OCM service account credentials given on the command line are passed to the background process of an
+// --async job in these environment variables, as its command line can be read by every user of the host
+const (
+	asyncClientIDEnv     = "OSDCTL_SUPPORT_CLIENT_ID"
+	asyncClientSecretEnv = "OSDCTL_SUPPORT_CLIENT_SECRET"
+)
+
+// asyncSecretFlags are the flags passed to the background process of an --async job in its environment
+var asyncSecretFlags = map[string]string{
+	"client-id":     asyncClientIDEnv,
+	"client-secret": asyncClientSecretEnv,
+}
+
+// asyncJobArgs returns the arguments running the command again in the background, with the flags given on
+// the command line except the ones selecting the clusters, and the environment variables holding the secret ones
+func asyncJobArgs(cmd *cobra.Command) (args []string, env []string) {
+	args = strings.Fields(cmd.CommandPath())[1:]
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		if _, ok := asyncExcludedFlags[flag.Name]; ok {
+			return
+		}
+		if name, ok := asyncSecretFlags[flag.Name]; ok {
+			env = append(env, name+"="+flag.Value.String())
+			return
+		}
+		if values, ok := flag.Value.(pflag.SliceValue); ok {
+			for _, value := range values.GetSlice() {
+				args = append(args, "--"+flag.Name+"="+value)
+			}
+			return
+		}
+		args = append(args, "--"+flag.Name+"="+flag.Value.String())
+	})
+	return args, env
+}
+
+// startAsyncJob saves the confirmed job and resumes it in a background process, which outlives the terminal.
+// The output of the process is written next to the job file.
+func (p *Post) startAsyncJob() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("cannot start the job in the background: %w", err)
+	}
+	p.job.Log = p.JobFile + ".log"
+	if err := p.job.save(p.JobFile); err != nil {
+		return err
+	}
+	log, err := os.OpenFile(p.job.Log, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("cannot create the log of the job: %w", err)
+	}
+	defer log.Close()
+
+	background := exec.Command(executable, p.asyncArgs...) //#nosec G204 -- runs osdctl itself with the flags of the user
+	background.Env = append(os.Environ(), p.asyncEnv...)
+	background.Stdout = log
+	background.Stderr = log
+	// A new session isn't terminated along with the terminal
+	background.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := background.Start(); err != nil {
+		return fmt.Errorf("cannot start the job in the background: %w", err)
+	}
+	fmt.Printf("Started the job %s in the background (pid %d), its output is written to %s\n", p.JobFile, background.Process.Pid, p.job.Log)
+	fmt.Printf("Check its progress with 'osdctl cluster support job status %s'\n", p.JobFile)
+	return background.Process.Release()
+}
+
+// newCmdjob implements the job command to follow the batches persisted with --job-file
+func newCmdjob(streams genericclioptions.IOStreams, globalOpts *globalflags.GlobalOptions) *cobra.Command {
+	jobCmd := &cobra.Command{
+		Use:               "job",
+		Short:             "Follow the batches posted with --job-file",
+		Args:              cobra.NoArgs,
+		DisableAutoGenTag: true,
+		Run:               help,
+	}
+	jobCmd.AddCommand(newCmdjobStatus(streams, globalOpts))
+	return jobCmd
+}
+
+type jobStatusOptions struct {
+	jobFile string
+	output  string
+
+	genericclioptions.IOStreams
+	GlobalOptions *globalflags.GlobalOptions
+}
+
+// jobStatus is the progress of a job, it is printed according to '-o'
+type jobStatus struct {
+	JobFile  string         `json:"job_file" yaml:"job_file"`
+	State    string         `json:"state" yaml:"state"`
+	Template string         `json:"template" yaml:"template"`
+	Started  time.Time      `json:"started" yaml:"started"`
+	Updated  time.Time      `json:"updated" yaml:"updated"`
+	Log      string         `json:"log,omitempty" yaml:"log,omitempty"`
+	Counts   map[string]int `json:"counts" yaml:"counts"`
+	Clusters []*jobCluster  `json:"clusters" yaml:"clusters"`
+}
+
+func (s jobStatus) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Job %s: %s\n", s.JobFile, s.State)
+	fmt.Fprintf(&buf, "Template: %s\n", s.Template)
+	fmt.Fprintf(&buf, "Started: %s, updated: %s\n", s.Started.Format(time.RFC3339), s.Updated.Format(time.RFC3339))
+	if s.Log != "" {
+		fmt.Fprintf(&buf, "Log: %s\n", s.Log)
+	}
+	fmt.Fprintf(&buf, "Pending: %d, Posted: %d, Rendered: %d, Failed: %d, Skipped: %d\n\n",
+		s.Counts[jobPending], s.Counts[batchPosted], s.Counts[batchRendered], s.Counts[batchFailed], s.Counts[batchSkipped])
+	table := printer.NewTablePrinter(&buf, 20, 1, 3, ' ')
+	table.AddRow([]string{"Cluster", "Status", "Reason ID", "Error"})
+	for _, c := range s.Clusters {
+		table.AddRow([]string{c.Key, c.Status, c.ReasonID, c.Error})
+	}
+	_ = table.Flush()
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// newJobStatus returns the progress of the job, running tells whether the process running it is alive
+func newJobStatus(path string, job *batchJob, running bool) jobStatus {
+	status := jobStatus{
+		JobFile:  path,
+		Template: job.Template,
+		Started:  job.Started,
+		Updated:  job.Updated,
+		Log:      job.Log,
+		Counts:   map[string]int{},
+		Clusters: job.Clusters,
+	}
+	for _, c := range job.Clusters {
+		status.Counts[c.Status]++
+	}
+	switch {
+	case job.Done:
+		status.State = "done"
+	case running:
+		status.State = fmt.Sprintf("running (pid %d)", job.PID)
+	case status.Counts[jobPending] == 0 && status.Counts[batchFailed] == 0:
+		status.State = "rendered, post again with the same --job-file and without --dry-run to post"
+	default:
+		status.State = "stopped, resume it by posting again with the same --job-file"
+	}
+	return status
+}
+
+// newCmdjobStatus implements the job status command to check the progress of a job
+func newCmdjobStatus(streams genericclioptions.IOStreams, globalOpts *globalflags.GlobalOptions) *cobra.Command {
+	ops := &jobStatusOptions{IOStreams: streams, GlobalOptions: globalOpts}
+	statusCmd := &cobra.Command{
+		Use:   "status JOB_FILE",
+		Short: "Show the progress of a job",
+		Long: `Shows the progress of a batch posted with --job-file, along with the outcome of each of its clusters.
+A job which is neither done nor running was interrupted, post again with the same --job-file to resume it.`,
+		Example: `  # Check the progress of a job started with --async
+  osdctl cluster support job status job.json`,
+		Args:              cobra.ExactArgs(1),
+		DisableAutoGenTag: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			ops.jobFile = args[0]
+			ops.output = ops.GlobalOptions.Output
+			cmdutil.CheckErr(ops.run())
+		},
+	}
+	return statusCmd
+}
+
+func (o *jobStatusOptions) run() error {
+	job, err := loadBatchJob(o.jobFile)
+	if err != nil {
+		return err
+	}
+	if job == nil {
+		return fmt.Errorf("the job file %s doesn't exist", o.jobFile)
+	}
+	return getoutput.PrintResponse(o.output, newJobStatus(o.jobFile, job, processRunning(job.PID)))
+}
+
+// recordProgress saves the last result of the batch to the job file and to the state file, if any
+func (p *Post) recordProgress(entry *clusterEntry, results []batchResult) error {
+	if p.state != nil && entry != nil {
+		if err := p.state.record(entry.ClusterID, results[len(results)-1]); err != nil {
+			return err
+		}
+	}
+	if p.job == nil {
+		return nil
+	}
+	return p.job.record(p.JobFile, results[len(results)-1])
+}
@@ -0,0 +1,148 @@
+package support
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func Test_runBatchJobFile(t *testing.T) {
+	jobFile := filepath.Join(t.TempDir(), "job.json")
+	source := &sliceSource{origin: "for the test", entries: []*clusterEntry{
+		{ClusterID: "missing-param"},
+		{ClusterID: "with-param", Params: map[string]string{"NAME": "foo"}},
+		{ClusterID: "another", Params: map[string]string{"NAME": "bar"}},
+	}}
+	template := []byte(`{"summary":"Summary","details":"Remove ${NAME}","detection_type":"manual"}`)
+	p := &Post{Template: "template.json", isDryRun: true, JobFile: jobFile, templateBytes: template}
+	if err := p.runBatch(nil, source); err == nil {
+		t.Errorf("runBatch() expected an error for the failing cluster")
+	}
+
+	job, err := loadBatchJob(jobFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statuses []string
+	for _, c := range job.Clusters {
+		statuses = append(statuses, c.Key+"="+c.Status)
+	}
+	if want := []string{"missing-param=" + batchFailed, "with-param=" + batchRendered, "another=" + batchRendered}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("job statuses = %v, want %v", statuses, want)
+	}
+	if job.Clusters[1].Params["NAME"] != "foo" {
+		t.Errorf("job params of with-param = %v, want the params of its entry", job.Clusters[1].Params)
+	}
+	if job.Done || job.Confirmed {
+		t.Errorf("dry-run job done = %v, confirmed = %v, want neither", job.Done, job.Confirmed)
+	}
+
+	// Resuming skips the clusters already posted to, and the job is done once they all were
+	job.Clusters[0].Status = batchPosted
+	job.Clusters[2].Status = batchSkipped
+	if err := job.save(jobFile); err != nil {
+		t.Fatal(err)
+	}
+	resumed, err := loadBatchJob(jobFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p = &Post{Template: "template.json", isDryRun: true, JobFile: jobFile, templateBytes: template, job: resumed}
+	jobSource := resumed.source(jobFile)
+	if err := p.runBatch(nil, jobSource); err != nil {
+		t.Fatal(err)
+	}
+	if jobSource.index != 3 {
+		t.Errorf("resumed job went through %d cluster(s), want 3", jobSource.index)
+	}
+	resumed, err = loadBatchJob(jobFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.Clusters[0].Status != batchPosted || resumed.Clusters[1].Status != batchRendered {
+		t.Errorf("resumed job statuses = %s, %s", resumed.Clusters[0].Status, resumed.Clusters[1].Status)
+	}
+}
+
+func Test_newJobStatus(t *testing.T) {
+	job := &batchJob{Template: "template.json", PID: 42, Clusters: []*jobCluster{
+		{Key: "a", batchResult: batchResult{Status: batchPosted, ReasonID: "reason-a"}},
+		{Key: "b", batchResult: batchResult{Status: batchFailed, Error: "boom"}},
+		{Key: "c", batchResult: batchResult{Status: jobPending}},
+		{Key: "d", batchResult: batchResult{Status: jobPending}},
+	}}
+
+	status := newJobStatus("job.json", job, true)
+	if want := map[string]int{batchPosted: 1, batchFailed: 1, jobPending: 2}; !reflect.DeepEqual(status.Counts, want) {
+		t.Errorf("Counts = %v, want %v", status.Counts, want)
+	}
+	if status.State != "running (pid 42)" {
+		t.Errorf("State = %q, want the job to be running", status.State)
+	}
+	if out := status.String(); !strings.Contains(out, "Pending: 2, Posted: 1, Rendered: 0, Failed: 1, Skipped: 0") || !strings.Contains(out, "reason-a") {
+		t.Errorf("String() = %q", out)
+	}
+	if status := newJobStatus("job.json", job, false); !strings.HasPrefix(status.State, "stopped") {
+		t.Errorf("State = %q, want the job to be stopped", status.State)
+	}
+	rendered := &batchJob{Clusters: []*jobCluster{{Key: "a", batchResult: batchResult{Status: batchRendered}}}}
+	if status := newJobStatus("job.json", rendered, false); !strings.HasPrefix(status.State, "rendered") {
+		t.Errorf("State = %q, want the dry-run job to be rendered", status.State)
+	}
+}
+
+func Test_asyncJobArgs(t *testing.T) {
+	root := &cobra.Command{Use: "osdctl"}
+	root.PersistentFlags().String("output", "", "")
+	root.PersistentFlags().String("client-id", "", "")
+	root.PersistentFlags().String("client-secret", "", "")
+	post := newCmdpost(nil, nil)
+	root.AddCommand(post)
+	if err := post.ParseFlags([]string{"-t", "template.json", "-p", "FOO=bar", "-p", "BAZ=qux", "--clusters-jsonl", "clusters.jsonl", "--async", "--job-file", "job.json", "--output=json",
+		"--client-id", "id", "--client-secret", "secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	args, env := asyncJobArgs(post)
+	want := []string{"post", "--job-file=job.json", "--output=json", "--param=FOO=bar", "--param=BAZ=qux", "--template=template.json"}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("asyncJobArgs() = %v, want %v", args, want)
+	}
+	// The credentials are kept off the command line of the background process
+	if want := []string{asyncClientIDEnv + "=id", asyncClientSecretEnv + "=secret"}; !reflect.DeepEqual(env, want) {
+		t.Errorf("asyncJobArgs() environment = %v, want %v", env, want)
+	}
+}
+
+func Test_resumeRunningJob(t *testing.T) {
+	jobFile := filepath.Join(t.TempDir(), "job.json")
+	unlock, err := lockBatchJob(jobFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lockBatchJob(jobFile); err == nil {
+		t.Errorf("lockBatchJob() expected an error while the job is locked")
+	}
+	unlock()
+	unlock, err = lockBatchJob(jobFile)
+	if err != nil {
+		t.Errorf("lockBatchJob() after the lock was released = %v", err)
+	} else {
+		unlock()
+	}
+
+	// The parent of the test is alive, the test itself is the process resuming the job
+	if err := (&batchJob{PID: os.Getppid()}).checkNotRunning(jobFile); err == nil {
+		t.Errorf("checkNotRunning() expected an error for a job whose process is alive")
+	}
+	if err := (&batchJob{PID: os.Getpid()}).checkNotRunning(jobFile); err != nil {
+		t.Errorf("checkNotRunning() for the current process = %v", err)
+	}
+	if err := (&batchJob{}).checkNotRunning(jobFile); err != nil {
+		t.Errorf("checkNotRunning() for a job never run = %v", err)
+	}
+}
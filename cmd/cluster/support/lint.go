@@ -0,0 +1,87 @@
+package support
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/spf13/viper"
+)
+
+// LintDisabledRulesKey can be set in the osdctl configuration file to a list of lint rule names that --lint should skip
+const LintDisabledRulesKey = "support_lint_disabled_rules"
+
+var (
+	automatedDetectionRE = regexp.MustCompile(`(?i)\b(automat(ed|ic|ically|ion)|auto-detected|detected by (monitoring|an alert))\b`)
+	manualDetectionRE    = regexp.MustCompile(`(?i)\b(manual(ly)?|SRE (review|investigation)|during (a|an) (review|investigation))\b`)
+)
+
+// lintRule is a correctness check of a rendered limited support reason.
+// check returns a warning for every inconsistency found, or nothing when the reason is fine.
+type lintRule struct {
+	name  string
+	check func(reason *cmv1.LimitedSupportReason) []string
+}
+
+// lintRules are the rules applied by --lint, in order
+var lintRules = []lintRule{
+	{
+		name: "detection-type-known",
+		check: func(reason *cmv1.LimitedSupportReason) []string {
+			switch reason.DetectionType() {
+			case cmv1.DetectionTypeManual, cmv1.DetectionTypeAuto:
+				return nil
+			}
+			return []string{fmt.Sprintf("detection_type %q is neither %q nor %q", reason.DetectionType(), cmv1.DetectionTypeManual, cmv1.DetectionTypeAuto)}
+		},
+	},
+	{
+		name: "manual-detection-wording",
+		check: func(reason *cmv1.LimitedSupportReason) []string {
+			if reason.DetectionType() != cmv1.DetectionTypeManual {
+				return nil
+			}
+			return detectionWordingWarnings(reason, automatedDetectionRE, "automated")
+		},
+	},
+	{
+		name: "auto-detection-wording",
+		check: func(reason *cmv1.LimitedSupportReason) []string {
+			if reason.DetectionType() != cmv1.DetectionTypeAuto {
+				return nil
+			}
+			return detectionWordingWarnings(reason, manualDetectionRE, "manual")
+		},
+	},
+}
+
+func detectionWordingWarnings(reason *cmv1.LimitedSupportReason, re *regexp.Regexp, detection string) []string {
+	var warnings []string
+	for field, text := range map[string]string{"summary": reason.Summary(), "details": reason.Details()} {
+		if match := re.FindString(text); match != "" {
+			warnings = append(warnings, fmt.Sprintf("detection_type is %q but the %s mentions %s detection (%q)", reason.DetectionType(), field, detection, match))
+		}
+	}
+	slices.Sort(warnings)
+	return warnings
+}
+
+// lintLimitedSupportReason applies the lint rules which are not disabled in the osdctl configuration
+// to the reason, and writes a warning for every finding. It returns the number of findings.
+func lintLimitedSupportReason(out io.Writer, reason *cmv1.LimitedSupportReason) int {
+	disabled := viper.GetStringSlice(LintDisabledRulesKey)
+
+	var findings int
+	for _, rule := range lintRules {
+		if slices.Contains(disabled, rule.name) {
+			continue
+		}
+		for _, warning := range rule.check(reason) {
+			findings++
+			fmt.Fprintf(out, "Warning: [%s] %s\n", rule.name, warning)
+		}
+	}
+	return findings
+}
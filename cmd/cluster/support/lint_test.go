@@ -0,0 +1,72 @@
+package support
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/spf13/viper"
+)
+
+func Test_lintLimitedSupportReason(t *testing.T) {
+	defer viper.Reset()
+
+	tests := []struct {
+		name          string
+		summary       string
+		detectionType cmv1.DetectionType
+		disabled      []string
+		wantRules     []string
+	}{
+		{
+			name:          "Consistent manual reason",
+			summary:       "Cluster is in Limited Support due to unsupported cluster configuration",
+			detectionType: cmv1.DetectionTypeManual,
+		},
+		{
+			name:          "Manual reason referencing automated detection",
+			summary:       "Cluster was automatically detected with an unsupported configuration",
+			detectionType: cmv1.DetectionTypeManual,
+			wantRules:     []string{"manual-detection-wording"},
+		},
+		{
+			name:          "Auto reason referencing a manual review",
+			summary:       "Cluster was manually found with an unsupported configuration",
+			detectionType: cmv1.DetectionTypeAuto,
+			wantRules:     []string{"auto-detection-wording"},
+		},
+		{
+			name:          "Unknown detection type",
+			summary:       "Cluster is in Limited Support",
+			detectionType: "sometimes",
+			wantRules:     []string{"detection-type-known"},
+		},
+		{
+			name:          "Disabled rule",
+			summary:       "Cluster was automatically detected with an unsupported configuration",
+			detectionType: cmv1.DetectionTypeManual,
+			disabled:      []string{"manual-detection-wording"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set(LintDisabledRulesKey, tt.disabled)
+			reason, err := cmv1.NewLimitedSupportReason().Summary(tt.summary).Details("Details").DetectionType(tt.detectionType).Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			if got := lintLimitedSupportReason(&out, reason); got != len(tt.wantRules) {
+				t.Errorf("lintLimitedSupportReason() = %d findings, want %d: %s", got, len(tt.wantRules), out.String())
+			}
+			for _, rule := range tt.wantRules {
+				if !strings.Contains(out.String(), "["+rule+"]") {
+					t.Errorf("expected a warning from %s, got %q", rule, out.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package support
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// listSuffix turns the placeholder of a parameter into its list form: '${NODES[]}' is replaced by the
+// comma-separated items of NODES as a JSON array, while '${NODES}' is replaced by the value as given
+const listSuffix = "[]"
+
+// listPlaceholder returns the list form of a placeholder, eg. '${NODES[]}' for '${NODES}'
+func listPlaceholder(placeholder string) string {
+	return strings.TrimSuffix(placeholder, "}") + listSuffix + "}"
+}
+
+// usesParam is true when the text uses the placeholder in either of its forms
+func usesParam(text string, placeholder string) bool {
+	return strings.Contains(text, placeholder) || strings.Contains(text, listPlaceholder(placeholder))
+}
+
+// listValue renders a comma-separated parameter value as a JSON array of strings, eg. '["a","b","c"]' for
+// 'a, b,c'. Items are trimmed and empty ones are dropped.
+func listValue(value string) (string, error) {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	data, err := json.Marshal(items)
+	if err != nil {
+		return "", fmt.Errorf("cannot render %q as a list: %w", value, err)
+	}
+	return string(data), nil
+}
+
+// substituteParam replaces both forms of the placeholder in the text
+func substituteParam(text string, placeholder string, value string) (string, error) {
+	if list := listPlaceholder(placeholder); strings.Contains(text, list) {
+		rendered, err := listValue(value)
+		if err != nil {
+			return "", err
+		}
+		text = strings.ReplaceAll(text, list, rendered)
+	}
+	return strings.ReplaceAll(text, placeholder, value), nil
+}
@@ -0,0 +1,71 @@
+package support
+
+import (
+	"testing"
+)
+
+func Test_buildLimitedSupportTemplateListParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   []string
+		cluster  map[string]string
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "The plain form keeps the comma-joined value",
+			params:   []string{"NODES=a, b,c"},
+			template: `{"summary":"Summary","details":"Drain ${NODES}","detection_type":"manual"}`,
+			want:     "Drain a, b,c",
+		},
+		{
+			name:     "The list form renders a JSON array",
+			params:   []string{"NODES=a, b,,c"},
+			template: `{"summary":"Summary","details":"Drain ${NODES[]}","detection_type":"manual"}`,
+			want:     `Drain ["a","b","c"]`,
+		},
+		{
+			name:     "Both forms can be used together",
+			params:   []string{"NODES=a,b"},
+			template: `{"summary":"Summary","details":"${NODES}: ${NODES[]}","detection_type":"manual"}`,
+			want:     `a,b: ["a","b"]`,
+		},
+		{
+			name:     "Items are escaped",
+			cluster:  map[string]string{"NODES": `say "hi",x`},
+			template: `{"summary":"Summary","details":"${NODES[]}","detection_type":"manual"}`,
+			want:     `["say \"hi\"","x"]`,
+		},
+		{
+			name:     "An unset list is missing",
+			template: `{"summary":"Summary","details":"Drain ${NODES[]}","detection_type":"manual"}`,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Post{Template: "template.json", TemplateParams: tt.params, templateBytes: []byte(tt.template)}
+			reason, err := p.buildLimitedSupportTemplate(tt.cluster)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("buildLimitedSupportTemplate() expected an error, got %q", reason.Details())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reason.Details() != tt.want {
+				t.Errorf("details = %q, want %q", reason.Details(), tt.want)
+			}
+		})
+	}
+}
+
+func Test_placeholderProblemsListParams(t *testing.T) {
+	template := &TemplateFile{Details: "Drain ${NODES[]}", Parameters: []TemplateParameter{{Name: "NODES", Required: true}}}
+	if problems := placeholderProblems(template); len(problems) != 0 {
+		t.Errorf("placeholderProblems() = %v, want the list form to use the declared parameter", problems)
+	}
+}
@@ -0,0 +1,78 @@
+package support
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// LogFormatKey selects how the logs of the support commands are written, 'text' or 'json'
+	LogFormatKey = "support_log_format"
+	// LogLevelKey is the minimum level of the logs written, 'debug', 'info', 'warn' or 'error'
+	LogLevelKey = "support_log_level"
+)
+
+var (
+	currentLogger *slog.Logger
+	loggerOnce    sync.Once
+)
+
+// logger returns the logger of the support commands, writing to stderr while results are printed on stdout.
+// It is configured from LogFormatKey and LogLevelKey the first time it is used, once flags were parsed.
+func logger() *slog.Logger {
+	loggerOnce.Do(func() {
+		if err := configureLogging(); err != nil {
+			currentLogger = slog.New(newLogHandler(os.Stderr, "text", slog.LevelInfo))
+			currentLogger.Warn("Ignoring the invalid logging configuration", "error", err)
+		}
+	})
+	return currentLogger
+}
+
+// configureLogging sets the logger up with the log format and level from the flags or the osdctl configuration
+func configureLogging() error {
+	l, err := newLogger(os.Stderr, viper.GetString(LogFormatKey), viper.GetString(LogLevelKey))
+	if err != nil {
+		return err
+	}
+	currentLogger = l
+	return nil
+}
+
+// newLogger returns a logger writing to w, an empty format or level stands for the default 'text' and 'info'
+func newLogger(w io.Writer, format string, level string) (*slog.Logger, error) {
+	var logLevel slog.Level
+	if level != "" {
+		if err := logLevel.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("invalid log level %q, expected 'debug', 'info', 'warn' or 'error'", level)
+		}
+	}
+	switch strings.ToLower(format) {
+	case "", "text", "json":
+		return slog.New(newLogHandler(w, strings.ToLower(format), logLevel)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q, expected 'text' or 'json'", format)
+	}
+}
+
+func newLogHandler(w io.Writer, format string, level slog.Level) slog.Handler {
+	if format == "json" {
+		return slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})
+	}
+	return slog.NewTextHandler(w, &slog.HandlerOptions{
+		Level: level,
+		// Text logs are read by the person running the command, the time only adds noise
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if len(groups) == 0 && attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	})
+}
@@ -0,0 +1,44 @@
+package support
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func Test_newLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := newLogger(&buf, "json", "warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("hidden")
+	l.Warn("cluster is hibernating", "cluster", "abc")
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected a single JSON record, got %q: %v", buf.String(), err)
+	}
+	if record["level"] != "WARN" || record["msg"] != "cluster is hibernating" || record["cluster"] != "abc" {
+		t.Errorf("unexpected record %v", record)
+	}
+
+	buf.Reset()
+	l, err = newLogger(&buf, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Debug("hidden")
+	l.Info("posted", "cluster", "abc")
+	if got := strings.TrimSpace(buf.String()); got != "level=INFO msg=posted cluster=abc" {
+		t.Errorf("text log = %q, want the level, message and attributes without a time", got)
+	}
+
+	if _, err := newLogger(&buf, "xml", ""); err == nil {
+		t.Errorf("newLogger() expected an error for an invalid format")
+	}
+	if _, err := newLogger(&buf, "text", "verbose"); err == nil {
+		t.Errorf("newLogger() expected an error for an invalid level")
+	}
+}
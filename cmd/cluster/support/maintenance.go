@@ -0,0 +1,92 @@
+package support
+
+import (
+	"fmt"
+	"time"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+)
+
+// defaultMaintenanceWindow is how long a scheduled upgrade is assumed to keep the cluster in maintenance after it
+// starts, unless --maintenance-window is given
+const defaultMaintenanceWindow = 2 * time.Hour
+
+// scheduledUpgrade is an upgrade of the cluster scheduled through an OCM upgrade policy
+type scheduledUpgrade struct {
+	version string
+	nextRun time.Time
+}
+
+// scheduledUpgrades returns the upgrades scheduled for the cluster, from the control plane upgrade policies of
+// HCP clusters and from the upgrade policies of the other clusters
+func scheduledUpgrades(connection *sdk.Connection, cluster *cmv1.Cluster) ([]scheduledUpgrade, error) {
+	clusterClient := connection.ClustersMgmt().V1().Clusters().Cluster(cluster.ID())
+	var upgrades []scheduledUpgrade
+	if cluster.Hypershift().Enabled() {
+		response, err := clusterClient.ControlPlane().UpgradePolicies().List().Send()
+		if err != nil {
+			return nil, err
+		}
+		for _, policy := range response.Items().Slice() {
+			upgrades = append(upgrades, scheduledUpgrade{version: policy.Version(), nextRun: policy.NextRun()})
+		}
+		return upgrades, nil
+	}
+
+	response, err := clusterClient.UpgradePolicies().List().Send()
+	if err != nil {
+		return nil, err
+	}
+	for _, policy := range response.Items().Slice() {
+		upgrades = append(upgrades, scheduledUpgrade{version: policy.Version(), nextRun: policy.NextRun()})
+	}
+	return upgrades, nil
+}
+
+// coincidingUpgrade returns the upgrade whose maintenance window includes now, if any
+func coincidingUpgrade(upgrades []scheduledUpgrade, now time.Time, window time.Duration) *scheduledUpgrade {
+	for i, upgrade := range upgrades {
+		if upgrade.nextRun.IsZero() {
+			continue
+		}
+		if !now.Before(upgrade.nextRun) && now.Before(upgrade.nextRun.Add(window)) {
+			return &upgrades[i]
+		}
+	}
+	return nil
+}
+
+// checkMaintenanceWindow refuses to post while the cluster is in the maintenance window of a scheduled upgrade,
+// unless --ignore-maintenance is given. It is only checked with --check-maintenance, as it costs an OCM request
+// per cluster. The check is best effort, failing to look up the schedule is a warning.
+func (p *Post) checkMaintenanceWindow(connection *sdk.Connection, cluster *cmv1.Cluster) error {
+	if !p.checkMaintenance {
+		return nil
+	}
+	var upgrades []scheduledUpgrade
+	err := retryOCM(p.retries, func() error {
+		var err error
+		upgrades, err = scheduledUpgrades(connection, cluster)
+		return err
+	})
+	if err != nil {
+		logger().Warn("Could not look up the maintenance schedule of the cluster", "cluster", cluster.ID(), "error", err)
+		return nil
+	}
+	window := p.maintenanceWindow
+	if window <= 0 {
+		window = defaultMaintenanceWindow
+	}
+	upgrade := coincidingUpgrade(upgrades, time.Now(), window)
+	if upgrade == nil {
+		return nil
+	}
+
+	logger().Warn("The cluster is in the maintenance window of a scheduled upgrade", "cluster", cluster.ID(), "version", upgrade.version, "next_run", upgrade.nextRun.UTC().Format(time.RFC3339))
+	if p.isDryRun || p.ignoreMaintenance {
+		return nil
+	}
+	return fmt.Errorf("cluster %s is in the maintenance window of its upgrade to %s scheduled at %s, use --ignore-maintenance to post anyway",
+		cluster.ID(), upgrade.version, upgrade.nextRun.UTC().Format(time.RFC3339))
+}
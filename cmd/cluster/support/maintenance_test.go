@@ -0,0 +1,58 @@
+package support
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+)
+
+func Test_coincidingUpgrade(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	upgrades := []scheduledUpgrade{
+		{version: "4.15.1", nextRun: now.Add(time.Hour)},
+		{version: "4.15.0", nextRun: now.Add(-defaultMaintenanceWindow)},
+		{version: "unscheduled"},
+	}
+	if upgrade := coincidingUpgrade(upgrades, now, defaultMaintenanceWindow); upgrade != nil {
+		t.Errorf("coincidingUpgrade() = %+v, the upcoming and past upgrades don't coincide", upgrade)
+	}
+
+	upgrades = append(upgrades, scheduledUpgrade{version: "4.14.9", nextRun: now.Add(-time.Hour)})
+	if upgrade := coincidingUpgrade(upgrades, now, defaultMaintenanceWindow); upgrade == nil || upgrade.version != "4.14.9" {
+		t.Errorf("coincidingUpgrade() = %+v, want the upgrade which started an hour ago", upgrade)
+	}
+	if upgrade := coincidingUpgrade(upgrades, now.Add(time.Hour), defaultMaintenanceWindow); upgrade == nil {
+		t.Error("coincidingUpgrade() = nil at the start of the next upgrade")
+	}
+	// The window is configurable
+	if upgrade := coincidingUpgrade(upgrades[:3], now, 3*time.Hour); upgrade == nil || upgrade.version != "4.15.0" {
+		t.Errorf("coincidingUpgrade() = %+v with a 3h window, want the upgrade which started 2h ago", upgrade)
+	}
+}
+
+func Test_checkMaintenanceWindowIsOptIn(t *testing.T) {
+	const policies = "GET /api/clusters_mgmt/v1/clusters/cluster-id/upgrade_policies"
+	nextRun := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	fake, connection := newFakeOCM(t, map[string]http.HandlerFunc{
+		policies: respond(http.StatusOK, `{"kind":"UpgradePolicyList","page":1,"size":1,"total":1,"items":[
+			{"kind":"UpgradePolicy","id":"policy-id","version":"4.15.1","next_run":"`+nextRun+`"}]}`),
+	})
+	cluster, err := cmv1.NewCluster().ID("cluster-id").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (&Post{}).checkMaintenanceWindow(connection, cluster); err != nil || fake.count(policies) != 0 {
+		t.Errorf("checkMaintenanceWindow() = %v after %d lookup(s), want the schedule to be ignored by default", err, fake.count(policies))
+	}
+	err = (&Post{checkMaintenance: true}).checkMaintenanceWindow(connection, cluster)
+	if err == nil || !strings.Contains(err.Error(), "maintenance window") {
+		t.Errorf("checkMaintenanceWindow() with --check-maintenance = %v, want the post to be refused", err)
+	}
+	if err := (&Post{checkMaintenance: true, ignoreMaintenance: true}).checkMaintenanceWindow(connection, cluster); err != nil {
+		t.Errorf("checkMaintenanceWindow() with --ignore-maintenance = %v, want a warning only", err)
+	}
+}
@@ -0,0 +1,20 @@
+package support
+
+import (
+	"fmt"
+	"io"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+)
+
+// printOCMCommand prints the ocm CLI invocation posting the rendered reason to the cluster, for SREs who run
+// high-risk changes themselves. The body is given through a quoted heredoc, so that the shell doesn't expand it.
+func printOCMCommand(out io.Writer, clusterID string, limitedSupport *cmv1.LimitedSupportReason) error {
+	body := marshalReason(limitedSupport)
+	if body == nil {
+		return fmt.Errorf("failed to marshal the limited support reason of cluster %s", clusterID)
+	}
+	fmt.Fprintf(out, "To post the limited support reason to %s with the ocm CLI, run:\n", clusterID)
+	fmt.Fprintf(out, "ocm post %s --body - <<'EOF'\n%s\nEOF\n", reasonsAPIPath(clusterID, ""), body)
+	return nil
+}
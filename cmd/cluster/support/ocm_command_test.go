@@ -0,0 +1,72 @@
+package support
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+)
+
+func Test_printOCMCommand(t *testing.T) {
+	reason, err := cmv1.NewLimitedSupportReason().Summary("Summary").Details(`Remove the "custom" controller, it's $unsupported`).DetectionType(cmv1.DetectionTypeManual).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := printOCMCommand(&out, "abc123", reason); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) < 4 {
+		t.Fatalf("printOCMCommand() = %q, want the command, its body and the end of the heredoc", out.String())
+	}
+	if want := "ocm post /api/clusters_mgmt/v1/clusters/abc123/limited_support_reasons --body - <<'EOF'"; lines[1] != want {
+		t.Errorf("command = %q, want %q", lines[1], want)
+	}
+	// JSON strings can't contain newlines, no line of the body can end the heredoc
+	payload := strings.Join(lines[2:len(lines)-1], "\n")
+	var body map[string]interface{}
+	if err := json.Unmarshal([]byte(payload), &body); err != nil {
+		t.Fatalf("body %q isn't JSON: %v", payload, err)
+	}
+	if body["details"] != reason.Details() {
+		t.Errorf("details = %q, want %q", body["details"], reason.Details())
+	}
+	if lines[len(lines)-1] != "EOF" {
+		t.Errorf("last line = %q, want the end of the heredoc", lines[len(lines)-1])
+	}
+}
+
+func Test_checkEmitOCMCommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		post    *Post
+		wantErr bool
+	}{
+		{
+			name:    "Requires a dry-run",
+			post:    &Post{emitOCMCommands: true, Template: "template.json"},
+			wantErr: true,
+		},
+		{
+			name:    "Conflicts with --upsert",
+			post:    &Post{emitOCMCommands: true, isDryRun: true, Template: "template.json", upsertReasonID: "reason"},
+			wantErr: true,
+		},
+		{
+			name: "Accepts a dry-run",
+			post: &Post{emitOCMCommands: true, isDryRun: true, Template: "template.json"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.post.check(); (err != nil) != tt.wantErr {
+				t.Errorf("check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package support
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	ctlutil "github.com/openshift/osdctl/pkg/utils"
+)
+
+// fakeOCM is an OCM API served by the handlers of its routes, which are method and path (eg. 'POST /api/...').
+// It counts the requests of every route.
+type fakeOCM struct {
+	mu       sync.Mutex
+	routes   map[string]http.HandlerFunc
+	requests map[string]int
+}
+
+// newFakeOCM serves the routes and returns a connection to them
+func newFakeOCM(t *testing.T, routes map[string]http.HandlerFunc) (*fakeOCM, *sdk.Connection) {
+	t.Helper()
+	fake := &fakeOCM{routes: routes, requests: map[string]int{}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.Method + " " + r.URL.Path
+		fake.mu.Lock()
+		fake.requests[route]++
+		fake.mu.Unlock()
+		handler, ok := fake.routes[route]
+		if !ok {
+			http.Error(w, `{"kind":"Error","reason":"not found"}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	// The SDK only parses the token, it doesn't check its signature
+	encode := base64.RawURLEncoding.EncodeToString
+	token := encode([]byte(`{"alg":"none","typ":"JWT"}`)) + "." +
+		encode([]byte(fmt.Sprintf(`{"typ":"Bearer","exp":%d}`, time.Now().Add(time.Hour).Unix()))) + "."
+	connection, err := sdk.NewConnectionBuilder().URL(server.URL).Tokens(token).RetryLimit(0).TransportWrapper(ctlutil.BodyCaptureWrapper).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = connection.Close() })
+	return fake, connection
+}
+
+// count returns the number of requests made to the route
+func (f *fakeOCM) count(route string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[route]
+}
+
+// respond returns a handler replying with the status and body
+func respond(status int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}
+}
@@ -0,0 +1,124 @@
+package support
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+// templateVarFileFlag is an alias of --params-file for teams whose tooling produces TOML or HCL variables files
+const templateVarFileFlag = "template-var-file"
+
+// paramsFileAlias normalizes --template-var-file to --params-file, so that both flags fill the same list of files
+// in the order they are given on the command line
+func paramsFileAlias(_ *pflag.FlagSet, name string) pflag.NormalizedName {
+	if name == templateVarFileFlag {
+		name = "params-file"
+	}
+	return pflag.NormalizedName(name)
+}
+
+// fileParameter is a parameter set by --params-file
+type fileParameter struct {
+	value string
+	// files lists every file setting the parameter, in order, the value comes from the last one
+	files []string
+}
+
+// source describes where the value of the parameter comes from, and which files it overrides
+func (f *fileParameter) source() string {
+	sources := make([]string, 0, len(f.files))
+	for _, file := range f.files {
+		sources = append(sources, "--params-file "+file)
+	}
+	return overriding(sources[len(sources)-1], sources[:len(sources)-1]...)
+}
+
+// overriding appends the overridden sources, if any, to the source of a value
+func overriding(source string, overridden ...string) string {
+	for i := len(overridden) - 1; i >= 0; i-- {
+		source += ", overriding " + overridden[i]
+	}
+	return source
+}
+
+// loadParamsFiles merges the --params-file files in order, later files overriding earlier ones.
+// The files are only read once, so they can be used to render the template for many clusters.
+func (p *Post) loadParamsFiles() error {
+	if p.fileParams != nil || len(p.ParamsFiles) == 0 {
+		return nil
+	}
+
+	fileParams := map[string]*fileParameter{}
+	for _, path := range p.ParamsFiles {
+		contents, err := p.accessFile(path)
+		if err != nil {
+			return err
+		}
+		params, err := parseParamsFile(path, contents)
+		if err != nil {
+			return err
+		}
+		for name, value := range params {
+			if _, ok := fileParams[name]; !ok {
+				fileParams[name] = &fileParameter{}
+			}
+			fileParams[name].value = value
+			fileParams[name].files = append(fileParams[name].files, path)
+		}
+	}
+	p.fileParams = fileParams
+	return nil
+}
+
+// parseParamsFile parses an object mapping parameter names to scalar values. The format is detected from the
+// extension of the file: TOML for .toml, HCL for .hcl and .tfvars, YAML or JSON otherwise.
+func parseParamsFile(path string, contents []byte) (map[string]string, error) {
+	var raw map[string]interface{}
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		err = toml.Unmarshal(contents, &raw)
+	case ".hcl", ".tfvars":
+		err = hcl.Unmarshal(contents, &raw)
+	default:
+		err = yaml.Unmarshal(contents, &raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse the parameters file %s: %w", path, err)
+	}
+	return scalarParams(path, raw)
+}
+
+// scalarParams converts the parsed parameters of the file to strings, they must be non-empty scalars
+func scalarParams(path string, raw map[string]interface{}) (map[string]string, error) {
+	params := make(map[string]string, len(raw))
+	for name, value := range raw {
+		switch value.(type) {
+		// HCL decodes blocks as lists of objects
+		case map[string]interface{}, []interface{}, []map[string]interface{}, nil:
+			return nil, fmt.Errorf("parameter %s of %s must be a string, number or boolean", name, path)
+		}
+		params[name] = fmt.Sprint(value)
+		if name == "" || params[name] == "" {
+			return nil, fmt.Errorf("the parameters file %s has an empty parameter name or value", path)
+		}
+	}
+	return params, nil
+}
+
+// fileParamNames returns the names of the parameters set by --params-file, sorted
+func (p *Post) fileParamNames() []string {
+	names := make([]string, 0, len(p.fileParams))
+	for name := range p.fileParams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
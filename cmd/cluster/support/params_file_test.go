@@ -0,0 +1,103 @@
+package support
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_paramsFilesPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	defaults := filepath.Join(dir, "defaults.yaml")
+	if err := os.WriteFile(defaults, []byte("TEAM: sre\nZONE: us-east-1a\nUNUSED: value\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	incident := filepath.Join(dir, "incident.json")
+	if err := os.WriteFile(incident, []byte(`{"ZONE": "us-west-2b", "NAME": "foo", "COUNT": 3}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Post{
+		Template:       "template.json",
+		ParamsFiles:    []string{defaults, incident},
+		TemplateParams: []string{"NAME=bar"},
+		templateBytes:  []byte(`{"summary":"Summary","details":"${TEAM} ${ZONE} ${NAME} ${COUNT}","detection_type":"manual"}`),
+	}
+	reason, err := p.buildLimitedSupportTemplate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "sre us-west-2b bar 3"; reason.Details() != want {
+		t.Errorf("details = %q, want %q", reason.Details(), want)
+	}
+
+	wantSources := map[string]string{
+		"${TEAM}":   "--params-file " + defaults,
+		"${ZONE}":   "--params-file " + incident + ", overriding --params-file " + defaults,
+		"${NAME}":   "--param, overriding --params-file " + incident,
+		"${COUNT}":  "--params-file " + incident,
+		"${UNUSED}": "--params-file " + defaults,
+	}
+	for placeholder, want := range wantSources {
+		if got := p.paramSources[placeholder]; got != want {
+			t.Errorf("source of %s = %q, want %q", placeholder, got, want)
+		}
+	}
+}
+
+func Test_parseParamsFile(t *testing.T) {
+	if _, err := parseParamsFile("nested.yaml", []byte("FOO:\n  BAR: baz\n")); err == nil {
+		t.Errorf("parseParamsFile() expected an error for a nested value")
+	}
+	if _, err := parseParamsFile("empty-value.yaml", []byte("FOO: \"\"\n")); err == nil {
+		t.Errorf("parseParamsFile() expected an error for an empty value")
+	}
+}
+
+func Test_parseParamsFileFormats(t *testing.T) {
+	tests := []struct {
+		path     string
+		contents string
+	}{
+		{path: "vars.yaml", contents: "TEAM: sre\nCOUNT: 3\n"},
+		{path: "vars.json", contents: `{"TEAM": "sre", "COUNT": 3}`},
+		{path: "vars.toml", contents: "TEAM = \"sre\"\nCOUNT = 3\n"},
+		{path: "vars.hcl", contents: "TEAM = \"sre\"\nCOUNT = 3\n"},
+		{path: "vars.TFVARS", contents: "TEAM = \"sre\"\nCOUNT = 3\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			params, err := parseParamsFile(tt.path, []byte(tt.contents))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if params["TEAM"] != "sre" || params["COUNT"] != "3" {
+				t.Errorf("parseParamsFile() = %v", params)
+			}
+		})
+	}
+
+	if _, err := parseParamsFile("nested.toml", []byte("[FOO]\nBAR = \"baz\"\n")); err == nil {
+		t.Errorf("parseParamsFile() expected an error for a TOML table")
+	}
+	if _, err := parseParamsFile("nested.hcl", []byte("FOO {\n  BAR = \"baz\"\n}\n")); err == nil {
+		t.Errorf("parseParamsFile() expected an error for an HCL block")
+	}
+	if _, err := parseParamsFile("invalid.toml", []byte("TEAM: sre\n")); err == nil {
+		t.Errorf("parseParamsFile() expected an error for YAML in a .toml file")
+	}
+}
+
+func Test_templateVarFileAlias(t *testing.T) {
+	cmd := newCmdpost(nil, nil)
+	if err := cmd.ParseFlags([]string{"--template-var-file", "vars.toml", "--params-file", "defaults.yaml"}); err != nil {
+		t.Fatal(err)
+	}
+	files, err := cmd.Flags().GetStringArray("params-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0] != "vars.toml" || files[1] != "defaults.yaml" {
+		t.Errorf("params files = %v, want [vars.toml defaults.yaml]", files)
+	}
+}
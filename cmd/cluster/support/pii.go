@@ -0,0 +1,78 @@
+package support
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/spf13/viper"
+)
+
+// PIIPatternsKey can be set in the osdctl configuration file to a map of pattern names to regular expressions
+// that --pii-check looks for, on top of the default ones. An empty expression disables the default pattern of that name.
+const PIIPatternsKey = "support_pii_patterns"
+
+const (
+	piiCheckWarn   = "warn"
+	piiCheckStrict = "strict"
+)
+
+// defaultPIIPatterns are the patterns --pii-check looks for unless they are overridden in the osdctl configuration
+var defaultPIIPatterns = map[string]string{
+	"email":          `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+	"ipv4":           `\b(?:(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])\b`,
+	"aws-access-key": `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	"secret":         `(?i)\b(?:password|passwd|secret|token|api[_-]?key)\s*[:=]\s*\S+`,
+}
+
+// piiPatterns returns the compiled patterns, by name, merging the osdctl configuration into the defaults
+func piiPatterns() (map[string]*regexp.Regexp, error) {
+	sources := map[string]string{}
+	for name, expression := range defaultPIIPatterns {
+		sources[name] = expression
+	}
+	for name, expression := range viper.GetStringMapString(PIIPatternsKey) {
+		sources[name] = expression
+	}
+
+	patterns := map[string]*regexp.Regexp{}
+	for name, expression := range sources {
+		if expression == "" {
+			continue
+		}
+		re, err := regexp.Compile(expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PII pattern %q in '%s': %w", name, PIIPatternsKey, err)
+		}
+		patterns[name] = re
+	}
+	return patterns, nil
+}
+
+// checkPII warns about every match of the PII patterns in the details of the reason, which are customer facing.
+// It returns an error when anything was found in strict mode.
+func checkPII(out io.Writer, mode string, reason *cmv1.LimitedSupportReason) error {
+	patterns, err := piiPatterns()
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(patterns))
+	for name := range patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var findings int
+	for _, name := range names {
+		for _, match := range patterns[name].FindAllString(reason.Details(), -1) {
+			findings++
+			fmt.Fprintf(out, "Warning: [pii:%s] the details contain %q, limited support reasons can be visible to the customer\n", name, match)
+		}
+	}
+	if findings > 0 && mode == piiCheckStrict {
+		return fmt.Errorf("the details contain %d possible PII match(es), remove them or use '--pii-check' to only warn", findings)
+	}
+	return nil
+}
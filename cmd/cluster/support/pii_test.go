@@ -0,0 +1,78 @@
+package support
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/spf13/viper"
+)
+
+func Test_checkPII(t *testing.T) {
+	tests := []struct {
+		name         string
+		details      string
+		mode         string
+		patterns     map[string]string
+		wantWarnings []string
+		wantErr      bool
+	}{
+		{
+			name:    "Nothing to report",
+			details: "Remove the additional ingress controller",
+			mode:    piiCheckStrict,
+		},
+		{
+			name:         "Warns about emails and IPs",
+			details:      "Contact jane.doe@example.com about 10.0.0.12",
+			mode:         piiCheckWarn,
+			wantWarnings: []string{"[pii:email]", "[pii:ipv4]"},
+		},
+		{
+			name:         "Strict refuses secrets",
+			details:      "The kubeadmin password: hunter2 was found",
+			mode:         piiCheckStrict,
+			wantWarnings: []string{"[pii:secret]"},
+			wantErr:      true,
+		},
+		{
+			name:         "Configured patterns are added and can disable defaults",
+			details:      "Contact jane.doe@example.com about ACME-1234",
+			mode:         piiCheckWarn,
+			patterns:     map[string]string{"email": "", "customer-ticket": `ACME-[0-9]+`},
+			wantWarnings: []string{"[pii:customer-ticket]"},
+		},
+		{
+			name:     "Invalid configured pattern",
+			details:  "Details",
+			mode:     piiCheckWarn,
+			patterns: map[string]string{"broken": `(`},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set(PIIPatternsKey, tt.patterns)
+			defer viper.Set(PIIPatternsKey, nil)
+
+			reason, err := cmv1.NewLimitedSupportReason().Details(tt.details).Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := &bytes.Buffer{}
+			err = checkPII(out, tt.mode, reason)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkPII() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := strings.Count(out.String(), "Warning:"); got != len(tt.wantWarnings) {
+				t.Errorf("checkPII() printed %d warnings, want %d:\n%s", got, len(tt.wantWarnings), out)
+			}
+			for _, warning := range tt.wantWarnings {
+				if !strings.Contains(out.String(), warning) {
+					t.Errorf("checkPII() output is missing %s:\n%s", warning, out)
+				}
+			}
+		})
+	}
+}
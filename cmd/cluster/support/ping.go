@@ -0,0 +1,93 @@
+package support
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/openshift/osdctl/cmd/getoutput"
+	"github.com/openshift/osdctl/internal/utils/globalflags"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+type pingOptions struct {
+	output string
+
+	genericclioptions.IOStreams
+	GlobalOptions *globalflags.GlobalOptions
+}
+
+// pingOutput describes the OCM environment osdctl is connected to, it is printed according to '-o'
+type pingOutput struct {
+	URL      string `json:"url" yaml:"url"`
+	Username string `json:"username" yaml:"username"`
+	Email    string `json:"email" yaml:"email"`
+	Org      string `json:"organization" yaml:"organization"`
+	Latency  string `json:"latency" yaml:"latency"`
+}
+
+func (o pingOutput) String() string {
+	return fmt.Sprintf("Connected to %s as %s (%s) of organization %s in %s", o.URL, o.Username, o.Email, o.Org, o.Latency)
+}
+
+// newCmdping implements the ping command to check that osdctl can reach and authenticate to OCM
+func newCmdping(streams genericclioptions.IOStreams, globalOpts *globalflags.GlobalOptions) *cobra.Command {
+	ops := newPingOptions(streams, globalOpts)
+	pingCmd := &cobra.Command{
+		Use:   "ping",
+		Short: "Checks that OCM can be reached with the current credentials",
+		Long: `Checks that OCM can be reached with the current credentials, by opening a connection and fetching the authenticated account.
+Run it before a large batch so that authentication problems show up before anything is posted.`,
+		Example:           `  osdctl cluster support ping --profile production`,
+		Args:              cobra.NoArgs,
+		DisableAutoGenTag: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(ops.complete(cmd, args))
+			cmdutil.CheckErr(ops.run())
+		},
+	}
+
+	return pingCmd
+}
+
+func newPingOptions(streams genericclioptions.IOStreams, globalOpts *globalflags.GlobalOptions) *pingOptions {
+	return &pingOptions{
+		IOStreams:     streams,
+		GlobalOptions: globalOpts,
+	}
+}
+
+func (o *pingOptions) complete(cmd *cobra.Command, _ []string) error {
+	o.output = o.GlobalOptions.Output
+	return nil
+}
+
+func (o *pingOptions) run() error {
+	connection, err := createConnection()
+	if err != nil {
+		return fmt.Errorf("cannot connect to OCM: %w", err)
+	}
+	defer func() {
+		if err := connection.Close(); err != nil {
+			fmt.Printf("Cannot close the connection: %q\n", err)
+			os.Exit(1)
+		}
+	}()
+
+	start := time.Now()
+	response, err := connection.AccountsMgmt().V1().CurrentAccount().Get().Send()
+	if err != nil {
+		return fmt.Errorf("cannot authenticate to OCM at %s: %w", connection.URL(), err)
+	}
+	account := response.Body()
+
+	return getoutput.PrintResponse(o.output, pingOutput{
+		URL:      connection.URL(),
+		Username: account.Username(),
+		Email:    account.Email(),
+		Org:      account.Organization().ID(),
+		Latency:  time.Since(start).Round(time.Millisecond).String(),
+	})
+}
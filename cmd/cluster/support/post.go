@@ -2,25 +2,40 @@ package support
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"io"
 	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/blang/semver/v4"
 	"github.com/openshift-online/ocm-cli/pkg/dump"
 	sdk "github.com/openshift-online/ocm-sdk-go"
 	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
 	slv1 "github.com/openshift-online/ocm-sdk-go/servicelogs/v1"
+	"github.com/openshift/osdctl/cmd/alerts"
+	"github.com/openshift/osdctl/cmd/getoutput"
 	"github.com/openshift/osdctl/internal/utils"
+	"github.com/openshift/osdctl/internal/utils/globalflags"
+	"github.com/openshift/osdctl/pkg/printer"
 	ctlutil "github.com/openshift/osdctl/pkg/utils"
 	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
 )
 
+// incidentIDRE matches the JIRA style keys (eg. OHSS-1234) and other incident identifiers accepted by --incident-id
+var incidentIDRE = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
 const (
 	LimitedSupportSummaryCluster                         = "Cluster is in Limited Support due to unsupported cluster configuration"
 	LimitedSupportSummaryCloud                           = "Cluster is in Limited Support due to unsupported cloud provider configuration"
@@ -42,29 +57,263 @@ type Post struct {
 	Problem          string
 	Resolution       string
 	Evidence         string
-	cluster          *cmv1.Cluster
+	DescribeParams   bool
+	ClustersJSONL    string
+	ReasonFileGlob   string
+	// ClusterIDsFile lists the clusters to post to, each optionally followed by its own KEY=VALUE parameters
+	ClusterIDsFile string
+	// IncidentID is referenced at the end of the details of every posted reason
+	IncidentID string
+	// Links are appended to the details, OCM has no field to attach links to a limited support reason
+	Links []string
+	// SubscriptionSearch selects the clusters to post to through an OCM subscription search query
+	SubscriptionSearch string
+	// Query is the name of a subscription search saved in the osdctl configuration
+	Query string
+	// maxResults caps the number of clusters selected by SubscriptionSearch, Query or VersionRange, zero is no cap
+	maxResults int
+	// VersionRange selects the managed clusters to post to by their OpenShift version (eg. '>=4.12.0 <4.13.0')
+	VersionRange    string
+	isDryRun        bool
+	checkCluster    bool
+	summaryByOrg    bool
+	allowUnresolved bool
+	// lenientParams is set by --strict-params=false, placeholders the template doesn't declare are then literal content
+	lenientParams bool
+	// ExecFilter is a shell command the rendered reason is piped through before being posted
+	ExecFilter string
+	// ParamsFiles set parameters from YAML, JSON, TOML or HCL files, later files override earlier ones and '-p' overrides them all
+	ParamsFiles []string
+	// Bundle is a YAML file containing the template to post and default values for its parameters, instead of '-t'
+	Bundle string
+	// bundleTemplate and bundleParams are the JSON template and default parameters of Bundle, once read
+	bundleTemplate []byte
+	bundleParams   map[string]string
+	// JobFile persists the progress of a batch, so that it can be resumed and followed with 'job status'
+	JobFile string
+	// job is the batch persisted to JobFile
+	job *batchJob
+	// StateFile records the clusters of a batch which are done, so that running the batch again skips them
+	StateFile string
+	// state is the content of StateFile
+	state *batchState
+	// async posts the batch of JobFile in a background process, started with asyncArgs and asyncEnv
+	async     bool
+	asyncArgs []string
+	asyncEnv  []string
+	// fileParams are the merged parameters of ParamsFiles, once read
+	fileParams map[string]*fileParameter
+	// paramSources describes where the value of each placeholder comes from, for the last parsed parameters
+	paramSources map[string]string
+	// SummaryFile and DetailsFile override the summary and details of the template with the contents of a file
+	SummaryFile string
+	DetailsFile string
+	// fieldFiles caches the contents of SummaryFile and DetailsFile, so they are only fetched once per batch
+	fieldFiles map[string]string
+	// RawBodyFile is posted verbatim as the limited support reason, bypassing templating
+	RawBodyFile string
+	// expectProvider refuses to post to the clusters of another cloud provider, it overrides the template's 'provider'
+	expectProvider string
+	// emitOCMCommands prints, for a dry-run, the ocm CLI command posting the rendered reason to each cluster
+	emitOCMCommands bool
+	// renderOnlyTo is a directory where the reason rendered for each cluster is written instead of being posted
+	renderOnlyTo string
+	// deadline bounds the whole run, as a duration or an RFC3339 time
+	deadline string
+	// ctx is cancelled once the deadline has passed
+	ctx context.Context
+	// failFast stops a batch at the first cluster which could not be posted to
+	failFast bool
+	// onlyIfHealthy skips the clusters which already have any limited support reason
+	onlyIfHealthy bool
+	// clusterPrepared is set once the cluster was resolved and checked, so that posting several templates to it
+	// doesn't look it up again, nor is stopped by --only-if-healthy because of the reason of the first one
+	clusterPrepared bool
+	// excludeDeleting skips the clusters which are uninstalling or scheduled for deletion
+	excludeDeleting bool
+	// retryBudget is the number of retries a whole batch can make, with 0 only the attempts per cluster are bounded
+	retryBudget int
+	// retries is the budget of the batch being run, nil when it is unbounded
+	retries *retryBudget
+	// confirmTemplate replaces the default confirmation question, it is a text/template of confirmData
+	confirmTemplate string
+	// showAlerts prints the alerts firing on the cluster before asking for confirmation
+	showAlerts bool
+	// firingAlerts looks up the alerts shown by showAlerts, alerts.FiringAlerts when nil
+	firingAlerts func(ctx context.Context, clusterID string) ([]alerts.Alert, error)
+	// saveIODir is a directory where the body sent to OCM and its response are saved for every post
+	saveIODir string
+	// templateVersionFooter appends the '_template_version' of the template to the details
+	templateVersionFooter bool
+	// templateVersion is the '_template_version' of the template the last reason was built from
+	templateVersion string
+	// trustedInput skips the cluster key safety check for --clusters-jsonl and --cluster-ids-file.
+	// SECURITY: only for files generated by controlled automation, see checkClusterKey.
+	trustedInput bool
+	// validateSchema checks the dry-run reason against the schema of OCM's OpenAPI specification
+	validateSchema bool
+	// schema is the limited support reason schema of OCM, nil when it is unavailable
+	schema       *reasonSchema
+	schemaLoaded bool
+	// upsertReasonID is the ID of a reason posted earlier, which is updated instead of posting a new reason
+	upsertReasonID string
+	// maxTemplateBytes bounds the size of the templates and other files read by accessFile, defaultMaxTemplateBytes when zero
+	maxTemplateBytes int64
+	// thenList prints every limited support reason of the cluster once the reason was posted
+	thenList bool
+	// interactive lets the user review and deselect the clusters of a batch before posting
+	interactive bool
+	// force posts to hibernating clusters
+	force bool
+	// checkMaintenance looks up the upgrades scheduled for the cluster before posting to it
+	checkMaintenance bool
+	// ignoreMaintenance posts to clusters in the maintenance window of a scheduled upgrade
+	ignoreMaintenance bool
+	// maintenanceWindow is how long an upgrade keeps the cluster in maintenance after it starts
+	maintenanceWindow time.Duration
+	// explain annotates the dry-run output with where the value of each field comes from
+	explain bool
+	// explanation is collected while rendering the reason when explain is set
+	explanation []string
+	// verbose reports how every parameter was substituted in the template
+	verbose bool
+	// piiCheck is "warn" or "strict" when the rendered details are scanned for PII
+	piiCheck string
+	// lint warns about inconsistencies between the detection type and the content of the rendered reason
+	lint bool
+	// paramFromAWS fills the AWS placeholders of the template from the cluster's cloud account
+	paramFromAWS bool
+	// awsParams holds the AWS placeholder values of the cluster being posted to
+	awsParams map[string]string
+	// paramFromLabels fills the ${LABEL_<KEY>} placeholders of the template from the cluster's OCM labels
+	paramFromLabels bool
+	// labelParams holds the label placeholder values of the cluster being posted to
+	labelParams map[string]string
+	output      string
+	// MachinePool scopes the reason to a machine pool, it overrides the template's 'machine_pool'
+	MachinePool string
+	// scopedMachinePool is the machine pool the last built reason was scoped to
+	scopedMachinePool string
+	cluster           *cmv1.Cluster
+	templateBytes     []byte
+	// clusterIDs are the clusters to post to when several were read from stdin
+	clusterIDs []string
+	// ignoreUnusedParams is set when the '-p' flags are shared by several templates
+	ignoreUnusedParams bool
+	// allowlist is the reason allowlist read from ReasonAllowlistKey
+	allowlist *reasonAllowlist
+	// kubeCli reads 'configmap://' templates from the current kubeconfig context
+	kubeCli client.Client
 }
 
 type TemplateFile struct {
-	Severity       string             `json:"severity"`
-	Summary        string             `json:"summary"`
-	Log_type       string             `json:"log_type"`
-	Details        string             `json:"details"`
-	Detection_type cmv1.DetectionType `json:"detection_type"`
+	Severity       string              `json:"severity"`
+	Summary        string              `json:"summary"`
+	Log_type       string              `json:"log_type"`
+	Details        string              `json:"details"`
+	Detection_type cmv1.DetectionType  `json:"detection_type"`
+	Parameters     []TemplateParameter `json:"parameters,omitempty"`
+	// MachinePool optionally scopes the reason to a single machine pool (or node pool for HCP clusters)
+	MachinePool string `json:"machine_pool,omitempty"`
+	// Provider optionally restricts the reason to the clusters of a cloud provider (eg. 'aws'). It is checked
+	// whenever the cluster is resolved in OCM, which a plain dry-run doesn't do.
+	Provider string `json:"provider,omitempty"`
+	// TemplateVersion is metadata for traceability, it is recorded in the local history but never sent to OCM
+	TemplateVersion string `json:"_template_version,omitempty"`
 }
 
-var (
-	userParameterNames, userParameterValues []string
-)
+// TemplateParameter documents a '${NAME}' placeholder used by a template.
+// Templates are not required to declare their parameters, undeclared ones are treated as required.
+type TemplateParameter struct {
+	Name        string `json:"name"`
+	Required    bool   `json:"required"`
+	Description string `json:"description"`
+}
+
+// parameter returns the declaration for the given placeholder (eg. '${FOO}' or its list form '${FOO[]}'), if any
+func (t *TemplateFile) parameter(placeholder string) (TemplateParameter, bool) {
+	name := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(placeholder, "${"), "}"), listSuffix)
+	for _, param := range t.Parameters {
+		if param.Name == name {
+			return param, true
+		}
+	}
+	return TemplateParameter{}, false
+}
 
-func newCmdpost() *cobra.Command {
-	p := &Post{}
+// PostResult describes the limited support reason created by Post
+type PostResult struct {
+	ClusterID string
+	ReasonID  string
+	// Status is the HTTP status of the OCM response
+	Status    int
+	CreatedAt time.Time
+	// RawBody is the reason as returned by OCM, in JSON
+	RawBody []byte
+	// Unchanged is set when --upsert found the reason already up to date, nothing was sent
+	Unchanged bool
+}
+
+// newPostResult returns the result of a post from the reason created by OCM
+func newPostResult(clusterID string, status int, reason *cmv1.LimitedSupportReason) (*PostResult, error) {
+	body := bytes.Buffer{}
+	if err := cmv1.MarshalLimitedSupportReason(reason, &body); err != nil {
+		return nil, fmt.Errorf("failed to marshal the posted limited support reason: %w", err)
+	}
+	return &PostResult{
+		ClusterID: clusterID,
+		ReasonID:  reason.ID(),
+		Status:    status,
+		CreatedAt: reason.CreationTimestamp(),
+		RawBody:   bytes.TrimSpace(body.Bytes()),
+	}, nil
+}
+
+// previewOut is where the previews, explanations and prompts meant for the user are printed: stderr when '-o'
+// asks for a document on stdout which has to stay parsable
+func (p *Post) previewOut() io.Writer {
+	if p.output == "json" || p.output == "yaml" {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// printResult prints the result of a post according to '-o'
+func (p *Post) printResult(result *PostResult) error {
+	output := postOutput{ClusterID: result.ClusterID, ReasonID: result.ReasonID, CreatedAt: result.CreatedAt}
+	if err := getoutput.PrintResponse(p.output, output); err != nil {
+		return fmt.Errorf("failed to print the posted limited support reason: %w", err)
+	}
+	return nil
+}
+
+// postOutput describes a posted reason, it is printed according to '-o'
+type postOutput struct {
+	ClusterID string    `json:"cluster_id" yaml:"cluster_id"`
+	ReasonID  string    `json:"reason_id" yaml:"reason_id"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+}
+
+func (o postOutput) String() string {
+	return fmt.Sprintf("Successfully added new limited support reason with ID %v, created at %s", o.ReasonID, o.CreatedAt.Format(time.RFC3339))
+}
+
+func newCmdpost(client client.Client, globalOpts *globalflags.GlobalOptions) *cobra.Command {
+	p := &Post{kubeCli: client}
+	strictParams := true
 
 	postCmd := &cobra.Command{
-		Use:   "post CLUSTER_ID",
+		Use:   "post [CLUSTER_ID|-]",
 		Short: "Send limited support reason to a given cluster",
 		Long: `Sends limited support reason to a given cluster, along with an internal service log detailing why the cluster was placed into limited support.
-The caller will be prompted to continue before sending the limited support reason.`,
+The caller will be prompted to continue before sending the limited support reason.
+Cluster IDs can also be piped on stdin, one or more per line, by passing '-' or no cluster ID.
+'-', --cluster-ids-file, --clusters-jsonl, --subscription-search and --version-range can be combined: their clusters are
+posted to in this order, and a cluster selected several times, even by its name, internal and external ID, is posted to once.
+When posting to several clusters, '-o markdown' prints a Markdown table of the result of every cluster once the batch is over, to be pasted in chats and tickets.
+With '-o json' or '-o yaml', the previews and the confirmation prompt are printed on stderr, so that stdout only has the result.
+A parameter listing several items is given comma-separated (eg. -p NODES=a,b,c). Templates use '${NODES}' for the
+comma-joined string as given, or '${NODES[]}' for a JSON array of the trimmed items, eg. ["a","b","c"].`,
 		Example: `# Post a limited support reason for a cluster misconfiguration
 osdctl cluster support post 1a2B3c4DefghIjkLMNOpQrSTUV5 --misconfiguration cluster --problem="The cluster has a second failing ingress controller, which is not supported and can cause issues with SLA." \
 --resolution="Remove the additional ingress controller 'my-custom-ingresscontroller'. 'oc get ingresscontroller -n openshift-ingress-operator' should yield only 'default'" \
@@ -73,10 +322,96 @@ osdctl cluster support post 1a2B3c4DefghIjkLMNOpQrSTUV5 --misconfiguration clust
 Will result in the following limited-support text sent to the customer:
 The cluster has a second failing ingress controller, which is not supported and can cause issues with SLA. Remove the additional ingress controller 'my-custom-ingresscontroller'. 'oc get ingresscontroller -n openshift-ingress-operator' should yield only 'default'.
 `,
-		Args:              cobra.ExactArgs(1),
+		Args:              cobra.RangeArgs(0, 1),
 		DisableAutoGenTag: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := p.Run(args[0]); err != nil {
+			if err := configureLogging(); err != nil {
+				return err
+			}
+			p.output = globalOpts.Output
+			p.lenientParams = !strictParams
+			if p.deadline != "" {
+				deadline, err := parseDeadline(p.deadline, time.Now())
+				if err != nil {
+					return err
+				}
+				ctx, cancel := context.WithDeadline(context.Background(), deadline)
+				defer cancel()
+				p.ctx = ctx
+			}
+			// Nothing is posted when rendering to a directory
+			if p.renderOnlyTo != "" {
+				p.isDryRun = true
+			}
+			if p.DescribeParams {
+				return p.describeParameters()
+			}
+			if p.Query != "" {
+				if p.SubscriptionSearch != "" {
+					return errors.New("--query and --subscription-search cannot be used together")
+				}
+				search, err := resolveSavedQuery(p.Query)
+				if err != nil {
+					return err
+				}
+				p.SubscriptionSearch = search
+			}
+			if p.async {
+				p.asyncArgs, p.asyncEnv = asyncJobArgs(cmd)
+			}
+			// An existing job file lists the clusters of the batch to resume
+			resumesJob := false
+			if p.JobFile != "" {
+				_, err := os.Stat(p.JobFile)
+				resumesJob = err == nil
+			}
+			if p.ClustersJSONL != "" || p.ClusterIDsFile != "" || p.SubscriptionSearch != "" || p.VersionRange != "" || resumesJob {
+				// Only the clusters piped on stdin can be combined with the other sources
+				if len(args) != 0 && !(len(args) == 1 && args[0] == "-") {
+					return errors.New("a cluster ID cannot be given together with --clusters-jsonl, --cluster-ids-file, --subscription-search, --version-range or an existing --job-file, pass '-' to read them from stdin")
+				}
+				if len(args) == 1 {
+					clusterIDs, err := clusterIDArgs(args, os.Stdin)
+					if err != nil {
+						return err
+					}
+					p.clusterIDs = clusterIDs
+				}
+				if err := p.RunBatch(); err != nil {
+					return fmt.Errorf("error posting limited support reasons: %w", err)
+				}
+				return nil
+			}
+			clusterIDs, err := clusterIDArgs(args, os.Stdin)
+			if err != nil {
+				return err
+			}
+			if len(clusterIDs) > 1 {
+				p.clusterIDs = clusterIDs
+				if err := p.RunBatch(); err != nil {
+					return fmt.Errorf("error posting limited support reasons: %w", err)
+				}
+				return nil
+			}
+			if len(clusterIDs) != 1 {
+				return fmt.Errorf("accepts 1 arg(s), received %d", len(clusterIDs))
+			}
+			if p.interactive {
+				return errors.New("--interactive can only be used when posting to several clusters")
+			}
+			if p.summaryByOrg {
+				return errors.New("--summary-by-org can only be used when posting to several clusters")
+			}
+			if p.JobFile != "" || p.StateFile != "" {
+				return errors.New("--job-file and --state-file track a batch, they can only be used when posting to several clusters")
+			}
+			if p.trustedInput {
+				return errors.New("--trusted-input can only be used together with --clusters-jsonl or --cluster-ids-file")
+			}
+			if err := p.Run(clusterIDs[0]); err != nil {
+				if p.output == "json" {
+					reportError(p.output, clusterIDs[0], err)
+				}
 				return fmt.Errorf("error posting limited support reason: %w", err)
 			}
 			return nil
@@ -84,19 +419,73 @@ The cluster has a second failing ingress controller, which is not supported and
 	}
 
 	// Define required flags
-	postCmd.Flags().StringVarP(&p.Template, "template", "t", "", "Message template file or URL")
-	postCmd.Flags().StringArrayVarP(&p.TemplateParams, "param", "p", p.TemplateParams, "Specify a key-value pair (eg. -p FOO=BAR) to set/override a parameter value in the template.")
+	postCmd.Flags().StringVarP(&p.Template, "template", "t", "", "Message template file, URL or ConfigMap key (eg. configmap://namespace/name/key) in the current kubeconfig context. Templates are JSON, or YAML when the path ends in .yaml or .yml.")
+	postCmd.Flags().StringVarP(&p.Bundle, "bundle", "b", "", "YAML bundle file, URL or ConfigMap key containing the 'template' to post, instead of '-t', along with default 'params' and 'metadata'. '-p', --params-file and cluster entries override the defaults of the bundle.")
+	postCmd.Flags().StringArrayVarP(&p.TemplateParams, "param", "p", p.TemplateParams, "Specify a key-value pair (eg. -p FOO=BAR) to set/override a parameter value in the template. The SEVERITY parameter (eg. -p SEVERITY=critical) also sets the detection_type of the reason, according to '"+SeverityDetectionTypesKey+"' in the osdctl configuration file. A comma-separated value is also a list (eg. -p NODES=a,b,c): the template's '${NODES}' is replaced by the value as given, and '${NODES[]}' by the JSON array [\"a\",\"b\",\"c\"].")
+	postCmd.Flags().StringArrayVar(&p.ParamsFiles, "params-file", nil, "YAML or JSON file of template parameters (eg. 'FOO: BAR'), or TOML (.toml) or HCL (.hcl, .tfvars) variables file (eg. 'FOO = \"BAR\"'). Can be repeated, later files override earlier ones and '-p' overrides them all. Parameters the template doesn't use are ignored. Also accepted as --"+templateVarFileFlag+".")
 	postCmd.Flags().Var(&p.Misconfiguration, MisconfigurationFlag, "The type of misconfiguration responsible for the cluster being placed into limited support. Valid values are `cloud` or `cluster`.")
 	postCmd.Flags().StringVar(&p.Problem, ProblemFlag, "", "Complete sentence(s) describing the problem responsible for the cluster being placed into limited support. Will form the limited support message with the contents of --resolution appended")
 	postCmd.Flags().StringVar(&p.Resolution, ResolutionFlag, "", "Complete sentence(s) describing the steps for the customer to take to resolve the issue and move out of limited support. Will form the limited support message with the contents of --problem prepended")
 	postCmd.Flags().StringVar(&p.Evidence, EvidenceFlag, "", "(optional) The reasoning that led to the decision to place the cluster in limited support. Can also be a link to a Jira case. Used for internal service log only.")
+	postCmd.Flags().StringVar(&p.ClustersJSONL, "clusters-jsonl", "", `Post to every cluster listed in a JSON Lines file, one '{"cluster_id":"$CLUSTERID","params":{"FOO":"BAR"}}' object per line. Per-line params override '-p'. Requires '-t'.`)
+	postCmd.Flags().StringVar(&p.ClusterIDsFile, "cluster-ids-file", "", "Post to every cluster listed in a file, one 'CLUSTER_ID [KEY=VALUE ...]' line per cluster. Per-line parameters override '-p' for that cluster. Empty lines and lines starting with '#' are ignored. Requires '-t'.")
+	postCmd.Flags().StringVar(&p.IncidentID, "incident-id", "", "(optional) JIRA or incident ID (eg. OHSS-1234) to reference at the end of the limited support reason details.")
+	postCmd.Flags().StringArrayVar(&p.Links, "link", nil, "(optional) URL, eg. of a runbook, to append to the limited support reason details. Can be repeated.")
+	postCmd.Flags().StringVar(&p.MachinePool, "machine-pool", "", "(optional) Machine pool, or node pool for HCP clusters, the limited support reason is scoped to. Overrides the template's 'machine_pool' field. The customer sees it at the end of the details, as ' (Affected machine pool: NAME)'.")
+	postCmd.Flags().StringVar(&p.SubscriptionSearch, "subscription-search", "", "Post to the clusters of every OCM subscription matching the search query (eg. \"plan.id='OSD' and status='Active'\"). Requires '-t'.")
+	postCmd.Flags().StringVar(&p.SummaryFile, "summary-file", "", "(optional) File or URL whose contents replace the summary of the template given with '-t'. Parameters are substituted in the file contents.")
+	postCmd.Flags().StringVar(&p.DetailsFile, "details-file", "", "(optional) File or URL whose contents replace the details of the template given with '-t'. Parameters are substituted in the file contents.")
+	postCmd.Flags().StringVar(&p.RawBodyFile, "raw-body-file", "", "File or URL whose JSON content is posted verbatim as the limited support reason, without templating. Only its JSON syntax is checked.")
+	postCmd.Flags().StringVar(&p.Query, "query", "", "Post to the clusters selected by a subscription search saved under 'support_saved_queries' in the osdctl configuration file (eg. 'fedramp-prod'). Requires '-t'.")
+	postCmd.Flags().StringVar(&p.VersionRange, "version-range", "", "Post to every ready managed cluster whose OpenShift version is in the semver range (eg. \">=4.12.0 <4.13.0\"). The matching clusters are listed before posting. Requires '-t'.")
+	postCmd.Flags().IntVar(&p.maxResults, "max-results", 0, "(optional) Select at most this many clusters with --subscription-search, --query or --version-range, and warn when the search matches more. No cap by default.")
+	postCmd.Flags().StringVar(&p.ReasonFileGlob, "reason-file-glob", "", "Post every template matching the glob (eg. 'reasons/*.json'), rendered with the shared '-p' parameters. Cannot be used with '-t'.")
+	postCmd.Flags().BoolVarP(&p.isDryRun, "dry-run", "d", false, "Dry-run - print the limited support reason about to be sent but don't send it.")
+	postCmd.Flags().StringVar(&p.expectProvider, "expect-provider", "", "Refuse to post to the clusters which don't run on this cloud provider, 'aws' or 'gcp'. Overrides the 'provider' of the template. With --dry-run, the clusters are resolved in OCM to be checked.")
+	postCmd.Flags().BoolVar(&p.emitOCMCommands, "emit-ocm-commands", false, "With --dry-run, print for each cluster the 'ocm post' command sending the rendered reason, to run it manually. Requires access to OCM to resolve the internal ID of the clusters.")
+	postCmd.Flags().BoolVar(&p.checkCluster, "check-cluster", false, "When used with --dry-run, resolve each cluster in OCM and report the ones that cannot be found.")
+	postCmd.Flags().BoolVar(&p.validateSchema, "validate-schema", false, "When used with --dry-run, validate the fields of the rendered reason against the schema of OCM's OpenAPI specification, which is cached for a day. Falls back to the local validation when the schema is unavailable.")
+	postCmd.Flags().BoolVar(&p.summaryByOrg, "summary-by-org", false, "When used with --dry-run on several clusters, print how many of the targeted clusters each organization owns.")
+	postCmd.Flags().StringVar(&p.ExecFilter, "exec-filter", "", "(optional) Shell command the rendered limited support reason is piped through as JSON. Its output, which must be a valid limited support reason, is posted instead.")
+	postCmd.Flags().StringVar(&p.renderOnlyTo, "render-only-to", "", "Write the reason rendered for each cluster, with its per-cluster params, to DIR/<cluster ID>.json instead of posting it. Implies --dry-run.")
+	postCmd.Flags().StringVar(&p.deadline, "deadline", "", "(optional) Bound the whole run by a duration (eg. '2m30s') or an RFC3339 time. Once it passes, the clusters not processed yet are reported and skipped. The cluster being posted to at that time is finished, along with its service log.")
+	postCmd.Flags().StringVar(&p.JobFile, "job-file", "", "When posting to several clusters, save the clusters of the batch and the outcome of each of them to this file as the batch progresses. If the file exists, the batch it lists is resumed instead, skipping the clusters already posted to, unless another process is still running it. Follow it with 'osdctl cluster support job status'.")
+	postCmd.Flags().StringVar(&p.StateFile, "state-file", "", "When posting to several clusters, append every cluster which is done to this file. Running the same batch again with the same --state-file skips them, eg. after a crash, Ctrl-C or --deadline.")
+	postCmd.Flags().BoolVar(&p.async, "async", false, "Confirm the batch, then post to its clusters in a background process and return immediately. Requires --job-file, the output of the process is written to the job file with a '.log' suffix.")
+	postCmd.Flags().BoolVar(&p.failFast, "fail-fast", false, "When posting to several clusters, stop at the first failure instead of continuing with the remaining clusters.")
+	postCmd.Flags().BoolVar(&p.excludeDeleting, "exclude-deleting", false, "Skip the clusters which are uninstalling or scheduled for deletion, posting to them is wasteful. They are flagged in the preview of every batch, unless it is a dry-run which doesn't talk to OCM otherwise, and reported in the batch summary.")
+	postCmd.Flags().BoolVar(&p.onlyIfHealthy, "only-if-healthy", false, "Skip the clusters which already have any limited support reason, so that only clusters which are fully supported are posted to. Skipped clusters are reported in the batch summary.")
+	postCmd.Flags().IntVar(&p.retryBudget, "retry-budget", 0, "When posting to several clusters, the number of retries of transient OCM failures shared by the whole batch (eg. 20). The batch stops once they are used up, instead of retrying every cluster during an outage. By default only the retries of each cluster are bounded.")
+	postCmd.Flags().BoolVar(&p.showAlerts, "show-alerts", false, "Show the alerts currently firing on the cluster before asking for confirmation, for context. They are looked up through backplane like 'osdctl alert list', the post goes on with a warning when they cannot be. Only available when posting to a single cluster.")
+	postCmd.Flags().StringVar(&p.confirmTemplate, "confirm-message", "", "(optional) Confirmation question asked instead of 'Continue?'. Can use {{.ClusterID}}, {{.ClusterName}} and {{.Summary}}, eg. 'Place {{.ClusterName}} in limited support for {{.Summary}}?'. When posting to several clusters, {{.ClusterName}} describes the clusters and {{.ClusterID}} is empty.")
+	postCmd.Flags().StringVar(&p.saveIODir, "save-io", "", "(optional) Save the bodies sent to OCM and received from it for every post to DIR/<cluster ID>-request.json and DIR/<cluster ID>-response.json, and for the internal service log of --evidence to DIR/<cluster ID>-servicelog-request.json and DIR/<cluster ID>-servicelog-response.json, readable by the current user only.")
+	postCmd.Flags().BoolVar(&p.templateVersionFooter, "template-version-footer", false, "(optional) Append the '_template_version' declared by the template to the limited support reason details. The version is always recorded in the local history.")
+	postCmd.Flags().BoolVar(&p.trustedInput, "trusted-input", false, "SECURITY-RELEVANT: skip the safety check of the cluster keys read from --clusters-jsonl or --cluster-ids-file, which guards OCM queries against injection. Only for files generated by controlled automation. Quotes and backslashes are still rejected.")
+	postCmd.Flags().StringVar(&p.upsertReasonID, "upsert", "", "(optional) ID of a limited support reason posted earlier, eg. from '-o json' or the local history. It is updated when the cluster still has it, and a new reason is posted otherwise. OCM assigns the reason IDs, they cannot be chosen.")
+	postCmd.Flags().Int64Var(&p.maxTemplateBytes, "max-template-bytes", defaultMaxTemplateBytes, "Maximum size of the template, and of the other files and URLs read to build the reason, so that pointing '-t' at the wrong file fails instead of loading it into memory.")
+	postCmd.Flags().BoolVar(&p.thenList, "then-list", false, "After a successful post, list every limited support reason of the cluster as the status command does. With '-o json' or '-o yaml', the posted reason and the list are printed as a single document.")
+	postCmd.Flags().BoolVar(&p.interactive, "interactive", false, "When posting to several clusters, page through them and deselect the ones not to post to before confirming.")
+	postCmd.Flags().BoolVar(&p.force, "force", false, "Post to hibernating clusters, where the customer-facing effect of the reason is delayed until the cluster resumes.")
+	postCmd.Flags().BoolVar(&p.checkMaintenance, "check-maintenance", false, "Refuse to post to clusters in the maintenance window of an upgrade scheduled in OCM. This looks up the upgrade policies of every cluster posted to.")
+	postCmd.Flags().BoolVar(&p.ignoreMaintenance, "ignore-maintenance", false, "With --check-maintenance, only warn about the clusters in the maintenance window of an upgrade scheduled in OCM instead of failing. The window starts at the scheduled time of the upgrade and lasts --maintenance-window.")
+	postCmd.Flags().DurationVar(&p.maintenanceWindow, "maintenance-window", defaultMaintenanceWindow, "How long a scheduled upgrade is assumed to keep the cluster in maintenance after it starts (eg. 90m).")
+	postCmd.Flags().BoolVar(&p.explain, "explain", false, "When used with --dry-run, explain where the value of each field of the rendered reason comes from.")
+	postCmd.Flags().BoolVar(&p.verbose, "verbose", false, "Verbose output, report where every template parameter comes from and how it was substituted")
+	postCmd.Flags().BoolVar(&p.lint, "lint", false, "Warn when the rendered reason is inconsistent with its detection_type. Rules can be disabled with 'support_lint_disabled_rules' in the osdctl configuration file.")
+	postCmd.Flags().StringVar(&p.piiCheck, "pii-check", "", "Warn when the rendered details look like they contain emails, IP addresses or secrets. With --pii-check=strict, refuse to post them. Patterns can be added with 'support_pii_patterns' in the osdctl configuration file.")
+	postCmd.Flags().Lookup("pii-check").NoOptDefVal = piiCheckWarn
+	postCmd.Flags().BoolVar(&p.paramFromAWS, "param-from-aws", false, "Fill the ${AWS_ACCOUNT_ID} and ${AWS_REGION} template parameters by looking up the cluster's cloud account in OCM.")
+	postCmd.Flags().BoolVar(&p.paramFromLabels, "param-from-labels", false, "Fill the ${LABEL_<KEY>} template parameters from the OCM labels of the cluster's subscription, eg. the label 'my-team.owner' fills ${LABEL_MY_TEAM_OWNER}. '-p' takes precedence.")
+	postCmd.Flags().BoolVar(&p.allowUnresolved, "allow-unresolved", false, "Allow '${...}' sequences to remain in the rendered reason, for templates where they are literal content.")
+	postCmd.Flags().BoolVar(&strictParams, "strict-params", true, "Treat every '${...}' sequence of the template as a parameter. With --strict-params=false, only the parameters declared by the template or set with '-p' are substituted, other '${...}' sequences are kept verbatim.")
+	postCmd.Flags().BoolVar(&p.DescribeParams, "describe-params", false, "Print the parameters used by the template given with '-t' and exit without posting")
+	postCmd.Flags().SetNormalizeFunc(paramsFileAlias)
 	return postCmd
 }
 
 func (p *Post) Init() error {
-	userParameterNames = []string{}
-	userParameterValues = []string{}
-	return nil
+	p.templateBytes = nil
+	return p.loadBundle()
 }
 
 func (p *Post) setup() error {
@@ -119,7 +508,108 @@ func validateResolutionString(res string) error {
 }
 
 func (p *Post) check() error {
-	if p.Template != "" {
+	if p.maxTemplateBytes < 0 {
+		return errors.New("--max-template-bytes cannot be negative")
+	}
+	if p.maxResults < 0 {
+		return errors.New("--max-results cannot be negative")
+	}
+	if p.maintenanceWindow < 0 {
+		return errors.New("--maintenance-window cannot be negative")
+	}
+	if p.retryBudget < 0 {
+		return errors.New("--retry-budget cannot be negative")
+	}
+	if p.async && p.JobFile == "" {
+		return errors.New("--async requires --job-file, to follow and resume the job")
+	}
+	if p.StateFile != "" && p.JobFile != "" {
+		return errors.New("--state-file and --job-file cannot be used together, a job file already records the progress of its batch")
+	}
+	if p.showAlerts && p.isDryRun {
+		return errors.New("--show-alerts shows the alerts of the cluster when asking for confirmation, which --dry-run never does")
+	}
+	if p.confirmTemplate != "" {
+		if _, err := renderConfirmMessage(p.confirmTemplate, confirmData{}); err != nil {
+			return err
+		}
+	}
+	if p.checkCluster && !p.isDryRun {
+		return errors.New("--check-cluster can only be used together with --dry-run")
+	}
+	if p.validateSchema && !p.isDryRun {
+		return errors.New("--validate-schema can only be used together with --dry-run")
+	}
+	if p.summaryByOrg && !p.isDryRun {
+		return errors.New("--summary-by-org can only be used together with --dry-run")
+	}
+	if p.explain && !p.isDryRun {
+		return errors.New("--explain can only be used together with --dry-run")
+	}
+	if p.expectProvider != "" {
+		if err := checkCloudProviderName(p.expectProvider); err != nil {
+			return fmt.Errorf("invalid --expect-provider: %w", err)
+		}
+	}
+	if p.emitOCMCommands {
+		if !p.isDryRun {
+			return errors.New("--emit-ocm-commands can only be used together with --dry-run")
+		}
+		if p.upsertReasonID != "" || p.RawBodyFile != "" || p.renderOnlyTo != "" {
+			return errors.New("--emit-ocm-commands prints the command posting a new reason, it cannot be used with --upsert, --raw-body-file or --render-only-to")
+		}
+	}
+	for _, link := range p.Links {
+		if !utils.IsValidUrl(link) || !(strings.HasPrefix(link, "https://") || strings.HasPrefix(link, "http://")) || strings.ContainsAny(link, " ()") {
+			return fmt.Errorf("--link %q must be an http or https URL without spaces or parentheses", link)
+		}
+	}
+	if p.IncidentID != "" && !incidentIDRE.MatchString(p.IncidentID) {
+		return fmt.Errorf("--incident-id %q must contain only letters, digits, dots, dashes and underscores", p.IncidentID)
+	}
+	if p.Template != "" && p.ReasonFileGlob != "" {
+		return errors.New("--template and --reason-file-glob cannot be used together")
+	}
+	if p.piiCheck != "" && p.piiCheck != piiCheckWarn && p.piiCheck != piiCheckStrict {
+		return fmt.Errorf("--pii-check must be %q or %q", piiCheckWarn, piiCheckStrict)
+	}
+	if p.upsertReasonID != "" && (p.ReasonFileGlob != "" || p.RawBodyFile != "") {
+		return errors.New("--upsert updates a single reason, it cannot be used with --reason-file-glob or --raw-body-file")
+	}
+	if p.renderOnlyTo != "" && (p.ReasonFileGlob != "" || p.RawBodyFile != "") {
+		return errors.New("--render-only-to renders a single template per cluster, it cannot be used with --reason-file-glob or --raw-body-file")
+	}
+	if (p.SummaryFile != "" || p.DetailsFile != "") && p.Template == "" {
+		return errors.New("--summary-file and --details-file override fields of the template, they require '-t'")
+	}
+	if p.RawBodyFile != "" {
+		// The raw body is sent as is, none of the flags shaping the reason apply
+		if p.Template != "" || p.ReasonFileGlob != "" || len(p.TemplateParams) > 0 || p.Problem != "" || p.Resolution != "" ||
+			p.Misconfiguration != "" || p.Evidence != "" || p.IncidentID != "" || p.MachinePool != "" || p.ExecFilter != "" || p.paramFromAWS || p.paramFromLabels || p.explain || len(p.Links) > 0 {
+			return errors.New("--raw-body-file cannot be used together with the flags building the limited support reason")
+		}
+		return nil
+	}
+	if p.VersionRange != "" {
+		if _, err := semver.ParseRange(p.VersionRange); err != nil {
+			return fmt.Errorf("invalid --version-range %q: %w", p.VersionRange, err)
+		}
+	}
+	if p.paramFromAWS {
+		if p.Template == "" && p.ReasonFileGlob == "" {
+			return errors.New("--param-from-aws can only be used together with a template")
+		}
+		for _, v := range p.TemplateParams {
+			name, _, _ := strings.Cut(v, "=")
+			if _, ok := awsParameterNames[name]; ok {
+				return fmt.Errorf("'-p %s' cannot be used together with --param-from-aws", name)
+			}
+		}
+	}
+	if p.paramFromLabels && p.Template == "" && p.ReasonFileGlob == "" {
+		return errors.New("--param-from-labels can only be used together with a template")
+	}
+	if p.Template != "" || p.ReasonFileGlob != "" {
 		if p.Problem != "" || p.Resolution != "" || p.Misconfiguration != "" || p.Evidence != "" {
 			return fmt.Errorf("\nIf Template flag is present, --problem, --resolution, --misconfiguration and --evidence flags cannot be used")
 		}
@@ -138,91 +628,438 @@ func (p *Post) check() error {
 }
 
 func (p *Post) Run(clusterID string) error {
-	if err := p.Init(); err != nil {
+	result, err := p.Post(clusterID)
+	if err != nil || result == nil {
 		return err
 	}
+	if p.thenList {
+		return p.printResultAndReasons(result)
+	}
+	return p.printResult(result)
+}
 
-	if err := p.check(); err != nil {
+// printResultAndReasons prints the posted reason followed by every limited support reason of the cluster,
+// as listed by the status command, so that the effect of a post can be checked against the reasons the
+// cluster already had. With '-o json' or '-o yaml', both are printed as a single document.
+func (p *Post) printResultAndReasons(result *PostResult) error {
+	table, err := reasonColumns.pick(nil, "id", "summary", "details")
+	if err != nil {
 		return err
 	}
+	status := &statusOptions{output: p.output, table: table}
+	clusterID, reasons, err := status.clusterReasons(result.ClusterID)
+	if err != nil {
+		return fmt.Errorf("reason posted, but the limited support reasons of the cluster cannot be listed: %w", err)
+	}
+	if !isDocumentOutput(p.output) {
+		if err := p.printResult(result); err != nil {
+			return err
+		}
+		return status.printReasons(clusterID, reasons)
+	}
+	output := postThenListOutput{
+		postOutput: postOutput{ClusterID: result.ClusterID, ReasonID: result.ReasonID, CreatedAt: result.CreatedAt},
+		Reasons:    newReasonsOutput(clusterID, reasons).Reasons,
+	}
+	if err := getoutput.PrintResponse(p.output, output); err != nil {
+		return fmt.Errorf("failed to print the posted limited support reason: %w", err)
+	}
+	return nil
+}
+
+// postThenListOutput is the posted reason along with the limited support reasons of the cluster, printed
+// with --then-list and '-o json' or '-o yaml'
+type postThenListOutput struct {
+	postOutput `yaml:",inline"`
+	Reasons    []reasonOutput `json:"limited_support_reasons" yaml:"limited_support_reasons"`
+}
+
+// Post renders and posts the limited support reason to the cluster, without printing the outcome.
+// The returned result is nil when nothing was sent, eg. for a dry-run or with --reason-file-glob,
+// which reports each template on its own.
+func (p *Post) Post(clusterID string) (*PostResult, error) {
+	if err := p.Init(); err != nil {
+		return nil, err
+	}
+
+	if err := p.check(); err != nil {
+		return nil, err
+	}
 
 	// Check that the cluster key (name, identifier or external identifier) given by the user
 	// is reasonably safe so that there is no risk of SQL injection
 	if err := ctlutil.IsValidClusterKey(clusterID); err != nil {
-		return err
+		return nil, err
+	}
+
+	// A plain dry-run only renders the template, there is no need to talk to OCM
+	var connection *sdk.Connection
+	if !p.isDryRun || p.checkCluster || p.paramFromAWS || p.paramFromLabels || p.validateSchema || p.onlyIfHealthy || p.excludeDeleting || p.emitOCMCommands || p.expectProvider != "" {
+		var err error
+		connection, err = createConnection()
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err = connection.Close(); err != nil {
+				logger().Error("Cannot close the connection", "error", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	if p.ReasonFileGlob != "" {
+		return nil, p.postReasonFiles(connection, clusterID)
+	}
+	var result *PostResult
+	var err error
+	if p.RawBodyFile != "" {
+		result, err = p.postRawBody(connection, clusterID)
+	} else {
+		result, err = p.postToCluster(connection, clusterID, nil, true)
+	}
+	if skippedCluster(err) {
+		logger().Info("Skipped the cluster", "cluster", clusterID, "reason", err)
+		return nil, nil
+	}
+	return result, err
+}
+
+// postReasonFiles posts every template matching --reason-file-glob to the cluster, one after the other,
+// and reports the outcome of each file
+func (p *Post) postReasonFiles(connection *sdk.Connection, clusterID string) error {
+	files, err := filepath.Glob(p.ReasonFileGlob)
+	if err != nil {
+		return fmt.Errorf("invalid --reason-file-glob %q: %w", p.ReasonFileGlob, err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no template matches %q", p.ReasonFileGlob)
 	}
 
-	connection, err := ctlutil.CreateConnection()
+	// A parameter only has to be used by one of the templates
+	p.ignoreUnusedParams = true
+	names, _, err := p.parseUserParameters(nil)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if err = connection.Close(); err != nil {
-			fmt.Printf("Cannot close the connection: %q\n", err)
-			os.Exit(1)
+	// The parameters are checked before anything is posted
+	used := map[string]bool{}
+	for _, file := range files {
+		p.Template = file
+		p.templateBytes = nil
+		t, err := p.readTemplate()
+		if err != nil {
+			continue
 		}
-	}()
+		details, err := p.renderedDetails(t)
+		if err != nil {
+			return err
+		}
+		summary, err := p.renderedSummary()
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if usesParam(details, name) || usesParam(summary, name) {
+				used[name] = true
+			}
+		}
+	}
+	for _, name := range names {
+		// Parameters files are shared defaults, only '-p' has to be used
+		if !used[name] && !strings.HasPrefix(p.paramSources[name], "--params-file") && name != severityPlaceholder {
+			return fmt.Errorf("none of the templates is using '%s' parameter, but '--param' flag was set", name)
+		}
+	}
 
-	p.cluster, err = ctlutil.GetCluster(connection, clusterID)
+	results := map[string]string{}
+	var failed int
+	// The cluster is resolved and checked once, the templates posted to it put it in limited support
+	if connection != nil {
+		if err := p.prepareCluster(connection, clusterID); err != nil {
+			if !skippedCluster(err) {
+				return err
+			}
+			logger().Info("Skipped the cluster", "cluster", clusterID, "reason", err)
+			for _, file := range files {
+				results[file] = fmt.Sprintf("Skipped: %v", err)
+			}
+			return printTemplateResults(p.previewOut(), files, results)
+		}
+		p.clusterPrepared = true
+		defer func() { p.clusterPrepared = false }()
+	}
+	// Each template is confirmed on its own, the impact of all of them is summarized first
+	if !p.isDryRun {
+		if err := p.printImpactSummary(len(files), 1, fmt.Sprintf("cluster %s by the %d template(s)", clusterID, len(files))); err != nil {
+			return err
+		}
+	}
+	for _, file := range files {
+		p.Template = file
+		p.templateBytes = nil
+
+		fmt.Fprintf(p.previewOut(), "Template %s:\n", file)
+		result, err := p.postToCluster(connection, clusterID, nil, true)
+		if err == nil && result != nil {
+			err = p.printResult(result)
+		}
+		switch {
+		case skippedCluster(err):
+			results[file] = fmt.Sprintf("Skipped: %v", err)
+		case err != nil:
+			failed++
+			results[file] = fmt.Sprintf("Failed: %v", err)
+		case result == nil:
+			results[file] = "Not sent"
+		default:
+			results[file] = fmt.Sprintf("Sent with ID %s", result.ReasonID)
+		}
+	}
+
+	if err := printTemplateResults(p.previewOut(), files, results); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to post %d of %d template(s)", failed, len(files))
+	}
+	return nil
+}
+
+// prepareCluster resolves the cluster to post to, checks that it can be posted to and looks up the parameters
+// read from OCM
+func (p *Post) prepareCluster(connection *sdk.Connection, clusterID string) error {
+	if err := checkClusterKey(clusterID, p.trustedInput); err != nil {
+		return err
+	}
+	var err error
+	p.cluster, err = resolveCluster(connection, clusterID, p.retries)
 	if err != nil {
 		return fmt.Errorf("can't retrieve cluster: %w", err)
 	}
+	if err := p.checkHibernation(p.cluster); err != nil {
+		return err
+	}
+	if err := p.checkDeletion(p.cluster); err != nil {
+		return err
+	}
+	if err := p.checkMaintenanceWindow(connection, p.cluster); err != nil {
+		return err
+	}
+	if err := p.checkHealthy(connection, p.cluster); err != nil {
+		return err
+	}
+	if err := p.checkProvider(p.cluster); err != nil {
+		return err
+	}
+	if p.paramFromAWS {
+		err = retryOCM(p.retries, func() error {
+			var err error
+			p.awsParams, err = awsTemplateParameters(connection, p.cluster)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if p.paramFromLabels {
+		err = retryOCM(p.retries, func() error {
+			var err error
+			p.labelParams, err = labelTemplateParameters(connection, p.cluster)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderedDetails returns the details the parameters are substituted in: those of --details-file when set,
+// which replace the template's
+func (p *Post) renderedDetails(t *TemplateFile) (string, error) {
+	if p.DetailsFile != "" {
+		return p.readFieldFile(p.DetailsFile)
+	}
+	return t.Details, nil
+}
+
+// renderedSummary returns the contents of --summary-file, which the parameters are substituted in like the
+// details. The summary of a template is posted verbatim.
+func (p *Post) renderedSummary() (string, error) {
+	if p.SummaryFile != "" {
+		return p.readFieldFile(p.SummaryFile)
+	}
+	return "", nil
+}
+
+// printTemplateResults prints the result of posting each template of --reason-file-glob
+func printTemplateResults(out io.Writer, files []string, results map[string]string) error {
+	table := printer.NewTablePrinter(out, 20, 1, 3, ' ')
+	table.AddRow([]string{"Template", "Result"})
+	for _, file := range files {
+		table.AddRow([]string{file, results[file]})
+	}
+	// Add empty row for readability
+	table.AddRow([]string{})
+	return table.Flush()
+}
+
+// postToCluster renders the limited support reason for the given cluster, using clusterParams on top of
+// the '-p' parameters, and posts it along with the internal service log, if any.
+// When prompt is true the caller is asked to confirm before anything is sent.
+// A nil connection is only valid for a dry-run, in which case the cluster is not resolved.
+// The returned result describes the reason created by OCM, it is nil when nothing was sent.
+func (p *Post) postToCluster(connection *sdk.Connection, clusterID string, clusterParams map[string]string, prompt bool) (*PostResult, error) {
+	var err error
+	if connection != nil && !p.clusterPrepared {
+		if err := p.prepareCluster(connection, clusterID); err != nil {
+			return nil, err
+		}
+	}
 	var limitedSupport *cmv1.LimitedSupportReason
 	if p.Template != "" {
-		limitedSupport, err = p.buildLimitedSupportTemplate()
+		limitedSupport, err = p.buildLimitedSupportTemplate(clusterParams)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	} else {
 		limitedSupport, err = p.buildLimitedSupport()
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	fmt.Printf("The following limited support reason will be sent to %s:\n", clusterID)
-	if err = printLimitedSupportReason(limitedSupport); err != nil {
-		return fmt.Errorf("failed to print limited support reason template: %w", err)
+	// The machine pool can only be checked against the cluster when it was resolved
+	if p.scopedMachinePool != "" && connection != nil {
+		if err := retryOCM(p.retries, func() error { return validateMachinePool(connection, p.cluster, p.scopedMachinePool) }); err != nil {
+			return nil, err
+		}
 	}
 
-	if !ctlutil.ConfirmPrompt() {
-		return nil
+	if p.ExecFilter != "" {
+		p.explainf("all fields: may have been changed by --exec-filter %q", p.ExecFilter)
+		limitedSupport, err = p.execFilter(limitedSupport)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.checkUnresolved(limitedSupport); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.checkAllowlist(limitedSupport); err != nil {
+		return nil, err
+	}
+
+	if p.lint {
+		lintLimitedSupportReason(os.Stderr, limitedSupport)
+	}
+
+	if p.piiCheck != "" {
+		if err := checkPII(os.Stderr, p.piiCheck, limitedSupport); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.renderOnlyTo != "" {
+		return nil, p.writeRenderedReason(clusterID, limitedSupport)
+	}
+
+	fmt.Fprintf(p.previewOut(), "The following limited support reason will be sent to %s (customer facing):\n", clusterID)
+	if err = printLimitedSupportReason(p.previewOut(), limitedSupport); err != nil {
+		return nil, fmt.Errorf("failed to print limited support reason template: %w", err)
+	}
+
+	if p.explain {
+		fmt.Fprintln(p.previewOut(), "Explanation:")
+		for _, line := range p.explanation {
+			fmt.Fprintf(p.previewOut(), "  - %s\n", line)
+		}
+	}
+
+	// If this is a dry-run, preview the internal service log too and don't proceed further.
+	if p.isDryRun {
+		if p.validateSchema {
+			if err := p.validateAgainstSchema(connection, marshalReason(limitedSupport)); err != nil {
+				return nil, err
+			}
+		}
+		printImpact(p.previewOut(), limitedSupport, p.scopedMachinePool)
+		// The command needs the internal ID of the cluster, which is only known once it was resolved
+		if p.emitOCMCommands && p.cluster != nil {
+			if err := printOCMCommand(p.previewOut(), p.cluster.ID(), limitedSupport); err != nil {
+				return nil, err
+			}
+		}
+		return nil, p.previewInternalServiceLog(clusterID)
+	}
+
+	if prompt {
+		printHistoryDiff(p.previewOut(), p.cluster.ID(), limitedSupport)
+		if err := p.printImpactSummary(1, 1, "cluster "+p.cluster.ID()); err != nil {
+			return nil, err
+		}
+		if ok, err := p.confirmPost(confirmData{ClusterID: p.cluster.ID(), ClusterName: p.cluster.Name(), Summary: limitedSupport.Summary()}); !ok {
+			return nil, err
+		}
 	}
 
-	postLimitedSupportResponse, err := sendLimitedSupportPostRequest(connection, p.cluster.ID(), limitedSupport)
+	var result *PostResult
+	if p.upsertReasonID != "" {
+		result, err = p.upsertReason(connection, limitedSupport)
+	} else {
+		result, err = p.postNewReason(connection, limitedSupport)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to post limited support reason: %w", err)
+		return nil, err
 	}
-	fmt.Printf("Successfully added new limited support reason with ID %v\n", postLimitedSupportResponse.Body().ID())
 
-	if p.Evidence != "" {
+	// An idempotent re-run changes nothing, its evidence was sent along with the reason the first time
+	if p.Evidence != "" && result.Unchanged {
+		logger().Info("Not sending the internal service log, the reason was unchanged", "cluster", clusterID, "reason_id", result.ReasonID)
+	} else if p.Evidence != "" {
 		var subscriptionId string
 		if subscription, ok := p.cluster.GetSubscription(); ok {
 			subscriptionId = subscription.ID()
 		}
-		log, err := p.buildInternalServiceLog(postLimitedSupportResponse.Body().ID(), subscriptionId)
+		log, err := p.buildInternalServiceLog(result.ReasonID, subscriptionId)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		fmt.Printf("Sending the following internal service log to %s:\n", clusterID)
-		if err = printInternalServiceLog(log); err != nil {
-			return fmt.Errorf("failed to print internal service log template: %w", err)
+		fmt.Fprintf(p.previewOut(), "Sending the following internal service log to %s:\n", clusterID)
+		if err = printInternalServiceLog(p.previewOut(), log); err != nil {
+			return nil, fmt.Errorf("failed to print internal service log template: %w", err)
 		}
 
-		postServiceLogResponse, err := sendInternalServiceLogPostRequest(connection, log)
+		var postServiceLogResponse *slv1.ClusterLogsAddResponse
+		ctx, capture := ctlutil.WithBodyCapture(context.Background())
+		err = retryOCMCreate(p.retries, func() error {
+			var err error
+			postServiceLogResponse, err = sendInternalServiceLogPostRequest(ctx, connection, log)
+			return err
+		})
+		request, received := capturedBodies(capture, marshalServiceLog(log))
+		p.saveIO(p.cluster.ID()+serviceLogIOSuffix, request, received, err)
 		if err != nil {
-			return fmt.Errorf("failed to post internal service log: %w", err)
+			return nil, fmt.Errorf("failed to post internal service log: %w", err)
 		}
-		fmt.Printf("Successfully sent internal service log with ID %v\n", postServiceLogResponse.Body().ID())
+		logger().Info("Sent the internal service log", "cluster", clusterID, "service_log_id", postServiceLogResponse.Body().ID())
 	}
 
-	return nil
+	return result, nil
 }
 
 func (p *Post) buildLimitedSupport() (*cmv1.LimitedSupportReason, error) {
+	p.explanation = nil
+	p.templateVersion = ""
+	p.explainf("summary: picked by --misconfiguration %s", p.Misconfiguration)
+	p.explainf("details: --problem followed by --resolution")
+	p.explainf("detection_type: always %s without a template", cmv1.DetectionTypeManual)
+	p.scopedMachinePool = p.MachinePool
 	limitedSupportBuilder := cmv1.NewLimitedSupportReason().
-		Details(fmt.Sprintf("%s %s", p.Problem, p.Resolution)).
+		Details(p.withLinks(p.withIncidentReference(p.withMachinePoolScope(fmt.Sprintf("%s %s", p.Problem, p.Resolution))))).
 		DetectionType(cmv1.DetectionTypeManual)
 	switch p.Misconfiguration {
 	case cloud:
@@ -238,77 +1075,491 @@ func (p *Post) buildLimitedSupport() (*cmv1.LimitedSupportReason, error) {
 	return limitedSupport, nil
 }
 
-func (p *Post) buildLimitedSupportTemplate() (*cmv1.LimitedSupportReason, error) {
-	t := p.readTemplate() // parse the given JSON template provided via '-t' flag
+func (p *Post) buildLimitedSupportTemplate(clusterParams map[string]string) (*cmv1.LimitedSupportReason, error) {
+	t, err := p.readTemplate() // parse the given JSON template provided via '-t' flag
+	if err != nil {
+		return nil, err
+	}
 
-	p.parseUserParameters() // parse all the '-p' user flags
-	// For every '-p' flag, replace its related placeholder in the template
-	for k := range userParameterNames {
-		p.replaceFlags(t, userParameterNames[k], userParameterValues[k])
+	p.explanation = nil
+	summarySource, detailsSource := "template "+p.Template, "template "+p.Template
+	if p.SummaryFile != "" {
+		if t.Summary, err = p.readFieldFile(p.SummaryFile); err != nil {
+			return nil, err
+		}
+		summarySource = "--summary-file " + p.SummaryFile
+	}
+	if p.DetailsFile != "" {
+		if t.Details, err = p.readFieldFile(p.DetailsFile); err != nil {
+			return nil, err
+		}
+		detailsSource = "--details-file " + p.DetailsFile
+	}
+	// The parameters are substituted in the summary of --summary-file, like in the details
+	substituteSummary := p.SummaryFile != ""
+	summarySubstituted := false
+	if !substituteSummary {
+		p.explainf("summary: verbatim from %s", summarySource)
 	}
-	p.checkLeftovers(t)
 
-	limitedSupportBuilder := cmv1.NewLimitedSupportReason().Summary(t.Summary).Details(t.Details).DetectionType(t.Detection_type)
+	names, values, err := p.parseUserParameters(clusterParams) // parse all the '-p' user flags
+	if err != nil {
+		return nil, err
+	}
+	if i := slices.Index(names, severityPlaceholder); i >= 0 {
+		if t.Detection_type, err = severityDetectionType(values[i]); err != nil {
+			return nil, err
+		}
+		p.explainf("detection_type: %s mapped from %s %q from %s", t.Detection_type, severityPlaceholder, values[i], p.paramSources[severityPlaceholder])
+	} else {
+		p.explainf("detection_type: verbatim from template %s", p.Template)
+	}
+	substituted := false
+	// For every parameter, replace its related placeholder in the template
+	for k := range names {
+		source := p.paramSources[names[k]]
+		if substituteSummary && values[k] != "" && usesParam(t.Summary, names[k]) {
+			if t.Summary, err = substituteParam(t.Summary, names[k], values[k]); err != nil {
+				return nil, err
+			}
+			p.explainf("summary: %s replaced by %q from %s", names[k], values[k], source)
+			summarySubstituted = true
+			// The parameter was used, it doesn't have to be in the details as well
+			if !usesParam(t.Details, names[k]) {
+				continue
+			}
+		}
+		// The severity selects the detection_type, templates don't have to use it in their details
+		if names[k] == severityPlaceholder && !usesParam(t.Details, names[k]) {
+			continue
+		}
+		if p.verbose {
+			reportSubstitution(os.Stderr, t.Details, names[k], values[k], source)
+		}
+		// Parameters files and bundles are shared defaults, templates don't have to use all their parameters
+		fromFileOnly := strings.HasPrefix(source, "--params-file") || source == p.bundleSource()
+		if (p.ignoreUnusedParams || fromFileOnly) && !usesParam(t.Details, names[k]) {
+			continue
+		}
+		p.explainf("details: %s replaced by %q from %s", names[k], values[k], source)
+		substituted = true
+		if err := p.replaceFlags(t, names[k], values[k]); err != nil {
+			return nil, err
+		}
+	}
+	// Templates which don't use the placeholders looked up in OCM are left untouched. They are substituted
+	// after '-p', which takes precedence.
+	for _, lookup := range []struct {
+		source string
+		params map[string]string
+	}{{"--param-from-aws", p.awsParams}, {"--param-from-labels", p.labelParams}} {
+		for name, value := range lookup.params {
+			placeholder := fmt.Sprintf("${%v}", name)
+			if substituteSummary && usesParam(t.Summary, placeholder) {
+				if t.Summary, err = substituteParam(t.Summary, placeholder, value); err != nil {
+					return nil, err
+				}
+				p.explainf("summary: %s replaced by %q from %s", placeholder, value, lookup.source)
+				summarySubstituted = true
+			}
+			if p.verbose {
+				reportSubstitution(os.Stderr, t.Details, placeholder, value, lookup.source)
+			}
+			if usesParam(t.Details, placeholder) {
+				p.explainf("details: %s replaced by %q from %s", placeholder, value, lookup.source)
+				substituted = true
+			}
+			if t.Details, err = substituteParam(t.Details, placeholder, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, leftover := range p.findLeftovers(t.Details) {
+		if param, declared := t.parameter(leftover); declared && !param.Required {
+			p.explainf("details: optional %s removed as it was not set", leftover)
+		} else if p.allowUnresolved {
+			p.explainf("details: %s kept verbatim because of --allow-unresolved", leftover)
+		} else if p.lenientParams && !declared {
+			p.explainf("details: %s kept verbatim because of --strict-params=false", leftover)
+		}
+		substituted = true
+	}
+	if substituteSummary && !summarySubstituted {
+		p.explainf("summary: verbatim from %s", summarySource)
+	}
+	if !substituted {
+		p.explainf("details: verbatim from %s", detailsSource)
+	}
+	if err := p.checkLeftovers(t); err != nil {
+		return nil, err
+	}
+
+	p.scopedMachinePool = p.MachinePool
+	if p.scopedMachinePool == "" {
+		p.scopedMachinePool = t.MachinePool
+	}
+	p.templateVersion = t.TemplateVersion
+	limitedSupportBuilder := cmv1.NewLimitedSupportReason().Summary(t.Summary).Details(p.withTemplateVersion(p.withLinks(p.withIncidentReference(p.withMachinePoolScope(t.Details))))).DetectionType(t.Detection_type)
 	limitedSupport, err := limitedSupportBuilder.Build()
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to build new limited support reason: %w", err)
 	}
+	if err := p.checkUnresolved(limitedSupport); err != nil {
+		return nil, err
+	}
 	return limitedSupport, nil
 }
 
-// parseUserParameters parse all the '-p FOO=BAR' parameters and checks for syntax errors
-func (p *Post) parseUserParameters() {
+// checkUnresolved scans the fully rendered reason, as it will be sent to OCM, for placeholders which
+// were not substituted, unless --allow-unresolved was given
+func (p *Post) checkUnresolved(limitedSupport *cmv1.LimitedSupportReason) error {
+	if p.allowUnresolved {
+		return nil
+	}
+
+	buf := bytes.Buffer{}
+	if err := cmv1.MarshalLimitedSupportReason(limitedSupport, &buf); err != nil {
+		return fmt.Errorf("failed to marshal limited support reason: %w", err)
+	}
+	unresolved := p.findLeftovers(buf.String())
+	if p.lenientParams && p.Template != "" {
+		t, err := p.readTemplate()
+		if err != nil {
+			return err
+		}
+		unresolved = slices.DeleteFunc(unresolved, func(placeholder string) bool {
+			_, declared := t.parameter(placeholder)
+			return !declared
+		})
+	}
+	if len(unresolved) == 0 {
+		return nil
+	}
+	slices.Sort(unresolved)
+	return fmt.Errorf("the rendered limited support reason still contains unresolved placeholders: %s. Set them with '-p' or use '--allow-unresolved' if they are literal content", strings.Join(slices.Compact(unresolved), ", "))
+}
+
+// withMachinePoolScope appends the machine pool the reason is scoped to to the details, if any.
+// OCM has no field to scope a limited support reason, so the scope is part of the customer facing text:
+// the suffix is worded for the customer, who knows the pool by the name shown in OCM.
+func (p *Post) withMachinePoolScope(details string) string {
+	if p.scopedMachinePool == "" {
+		return details
+	}
+	if p.MachinePool != "" {
+		p.explainf("details: machine pool %s appended from --machine-pool", p.scopedMachinePool)
+	} else {
+		p.explainf("details: machine pool %s appended from the template's machine_pool", p.scopedMachinePool)
+	}
+	return fmt.Sprintf("%s (Affected machine pool: %s)", details, p.scopedMachinePool)
+}
+
+// validateMachinePool checks that the machine pool, or node pool for HCP clusters, exists on the cluster
+func validateMachinePool(connection *sdk.Connection, cluster *cmv1.Cluster, name string) error {
+	clusterClient := connection.ClustersMgmt().V1().Clusters().Cluster(cluster.ID())
+	var pools []string
+	if cluster.Hypershift().Enabled() {
+		response, err := clusterClient.NodePools().List().Send()
+		if err != nil {
+			return fmt.Errorf("can't retrieve the node pools of the cluster: %w", err)
+		}
+		for _, pool := range response.Items().Slice() {
+			pools = append(pools, pool.ID())
+		}
+	} else {
+		response, err := clusterClient.MachinePools().List().Send()
+		if err != nil {
+			return fmt.Errorf("can't retrieve the machine pools of the cluster: %w", err)
+		}
+		for _, pool := range response.Items().Slice() {
+			pools = append(pools, pool.ID())
+		}
+	}
+
+	if !slices.Contains(pools, name) {
+		return fmt.Errorf("machine pool %q does not exist on cluster %s, valid pools are: %s", name, cluster.ID(), strings.Join(pools, ", "))
+	}
+	return nil
+}
+
+// hibernationStates are the states of a cluster which is, or is about to be, hibernating
+var hibernationStates = map[cmv1.ClusterState]struct{}{
+	cmv1.ClusterStateHibernating:  {},
+	cmv1.ClusterStatePoweringDown: {},
+	cmv1.ClusterStateResuming:     {},
+}
+
+// checkHibernation warns about hibernating clusters, as the customer-facing effect of the reason may be
+// delayed until they resume. Posting to them requires --force, except for a dry-run.
+func (p *Post) checkHibernation(cluster *cmv1.Cluster) error {
+	if _, ok := hibernationStates[cluster.State()]; !ok {
+		return nil
+	}
+
+	logger().Warn("The limited support reason may not take effect until the cluster resumes", "cluster", cluster.ID(), "state", cluster.State())
+	if p.isDryRun || p.force {
+		return nil
+	}
+	return fmt.Errorf("cluster %s is %s, use --force to post anyway", cluster.ID(), cluster.State())
+}
+
+// awsParameterNames are the template parameters filled by --param-from-aws
+var awsParameterNames = map[string]struct{}{
+	"AWS_ACCOUNT_ID": {},
+	"AWS_REGION":     {},
+}
+
+// awsTemplateParameters looks up the cloud account of the cluster to fill the placeholders of awsParameterNames
+func awsTemplateParameters(connection *sdk.Connection, cluster *cmv1.Cluster) (map[string]string, error) {
+	if cluster.CloudProvider().ID() != "aws" {
+		return nil, fmt.Errorf("--param-from-aws can only be used with AWS clusters, cluster %s is running on '%s'", cluster.ID(), cluster.CloudProvider().ID())
+	}
+
+	accountID, err := ctlutil.GetAWSAccountIdForCluster(connection, cluster.ID())
+	if err != nil {
+		return nil, fmt.Errorf("can't look up the AWS account of cluster %s: %w", cluster.ID(), err)
+	}
+
+	return map[string]string{
+		"AWS_ACCOUNT_ID": accountID,
+		"AWS_REGION":     cluster.Region().ID(),
+	}, nil
+}
+
+// labelTemplateParameters returns the ${LABEL_<KEY>} placeholder values of the OCM labels of the cluster's subscription
+func labelTemplateParameters(connection *sdk.Connection, cluster *cmv1.Cluster) (map[string]string, error) {
+	subscription, ok := cluster.GetSubscription()
+	if !ok || subscription.ID() == "" {
+		return nil, fmt.Errorf("cluster %s has no subscription to read labels from", cluster.ID())
+	}
+	response, err := connection.AccountsMgmt().V1().Subscriptions().Subscription(subscription.ID()).Labels().List().Send()
+	if err != nil {
+		return nil, fmt.Errorf("can't list the labels of cluster %s: %w", cluster.ID(), err)
+	}
+	labels := map[string]string{}
+	for _, label := range response.Items().Slice() {
+		labels[label.Key()] = label.Value()
+	}
+	return labelParameters(labels), nil
+}
+
+// labelParameterRE matches the characters of label keys which cannot be part of a parameter name
+var labelParameterRE = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// labelParameters maps label keys to parameter names, eg. 'my-team.owner' to 'LABEL_MY_TEAM_OWNER'.
+// When several keys map to the same name, the first key in lexical order is used.
+func labelParameters(labels map[string]string) map[string]string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	params := map[string]string{}
+	for _, key := range keys {
+		name := "LABEL_" + labelParameterRE.ReplaceAllString(strings.ToUpper(key), "_")
+		if _, ok := params[name]; ok {
+			logger().Warn("Several labels fill the same parameter, ignoring one of them", "parameter", name, "ignored_label", key)
+			continue
+		}
+		params[name] = labels[key]
+	}
+	return params
+}
+
+// withIncidentReference appends the standard reference to --incident-id to the details, if set
+func (p *Post) withIncidentReference(details string) string {
+	if p.IncidentID == "" {
+		return details
+	}
+	p.explainf("details: reference %s appended from --incident-id", p.IncidentID)
+	return fmt.Sprintf("%s (Reference: %s)", details, p.IncidentID)
+}
+
+// withLinks appends the --link URLs to the details, in a standard format tooling can parse back
+func (p *Post) withLinks(details string) string {
+	if len(p.Links) == 0 {
+		return details
+	}
+	p.explainf("details: links %s appended from --link", strings.Join(p.Links, ", "))
+	return fmt.Sprintf("%s (Links: %s)", details, strings.Join(p.Links, " "))
+}
+
+// withTemplateVersion appends the '_template_version' of the template to the details with --template-version-footer
+func (p *Post) withTemplateVersion(details string) string {
+	if !p.templateVersionFooter || p.templateVersion == "" {
+		return details
+	}
+	p.explainf("details: template version %s appended from '_template_version' because of --template-version-footer", p.templateVersion)
+	return fmt.Sprintf("%s (Template version: %s)", details, p.templateVersion)
+}
+
+// parseUserParameters parse all the '-p FOO=BAR' parameters and checks for syntax errors.
+// Values from clusterParams take precedence over the '-p' flags with the same name.
+func (p *Post) parseUserParameters(clusterParams map[string]string) (names []string, values []string, err error) {
+	if err := p.loadParamsFiles(); err != nil {
+		return nil, nil, err
+	}
+	p.paramSources = map[string]string{}
+
 	for _, v := range p.TemplateParams {
 		if !strings.Contains(v, "=") {
-			log.Fatalf("Wrong syntax of '-p' flag. Please use it like this: '-p FOO=BAR'")
+			return nil, nil, errors.New("wrong syntax of '-p' flag. Please use it like this: '-p FOO=BAR'")
 		}
 
 		param := strings.SplitN(v, "=", 2)
 		if param[0] == "" || param[1] == "" {
-			log.Fatalf("Wrong syntax of '-p' flag. Please use it like this: '-p FOO=BAR'")
+			return nil, nil, errors.New("wrong syntax of '-p' flag. Please use it like this: '-p FOO=BAR'")
+		}
+
+		names = append(names, fmt.Sprintf("${%v}", param[0]))
+		values = append(values, param[1])
+		p.paramSources[names[len(names)-1]] = "--param"
+		if fileParam, ok := p.fileParams[param[0]]; ok {
+			p.paramSources[names[len(names)-1]] = overriding("--param", fileParam.source())
+		}
+		if _, ok := p.bundleParams[param[0]]; ok {
+			p.paramSources[names[len(names)-1]] = overriding(p.paramSources[names[len(names)-1]], p.bundleSource())
 		}
+	}
 
-		userParameterNames = append(userParameterNames, fmt.Sprintf("${%v}", param[0]))
-		userParameterValues = append(userParameterValues, param[1])
+	for _, name := range p.fileParamNames() {
+		placeholder := fmt.Sprintf("${%v}", name)
+		if slices.Contains(names, placeholder) {
+			continue
+		}
+		names = append(names, placeholder)
+		values = append(values, p.fileParams[name].value)
+		p.paramSources[placeholder] = p.fileParams[name].source()
+		if _, ok := p.bundleParams[name]; ok {
+			p.paramSources[placeholder] = overriding(p.paramSources[placeholder], p.bundleSource())
+		}
 	}
+
+	// The defaults of the bundle have the lowest precedence
+	for _, name := range p.bundleParamNames() {
+		placeholder := fmt.Sprintf("${%v}", name)
+		if slices.Contains(names, placeholder) {
+			continue
+		}
+		names = append(names, placeholder)
+		values = append(values, p.bundleParams[name])
+		p.paramSources[placeholder] = p.bundleSource()
+	}
+
+	clusterParamNames := make([]string, 0, len(clusterParams))
+	for name := range clusterParams {
+		clusterParamNames = append(clusterParamNames, name)
+	}
+	sort.Strings(clusterParamNames)
+	for _, name := range clusterParamNames {
+		placeholder := fmt.Sprintf("${%v}", name)
+		if i := slices.Index(names, placeholder); i >= 0 {
+			values[i] = clusterParams[name]
+			p.paramSources[placeholder] = overriding("the cluster entry", p.paramSources[placeholder])
+			continue
+		}
+		names = append(names, placeholder)
+		values = append(values, clusterParams[name])
+		p.paramSources[placeholder] = "the cluster entry"
+	}
+
+	return names, values, nil
 }
 
-func (p *Post) readTemplate() *TemplateFile {
+// readTemplate returns a freshly parsed copy of the template given with '-t'.
+// The template is only fetched once, so it can be rendered for many clusters.
+func (p *Post) readTemplate() (*TemplateFile, error) {
 	if p.Template == "" {
-		log.Fatalf("Template file is not provided. Use '-t' to fix this.")
-	} else {
+		return nil, errors.New("template file is not provided. Use '-t' to fix this")
+	}
+
+	if p.templateBytes == nil {
 		templateObj, err := p.accessFile(p.Template)
 		if err != nil { //check the presence of this URL or file and also if this can be accessed
-			log.Fatal(err)
+			return nil, err
 		}
+		p.templateBytes = templateObj
+	}
 
-		var t1 TemplateFile
-		json.Unmarshal(templateObj, &t1)
-		return &t1
+	contents, err := templateJSON(p.Template, p.templateBytes)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	var t1 TemplateFile
+	if err := json.Unmarshal(contents, &t1); err != nil {
+		return nil, fmt.Errorf("cannot parse the JSON template: %w", err)
+	}
+	return &t1, nil
 }
 
-// accessFile returns the contents of a local file or url, and any errors encountered
+// templateJSON returns the JSON of a template, converting templates whose path ends in .yaml or .yml from YAML
+func templateJSON(path string, contents []byte) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		converted, err := yaml.YAMLToJSON(contents)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse the YAML template: %w", err)
+		}
+		return converted, nil
+	}
+	return contents, nil
+}
+
+// readFieldFile returns the contents of --summary-file or --details-file, which replace a field of the template
+// before parameters are substituted
+func (p *Post) readFieldFile(filePath string) (string, error) {
+	if field, ok := p.fieldFiles[filePath]; ok {
+		return field, nil
+	}
+	contents, err := p.accessFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	field := strings.TrimSpace(string(contents))
+	if field == "" {
+		return "", fmt.Errorf("the file %q is empty", filePath)
+	}
+	if p.fieldFiles == nil {
+		p.fieldFiles = map[string]string{}
+	}
+	p.fieldFiles[filePath] = field
+	return field, nil
+}
+
+// accessFile returns the contents of a local file, url or ConfigMap key, and any errors encountered
 func (p *Post) accessFile(filePath string) ([]byte, error) {
+	if strings.HasPrefix(filePath, configMapScheme) {
+		return p.readConfigMap(filePath)
+	}
 
 	if utils.IsValidUrl(filePath) {
 		urlPage, _ := url.Parse(filePath)
 		if err := utils.IsOnline(*urlPage); err != nil {
 			return nil, fmt.Errorf("host %q is not accessible", filePath)
 		}
-		return utils.CurlThis(urlPage.String())
+		body, err := utils.CurlThisLimited(urlPage.String(), p.templateSizeLimit())
+		return body, p.checkTemplateSize(filePath, err)
 	}
 
 	filePath = filepath.Clean(filePath)
 	if utils.FileExists(filePath) {
 		// template is file on the disk
-		file, err := os.ReadFile(filePath) //#nosec G304 -- Potential file inclusion via variable
+		file, err := os.Open(filePath) //#nosec G304 -- Potential file inclusion via variable
 		if err != nil {
-			return file, fmt.Errorf("cannot read the file.\nError: %q", err)
+			return nil, fmt.Errorf("cannot read the file.\nError: %q", err)
 		}
-		return file, nil
+		defer file.Close()
+		// The size isn't known for every file, eg. named pipes, reading is limited as well
+		if info, err := file.Stat(); err == nil && info.Size() > p.templateSizeLimit() {
+			return nil, p.checkTemplateSize(filePath, fmt.Errorf("%w, it is %d bytes", utils.ErrTooLarge, info.Size()))
+		}
+		content, err := utils.ReadLimited(file, p.templateSizeLimit())
+		if err != nil && !errors.Is(err, utils.ErrTooLarge) {
+			return nil, fmt.Errorf("cannot read the file.\nError: %q", err)
+		}
+		return content, p.checkTemplateSize(filePath, err)
 	}
 	if utils.FolderExists(filePath) {
 		return nil, fmt.Errorf("the provided path %q is a directory, not a file", filePath)
@@ -316,21 +1567,75 @@ func (p *Post) accessFile(filePath string) ([]byte, error) {
 	return nil, fmt.Errorf("cannot read the file %q", filePath)
 }
 
-func (p *Post) replaceFlags(template *TemplateFile, flagName string, flagValue string) {
+// defaultMaxTemplateBytes is the default --max-template-bytes, templates are a few KiB at most
+const defaultMaxTemplateBytes = 1 << 20
+
+// templateSizeLimit returns the maximum size of the files read by accessFile
+func (p *Post) templateSizeLimit() int64 {
+	if p.maxTemplateBytes > 0 {
+		return p.maxTemplateBytes
+	}
+	return defaultMaxTemplateBytes
+}
+
+// checkTemplateSize explains the error of reading a file larger than --max-template-bytes, other errors are
+// returned as is
+func (p *Post) checkTemplateSize(filePath string, err error) error {
+	if errors.Is(err, utils.ErrTooLarge) {
+		return fmt.Errorf("%s is too large (%v), check the path or raise --max-template-bytes", filePath, err)
+	}
+	return err
+}
+
+// replaceFlags substitutes the parameter in the parsed template, never in its JSON text: values are escaped when
+// the reason is marshalled, so they can contain quotes, backslashes or newlines.
+func (p *Post) replaceFlags(template *TemplateFile, flagName string, flagValue string) error {
 	if flagValue == "" {
-		log.Fatalf("The selected template is using '%[1]s' parameter, but '%[1]s' flag was not set. Use '-p %[1]s=\"FOOBAR\"' to fix this.", flagName)
+		return fmt.Errorf("the selected template is using '%[1]s' parameter, but '%[1]s' flag was not set. Use '-p %[1]s=\"FOOBAR\"' to fix this", flagName)
 	}
 
-	found := false
+	if !usesParam(template.Details, flagName) {
+		return fmt.Errorf("the selected template is not using '%s' parameter, but '--param' flag was set. Do not use '-p %s=%s' to fix this", flagName, flagName, flagValue)
+	}
+	details, err := substituteParam(template.Details, flagName, flagValue)
+	if err != nil {
+		return err
+	}
+	template.Details = details
+	return nil
+}
 
-	if strings.Contains(template.Details, flagName) {
-		found = true
-		template.Details = strings.ReplaceAll(template.Details, flagName, flagValue)
+// explainf records where the value of a field comes from, for --explain
+func (p *Post) explainf(format string, args ...interface{}) {
+	if p.explain {
+		p.explanation = append(p.explanation, fmt.Sprintf(format, args...))
 	}
+}
+
+// substitutionContext is the number of characters shown around a substituted placeholder in verbose mode
+const substitutionContext = 20
 
-	if !found {
-		log.Fatalf("The selected template is not using '%s' parameter, but '--param' flag was set. Do not use '-p %s=%s' to fix this.", flagName, flagName, flagValue)
+// reportSubstitution describes how the placeholder is going to be substituted in details, with the
+// text around its first occurrence before and after the substitution
+func reportSubstitution(out io.Writer, details string, placeholder string, value string, source string) {
+	// Templates using only the list form are reported with the rendered list
+	if list := listPlaceholder(placeholder); !strings.Contains(details, placeholder) && strings.Contains(details, list) {
+		if rendered, err := listValue(value); err == nil {
+			placeholder, value = list, rendered
+		}
+	}
+	count := strings.Count(details, placeholder)
+	if count == 0 {
+		fmt.Fprintf(out, "Parameter %s (from %s): not used by the template\n", placeholder, source)
+		return
 	}
+
+	index := strings.Index(details, placeholder)
+	start := max(0, index-substitutionContext)
+	end := min(len(details), index+len(placeholder)+substitutionContext)
+	before := details[start:end]
+	after := details[start:index] + value + details[index+len(placeholder):end]
+	fmt.Fprintf(out, "Parameter %s (from %s): substituted %d time(s)\n  before: %q\n  after:  %q\n", placeholder, source, count, before, after)
 }
 
 func (p *Post) findLeftovers(s string) (matches []string) {
@@ -339,36 +1644,110 @@ func (p *Post) findLeftovers(s string) (matches []string) {
 	return matches
 }
 
-func (p *Post) checkLeftovers(template *TemplateFile) {
+func (p *Post) checkLeftovers(template *TemplateFile) error {
 	unusedParameters := p.findLeftovers(template.Details)
 	var numberOfMissingParameters int
 	for _, v := range unusedParameters {
+		param, declared := template.parameter(v)
+		// Optional parameters which were not set are dropped from the rendered template
+		if declared && !param.Required {
+			template.Details = strings.ReplaceAll(template.Details, v, "")
+			continue
+		}
+		// Undeclared placeholders are literal content with --allow-unresolved or --strict-params=false
+		if p.allowUnresolved || (p.lenientParams && !declared) {
+			continue
+		}
 		// Ignore parameters in the exclude list, ie ${CLUSTER_UUID}, which will be replaced later for each cluster a servicelog is sent to
 		if strings.Contains(template.Details, v) {
 			numberOfMissingParameters++
-			regex := strings.NewReplacer("${", "", "}", "")
-			log.Printf("The one of the template files is using '%s' parameter, but '--param' flag is not set for this one. Use '-p %v=\"FOOBAR\"' to fix this.", v, regex.Replace(v))
+			regex := strings.NewReplacer("${", "", listSuffix+"}", "", "}", "")
+			attrs := []any{"parameter", v, "fix", fmt.Sprintf("-p %s=\"FOOBAR\"", regex.Replace(v))}
+			if declared && param.Description != "" {
+				attrs = append(attrs, "description", param.Description)
+			}
+			logger().Warn("The template is using a parameter which is not set with --param", attrs...)
 		}
 	}
 	if numberOfMissingParameters == 1 {
-		log.Fatal("Please define this missing parameter properly.")
+		return errors.New("please define this missing parameter properly")
 	} else if numberOfMissingParameters > 1 {
-		log.Fatalf("Please define all %v missing parameters properly.", numberOfMissingParameters)
+		return fmt.Errorf("please define all %v missing parameters properly", numberOfMissingParameters)
+	}
+	return nil
+}
+
+// describeParameters prints the parameters declared by the template, along with any placeholder
+// the template uses without declaring it
+func (p *Post) describeParameters() error {
+	if err := p.Init(); err != nil {
+		return err
 	}
+	t, err := p.readTemplate()
+	if err != nil {
+		return err
+	}
+
+	table := printer.NewTablePrinter(os.Stdout, 20, 1, 3, ' ')
+	table.AddRow([]string{"Name", "Required", "Description"})
+	for _, param := range t.Parameters {
+		table.AddRow([]string{param.Name, strconv.FormatBool(param.Required), param.Description})
+	}
+	seen := map[string]bool{}
+	for _, v := range p.findLeftovers(t.Details) {
+		// Both forms of a placeholder are the same parameter
+		name := strings.NewReplacer("${", "", listSuffix+"}", "", "}", "").Replace(v)
+		if _, declared := t.parameter(v); !declared && !seen[name] {
+			seen[name] = true
+			table.AddRow([]string{name, "true", "(not declared by the template)"})
+		}
+	}
+
+	// Add empty row for readability
+	table.AddRow([]string{})
+	return table.Flush()
 }
 
-func printLimitedSupportReason(limitedSupport *cmv1.LimitedSupportReason) error {
+func printLimitedSupportReason(out io.Writer, limitedSupport *cmv1.LimitedSupportReason) error {
 	buf := bytes.Buffer{}
 	err := cmv1.MarshalLimitedSupportReason(limitedSupport, &buf)
 	if err != nil {
 		return fmt.Errorf("failed to marshal limited support reason: %w", err)
 	}
 
-	return dump.Pretty(os.Stdout, buf.Bytes())
+	return dump.Pretty(out, buf.Bytes())
+}
+
+// writeRenderedReason writes the reason rendered for the cluster to --render-only-to, for review
+func (p *Post) writeRenderedReason(clusterID string, limitedSupport *cmv1.LimitedSupportReason) error {
+	if p.cluster != nil {
+		clusterID = p.cluster.ID()
+	}
+	if err := os.MkdirAll(p.renderOnlyTo, 0750); err != nil {
+		return fmt.Errorf("cannot create the directory %s: %w", p.renderOnlyTo, err)
+	}
+
+	buf := bytes.Buffer{}
+	if err := cmv1.MarshalLimitedSupportReason(limitedSupport, &buf); err != nil {
+		return fmt.Errorf("failed to marshal limited support reason: %w", err)
+	}
+	// Cluster keys are validated, the base name is only a safety net against escaping the directory
+	path := filepath.Join(p.renderOnlyTo, filepath.Base(clusterID)+".json")
+	file, err := os.OpenFile(filepath.Clean(path), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("cannot write the rendered reason: %w", err)
+	}
+	defer file.Close()
+	if err := dump.Pretty(file, buf.Bytes()); err != nil {
+		return fmt.Errorf("cannot write the rendered reason to %s: %w", path, err)
+	}
+
+	logger().Info("Rendered the limited support reason", "cluster", clusterID, "path", path)
+	return nil
 }
 
-func sendLimitedSupportPostRequest(ocmClient *sdk.Connection, clusterID string, limitedSupport *cmv1.LimitedSupportReason) (*cmv1.LimitedSupportReasonsAddResponse, error) {
-	response, err := ocmClient.ClustersMgmt().V1().Clusters().Cluster(clusterID).LimitedSupportReasons().Add().Body(limitedSupport).Send()
+func sendLimitedSupportPostRequest(ctx context.Context, ocmClient *sdk.Connection, clusterID string, limitedSupport *cmv1.LimitedSupportReason) (*cmv1.LimitedSupportReasonsAddResponse, error) {
+	response, err := ocmClient.ClustersMgmt().V1().Clusters().Cluster(clusterID).LimitedSupportReasons().Add().Body(limitedSupport).SendContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to post new limited support reason: %w", err)
 	}
@@ -394,17 +1773,49 @@ func (p *Post) buildInternalServiceLog(limitedSupportId string, subscriptionId s
 	return logEntry, nil
 }
 
-func printInternalServiceLog(logEntry *slv1.LogEntry) error {
+// previewInternalServiceLog prints the internal service log that would be sent along with the reason.
+// The reason does not exist yet, so the service log references a placeholder ID.
+func (p *Post) previewInternalServiceLog(clusterID string) error {
+	if p.Evidence == "" {
+		logger().Info("No internal service log will be sent, --evidence is not set", "cluster", clusterID)
+		return nil
+	}
+
+	preview := *p
+	var subscriptionId string
+	if p.cluster == nil {
+		// The cluster was not resolved in OCM, only the ID given by the user is known
+		cluster, err := cmv1.NewCluster().ID(clusterID).Build()
+		if err != nil {
+			return err
+		}
+		preview.cluster = cluster
+	} else if subscription, ok := p.cluster.GetSubscription(); ok {
+		subscriptionId = subscription.ID()
+	}
+
+	log, err := preview.buildInternalServiceLog("<limited support reason ID>", subscriptionId)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(p.previewOut(), "The following internal service log will be sent to %s (internal only):\n", clusterID)
+	if err = printInternalServiceLog(p.previewOut(), log); err != nil {
+		return fmt.Errorf("failed to print internal service log template: %w", err)
+	}
+	return nil
+}
+
+func printInternalServiceLog(out io.Writer, logEntry *slv1.LogEntry) error {
 	buf := bytes.Buffer{}
 	err := slv1.MarshalLogEntry(logEntry, &buf)
 	if err != nil {
 		return fmt.Errorf("failed to marshal log entry: %w", err)
 	}
-	return dump.Pretty(os.Stdout, buf.Bytes())
+	return dump.Pretty(out, buf.Bytes())
 }
 
-func sendInternalServiceLogPostRequest(ocmClient *sdk.Connection, logEntry *slv1.LogEntry) (*slv1.ClusterLogsAddResponse, error) {
-	response, err := ocmClient.ServiceLogs().V1().ClusterLogs().Add().Body(logEntry).Send()
+func sendInternalServiceLogPostRequest(ctx context.Context, ocmClient *sdk.Connection, logEntry *slv1.LogEntry) (*slv1.ClusterLogsAddResponse, error) {
+	response, err := ocmClient.ServiceLogs().V1().ClusterLogs().Add().Body(logEntry).SendContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to post new internal service log: %w", err)
 	}
@@ -1,10 +1,21 @@
 package support
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/spf13/viper"
 )
 
 func TestValidateResolutionString(t *testing.T) {
@@ -50,6 +61,16 @@ func Test_buildLimitedSupport(t *testing.T) {
 			},
 			wantSummary: LimitedSupportSummaryCluster,
 		},
+		{
+			name: "Builds a limited support struct referencing an incident",
+			post: &Post{
+				Misconfiguration: cluster,
+				Problem:          "test problem cluster",
+				Resolution:       "test resolution cluster",
+				IncidentID:       "OHSS-1234",
+			},
+			wantSummary: LimitedSupportSummaryCluster,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -64,8 +85,12 @@ func Test_buildLimitedSupport(t *testing.T) {
 			if detectionType := got.DetectionType(); detectionType != cmv1.DetectionTypeManual {
 				t.Errorf("buildLimitedSupport() got detectionType = %v, want %v", detectionType, cmv1.DetectionTypeManual)
 			}
-			if details := got.Details(); details != fmt.Sprintf("%s %s", tt.post.Problem, tt.post.Resolution) {
-				t.Errorf("buildLimitedSupport() got details = %s, want %s", details, fmt.Sprintf("%s %s", tt.post.Problem, tt.post.Resolution))
+			wantDetails := fmt.Sprintf("%s %s", tt.post.Problem, tt.post.Resolution)
+			if tt.post.IncidentID != "" {
+				wantDetails = fmt.Sprintf("%s (Reference: %s)", wantDetails, tt.post.IncidentID)
+			}
+			if details := got.Details(); details != wantDetails {
+				t.Errorf("buildLimitedSupport() got details = %s, want %s", details, wantDetails)
 			}
 		})
 	}
@@ -151,3 +176,504 @@ func Test_buildInternalServiceLog(t *testing.T) {
 		})
 	}
 }
+
+func Test_checkLeftoversDropsOptionalParameters(t *testing.T) {
+	template := &TemplateFile{
+		Details: "Details with an optional note:${NOTE}",
+		Parameters: []TemplateParameter{
+			{Name: "NOTE", Required: false, Description: "An optional note"},
+		},
+	}
+
+	p := &Post{}
+	if err := p.checkLeftovers(template); err != nil {
+		t.Fatalf("checkLeftovers() error = %v, wantErr %v", err, false)
+	}
+
+	if template.Details != "Details with an optional note:" {
+		t.Errorf("checkLeftovers() got details = %q, want the optional placeholder removed", template.Details)
+	}
+}
+
+func Test_parseUserParametersClusterOverrides(t *testing.T) {
+	p := &Post{TemplateParams: []string{"FOO=global", "BAR=global"}}
+
+	names, values, err := p.parseUserParameters(map[string]string{"FOO": "cluster", "BAZ": "cluster"})
+	if err != nil {
+		t.Fatalf("parseUserParameters() error = %v, wantErr %v", err, false)
+	}
+
+	got := map[string]string{}
+	for i := range names {
+		got[names[i]] = values[i]
+	}
+	want := map[string]string{"${FOO}": "cluster", "${BAR}": "global", "${BAZ}": "cluster"}
+	if len(got) != len(want) {
+		t.Fatalf("parseUserParameters() got %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("parseUserParameters() got %s = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func Test_checkUnresolved(t *testing.T) {
+	reason, err := cmv1.NewLimitedSupportReason().Summary("Summary for ${NAME}").Details("Details").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := (&Post{}).checkUnresolved(reason); err == nil {
+		t.Errorf("checkUnresolved() expected an error for the placeholder left in the summary")
+	}
+	if err := (&Post{allowUnresolved: true}).checkUnresolved(reason); err != nil {
+		t.Errorf("checkUnresolved() error = %v with --allow-unresolved, wantErr %v", err, false)
+	}
+}
+
+func Test_checkParamFromAWS(t *testing.T) {
+	tests := []struct {
+		name    string
+		post    *Post
+		wantErr bool
+	}{
+		{
+			name:    "Requires a template",
+			post:    &Post{paramFromAWS: true, Misconfiguration: cloud, Problem: "problem", Resolution: "resolution"},
+			wantErr: true,
+		},
+		{
+			name:    "Conflicts with an AWS parameter given with -p",
+			post:    &Post{paramFromAWS: true, Template: "template.json", TemplateParams: []string{"AWS_ACCOUNT_ID=123456789012"}},
+			wantErr: true,
+		},
+		{
+			name: "Accepts other parameters",
+			post: &Post{paramFromAWS: true, Template: "template.json", TemplateParams: []string{"FOO=BAR"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.post.check(); (err != nil) != tt.wantErr {
+				t.Errorf("check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_reportSubstitution(t *testing.T) {
+	var out bytes.Buffer
+	reportSubstitution(&out, "The ingress controller ${NAME} is not supported, remove ${NAME}", "${NAME}", "custom", "--param")
+	want := "Parameter ${NAME} (from --param): substituted 2 time(s)\n" +
+		"  before: \" ingress controller ${NAME} is not supported, r\"\n" +
+		"  after:  \" ingress controller custom is not supported, r\"\n"
+	if out.String() != want {
+		t.Errorf("reportSubstitution() = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	reportSubstitution(&out, "No parameters", "${NAME}", "custom", "cluster entry")
+	if !strings.Contains(out.String(), "not used by the template") {
+		t.Errorf("expected an unused parameter to be reported, got %q", out.String())
+	}
+}
+
+func Test_checkHibernation(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   cmv1.ClusterState
+		post    *Post
+		wantErr bool
+	}{
+		{name: "Ready cluster", state: cmv1.ClusterStateReady, post: &Post{}},
+		{name: "Hibernating cluster", state: cmv1.ClusterStateHibernating, post: &Post{}, wantErr: true},
+		{name: "Hibernating cluster with --force", state: cmv1.ClusterStateHibernating, post: &Post{force: true}},
+		{name: "Hibernating cluster in dry-run", state: cmv1.ClusterStateHibernating, post: &Post{isDryRun: true}},
+		{name: "Resuming cluster", state: cmv1.ClusterStateResuming, post: &Post{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster, err := cmv1.NewCluster().ID("abc").State(tt.state).Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.post.checkHibernation(cluster); (err != nil) != tt.wantErr {
+				t.Errorf("checkHibernation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_buildLimitedSupportTemplateExplain(t *testing.T) {
+	p := &Post{
+		Template:       "template.json",
+		TemplateParams: []string{"NAME=custom"},
+		IncidentID:     "OHSS-1234",
+		explain:        true,
+		templateBytes:  []byte(`{"summary":"Summary","details":"Remove ${NAME}${HINT}","detection_type":"manual","parameters":[{"name":"HINT","required":false}]}`),
+	}
+	if _, err := p.buildLimitedSupportTemplate(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"summary: verbatim from template template.json",
+		"detection_type: verbatim from template template.json",
+		`details: ${NAME} replaced by "custom" from --param`,
+		"details: optional ${HINT} removed as it was not set",
+		"details: reference OHSS-1234 appended from --incident-id",
+	}
+	if !reflect.DeepEqual(p.explanation, want) {
+		t.Errorf("explanation = %q, want %q", p.explanation, want)
+	}
+}
+
+func Test_buildLimitedSupportTemplateLenientParams(t *testing.T) {
+	template := []byte(`{"summary":"Summary","details":"Set ${NAME} in ${HOME}/.config, ${ZONE}","detection_type":"manual","parameters":[{"name":"ZONE","required":true}]}`)
+	tests := []struct {
+		name        string
+		lenient     bool
+		params      []string
+		wantDetails string
+		wantErr     bool
+	}{
+		{
+			name:    "Strict rejects undeclared placeholders",
+			params:  []string{"NAME=foo", "ZONE=us-east-1a"},
+			wantErr: true,
+		},
+		{
+			name:        "Lenient keeps undeclared placeholders verbatim",
+			lenient:     true,
+			params:      []string{"NAME=foo", "ZONE=us-east-1a"},
+			wantDetails: "Set foo in ${HOME}/.config, us-east-1a",
+		},
+		{
+			name:    "Lenient still requires declared parameters",
+			lenient: true,
+			params:  []string{"NAME=foo"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Post{Template: "template.json", TemplateParams: tt.params, lenientParams: tt.lenient, templateBytes: template}
+			reason, err := p.buildLimitedSupportTemplate(nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildLimitedSupportTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && reason.Details() != tt.wantDetails {
+				t.Errorf("buildLimitedSupportTemplate() details = %q, want %q", reason.Details(), tt.wantDetails)
+			}
+		})
+	}
+}
+
+func Test_newPostResult(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	reason, err := cmv1.NewLimitedSupportReason().ID("reason-id").Summary("Summary").CreationTimestamp(createdAt).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := newPostResult("cluster-id", 201, reason)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ClusterID != "cluster-id" || result.ReasonID != "reason-id" || result.Status != 201 || !result.CreatedAt.Equal(createdAt) {
+		t.Errorf("newPostResult() = %+v, want the cluster, reason ID, status and creation time", result)
+	}
+	parsed, err := cmv1.UnmarshalLimitedSupportReason(result.RawBody)
+	if err != nil {
+		t.Fatalf("newPostResult() RawBody is not a limited support reason: %v", err)
+	}
+	if parsed.Summary() != "Summary" {
+		t.Errorf("newPostResult() RawBody summary = %q, want %q", parsed.Summary(), "Summary")
+	}
+}
+
+func Test_buildLimitedSupportTemplateFieldFiles(t *testing.T) {
+	dir := t.TempDir()
+	summaryFile := filepath.Join(dir, "summary.txt")
+	if err := os.WriteFile(summaryFile, []byte("Summary from a file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	detailsFile := filepath.Join(dir, "details.md")
+	if err := os.WriteFile(detailsFile, []byte("Long details about ${NAME},\nspanning \"several\" lines.\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Post{
+		Template:       "template.json",
+		TemplateParams: []string{"NAME=foo"},
+		SummaryFile:    summaryFile,
+		DetailsFile:    detailsFile,
+		templateBytes:  []byte(`{"summary":"Summary","details":"Details","detection_type":"manual"}`),
+	}
+	reason, err := p.buildLimitedSupportTemplate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reason.Summary() != "Summary from a file" {
+		t.Errorf("summary = %q, want the contents of --summary-file", reason.Summary())
+	}
+	if want := "Long details about foo,\nspanning \"several\" lines."; reason.Details() != want {
+		t.Errorf("details = %q, want %q", reason.Details(), want)
+	}
+
+	// The parameters are substituted in the summary as well, one there only is used
+	summaryParamFile := filepath.Join(dir, "summary-param.txt")
+	if err := os.WriteFile(summaryParamFile, []byte("Summary for ${ZONE}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	p.SummaryFile = summaryParamFile
+	p.TemplateParams = []string{"NAME=foo", "ZONE=eu-west-1"}
+	reason, err = p.buildLimitedSupportTemplate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reason.Summary() != "Summary for eu-west-1" {
+		t.Errorf("summary = %q, want the parameters substituted in --summary-file", reason.Summary())
+	}
+
+	p.DetailsFile = filepath.Join(dir, "missing.md")
+	if _, err := p.buildLimitedSupportTemplate(nil); err == nil {
+		t.Errorf("buildLimitedSupportTemplate() expected an error for a missing --details-file")
+	}
+}
+
+func Test_withLinks(t *testing.T) {
+	p := &Post{
+		Template:      "template.json",
+		Links:         []string{"https://example.com/runbook", "https://example.com/kb?id=1"},
+		templateBytes: []byte(`{"summary":"Summary","details":"Details","detection_type":"manual"}`),
+	}
+	if err := p.check(); err != nil {
+		t.Fatalf("check() error = %v, wantErr %v", err, false)
+	}
+	reason, err := p.buildLimitedSupportTemplate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Details (Links: https://example.com/runbook https://example.com/kb?id=1)"; reason.Details() != want {
+		t.Errorf("details = %q, want %q", reason.Details(), want)
+	}
+
+	for _, link := range []string{"example.com/runbook", "ftp://example.com/runbook", "https://example.com/a (b)"} {
+		if err := (&Post{Template: "template.json", Links: []string{link}}).check(); err == nil {
+			t.Errorf("check() expected an error for --link %q", link)
+		}
+	}
+}
+
+func Test_templateVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	viper.Set(HistoryFileKey, path)
+	defer viper.Set(HistoryFileKey, "")
+
+	for _, footer := range []bool{false, true} {
+		p := &Post{
+			Template:              "template.json",
+			templateVersionFooter: footer,
+			templateBytes:         []byte(`{"_template_version":"2.1.0","summary":"Summary","details":"Details","detection_type":"manual"}`),
+		}
+		reason, err := p.buildLimitedSupportTemplate(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := "Details"
+		if footer {
+			want = "Details (Template version: 2.1.0)"
+		}
+		if reason.Details() != want {
+			t.Errorf("details with footer %v = %q, want %q", footer, reason.Details(), want)
+		}
+
+		var body bytes.Buffer
+		if err := cmv1.MarshalLimitedSupportReason(reason, &body); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(body.String(), "_template_version") {
+			t.Errorf("body sent to OCM %s contains the template version metadata", body.String())
+		}
+
+		p.recordHistory("cluster-a", reason)
+		entry, err := lastHistoryEntry("cluster-a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry == nil || entry.TemplateVersion != "2.1.0" {
+			t.Errorf("lastHistoryEntry() = %+v, want the template version to be recorded", entry)
+		}
+	}
+}
+
+func Test_paramFromLabels(t *testing.T) {
+	params := labelParameters(map[string]string{
+		"my-team.owner": "sre",
+		"MY_TEAM_OWNER": "ignored",
+		"tier":          "gold",
+	})
+	want := map[string]string{"LABEL_MY_TEAM_OWNER": "ignored", "LABEL_TIER": "gold"}
+	if !reflect.DeepEqual(params, want) {
+		t.Errorf("labelParameters() = %v, want %v", params, want)
+	}
+
+	// '-p' takes precedence over the labels
+	p := &Post{
+		Template:       "template.json",
+		TemplateParams: []string{"LABEL_TIER=silver"},
+		labelParams:    map[string]string{"LABEL_TIER": "gold", "LABEL_OWNER": "sre"},
+		templateBytes:  []byte(`{"summary":"Summary","details":"Tier ${LABEL_TIER} owned by ${LABEL_OWNER}","detection_type":"manual"}`),
+	}
+	reason, err := p.buildLimitedSupportTemplate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reason.Details() != "Tier silver owned by sre" {
+		t.Errorf("details = %q, want %q", reason.Details(), "Tier silver owned by sre")
+	}
+}
+
+func Test_accessFileMaxTemplateBytes(t *testing.T) {
+	content := `{"summary":"Summary","details":"Details","detection_type":"manual"}`
+	path := filepath.Join(t.TempDir(), "template.json")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	for _, source := range []string{path, server.URL} {
+		p := &Post{maxTemplateBytes: int64(len(content))}
+		if got, err := p.accessFile(source); err != nil || string(got) != content {
+			t.Errorf("accessFile(%s) = %q, %v, want the content", source, got, err)
+		}
+
+		p.maxTemplateBytes = int64(len(content)) - 1
+		_, err := p.accessFile(source)
+		if err == nil || !strings.Contains(err.Error(), "--max-template-bytes") {
+			t.Errorf("accessFile(%s) error = %v, want the size limit to be reported", source, err)
+		}
+	}
+
+	// The default applies to the options built by other commands
+	if limit := (&Post{}).templateSizeLimit(); limit != defaultMaxTemplateBytes {
+		t.Errorf("templateSizeLimit() = %d, want %d", limit, defaultMaxTemplateBytes)
+	}
+}
+
+func Test_buildLimitedSupportTemplateSpecialCharacters(t *testing.T) {
+	values := map[string]string{
+		"quotes":          `the "default" ingress`,
+		"backslashes":     `C:\path\to\file and \"escaped\"`,
+		"newlines":        "first line\nsecond line\r\n\ttabbed",
+		"control":         "bell\a and nul\x00 characters",
+		"json breaking":   `", "detection_type": "auto`,
+		"template syntax": "${NOT_A_PARAM}",
+	}
+	dir := t.TempDir()
+	for name, value := range values {
+		t.Run(name, func(t *testing.T) {
+			paramsFile := filepath.Join(dir, strings.ReplaceAll(name, " ", "-")+".json")
+			data, err := json.Marshal(map[string]string{"FROM_FILE": value})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(paramsFile, data, 0600); err != nil {
+				t.Fatal(err)
+			}
+			p := &Post{
+				Template:        "template.json",
+				TemplateParams:  []string{"FROM_FLAG=" + value},
+				ParamsFiles:     []string{paramsFile},
+				allowUnresolved: true,
+				templateBytes:   []byte(`{"summary":"Summary","details":"${FROM_FLAG}|${FROM_FILE}|${FROM_CLUSTER}","detection_type":"manual"}`),
+			}
+			reason, err := p.buildLimitedSupportTemplate(map[string]string{"FROM_CLUSTER": value})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := value + "|" + value + "|" + value
+			if reason.Details() != want {
+				t.Errorf("details = %q, want %q", reason.Details(), want)
+			}
+
+			body := marshalReason(reason)
+			if !json.Valid(body) {
+				t.Fatalf("the reason is not valid JSON: %s", body)
+			}
+			var posted map[string]string
+			if err := json.Unmarshal(body, &posted); err != nil {
+				t.Fatal(err)
+			}
+			if posted["details"] != want || posted["detection_type"] != "manual" {
+				t.Errorf("the reason sent to OCM = %v, want the details %q", posted, want)
+			}
+		})
+	}
+}
+
+func Test_postReasonFilesUnusedParameter(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.json", "b.json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(`{"summary":"Summary","details":"Details of ${NAME}","detection_type":"manual"}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := &Post{ReasonFileGlob: filepath.Join(dir, "*.json"), isDryRun: true, TemplateParams: []string{"NAME=foo", "UNUSED=bar"}}
+
+	// Nothing is rendered, nor posted, before the mistake is reported
+	stdout := os.Stdout
+	read, write, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = write
+	err = p.postReasonFiles(nil, "cluster-id")
+	os.Stdout = stdout
+	_ = write.Close()
+	out, _ := io.ReadAll(read)
+	if err == nil || !strings.Contains(err.Error(), "${UNUSED}") {
+		t.Errorf("postReasonFiles() = %v, want the unused parameter to be reported", err)
+	}
+	if len(out) != 0 {
+		t.Errorf("postReasonFiles() printed %q before reporting the unused parameter", out)
+	}
+}
+
+func Test_previewOutWithJSON(t *testing.T) {
+	p := &Post{
+		Template:      "template.json",
+		templateBytes: []byte(`{"summary":"Summary","details":"Details","detection_type":"manual"}`),
+		isDryRun:      true,
+		Evidence:      "See OHSS-1234",
+		output:        "json",
+	}
+	stdout := os.Stdout
+	read, write, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = write
+	_, err = p.postToCluster(nil, "cluster-id", nil, false)
+	os.Stdout = stdout
+	_ = write.Close()
+	out, _ := io.ReadAll(read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the JSON document of the result belongs on stdout
+	if len(out) != 0 {
+		t.Errorf("the preview of a dry-run with '-o json' was printed on stdout: %q", out)
+	}
+	if (&Post{}).previewOut() != os.Stdout {
+		t.Errorf("previewOut() without '-o' isn't stdout")
+	}
+}
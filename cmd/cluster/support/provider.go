@@ -0,0 +1,63 @@
+package support
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+)
+
+// cloudProviders are the cloud provider IDs of OCM a reason can be restricted to
+var cloudProviders = map[string]struct{}{
+	"aws": {},
+	"gcp": {},
+}
+
+// checkCloudProviderName checks that the provider is one of cloudProviders
+func checkCloudProviderName(provider string) error {
+	if _, ok := cloudProviders[provider]; ok {
+		return nil
+	}
+	names := make([]string, 0, len(cloudProviders))
+	for name := range cloudProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("unknown cloud provider %q, it must be one of %s", provider, strings.Join(names, ", "))
+}
+
+// expectedProvider returns the cloud provider the reason is restricted to: --expect-provider, or else the
+// 'provider' of the template. It is empty when the reason applies to every provider.
+func (p *Post) expectedProvider() (provider string, source string, err error) {
+	if p.expectProvider != "" {
+		return p.expectProvider, "--expect-provider", nil
+	}
+	if p.Template == "" {
+		return "", "", nil
+	}
+	t, err := p.readTemplate()
+	if err != nil {
+		return "", "", err
+	}
+	if t.Provider == "" {
+		return "", "", nil
+	}
+	if err := checkCloudProviderName(t.Provider); err != nil {
+		return "", "", fmt.Errorf("invalid 'provider' in template %s: %w", p.Template, err)
+	}
+	return t.Provider, "the template's 'provider'", nil
+}
+
+// checkProvider refuses to post a cloud-specific reason to a cluster of another cloud provider, eg. an AWS
+// reason to a GCP cluster
+func (p *Post) checkProvider(cluster *cmv1.Cluster) error {
+	provider, source, err := p.expectedProvider()
+	if err != nil || provider == "" {
+		return err
+	}
+	if actual := cluster.CloudProvider().ID(); actual != provider {
+		return fmt.Errorf("cluster %s runs on %q, but %s restricts the reason to %q", cluster.ID(), actual, source, provider)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package support
+
+import (
+	"testing"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+)
+
+func Test_checkProvider(t *testing.T) {
+	gcpCluster, err := cmv1.NewCluster().ID("gcp-cluster").CloudProvider(cmv1.NewCloudProvider().ID("gcp")).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	awsTemplate := []byte(`{"summary":"Summary","details":"Details","detection_type":"manual","provider":"aws"}`)
+
+	tests := []struct {
+		name    string
+		post    *Post
+		wantErr bool
+	}{
+		{
+			name: "Templates without a provider apply to every cluster",
+			post: &Post{Template: "template.json", templateBytes: []byte(`{"summary":"Summary","details":"Details"}`)},
+		},
+		{
+			name:    "Refuses a cluster of another provider than the template's",
+			post:    &Post{Template: "template.json", templateBytes: awsTemplate},
+			wantErr: true,
+		},
+		{
+			name: "--expect-provider overrides the template",
+			post: &Post{Template: "template.json", templateBytes: awsTemplate, expectProvider: "gcp"},
+		},
+		{
+			name:    "Refuses a cluster of another provider than --expect-provider",
+			post:    &Post{expectProvider: "aws", Misconfiguration: cloud, Problem: "problem", Resolution: "resolution"},
+			wantErr: true,
+		},
+		{
+			name:    "Rejects an unknown provider in the template",
+			post:    &Post{Template: "template.json", templateBytes: []byte(`{"summary":"Summary","details":"Details","provider":"AWS"}`)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.post.checkProvider(gcpCluster); (err != nil) != tt.wantErr {
+				t.Errorf("checkProvider() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := (&Post{expectProvider: "azure", Template: "template.json"}).check(); err == nil {
+		t.Errorf("check() expected an error for an unknown --expect-provider")
+	}
+}
@@ -0,0 +1,133 @@
+package support
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	ocmerrors "github.com/openshift-online/ocm-sdk-go/errors"
+	ctlutil "github.com/openshift/osdctl/pkg/utils"
+	"github.com/spf13/viper"
+)
+
+// readRawBody returns the contents of --raw-body-file, which only has to be well-formed JSON
+func (p *Post) readRawBody() ([]byte, error) {
+	body, err := p.accessFile(p.RawBodyFile)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("the raw body %s is not well-formed JSON", p.RawBodyFile)
+	}
+	// The reason allowlist is a policy, it applies to raw bodies too
+	if reason, err := cmv1.UnmarshalLimitedSupportReason(body); err == nil {
+		if err := p.checkAllowlist(reason); err != nil {
+			return nil, err
+		}
+	} else if viper.GetString(ReasonAllowlistKey) != "" {
+		return nil, fmt.Errorf("cannot check the raw body %s against the reason allowlist: %w", p.RawBodyFile, err)
+	}
+	return body, nil
+}
+
+// postRawBody sends the exact bytes of --raw-body-file to the limited support reasons of the cluster,
+// without any parsing or substitution. A nil connection is only valid for a dry-run.
+func (p *Post) postRawBody(connection *sdk.Connection, clusterID string) (*PostResult, error) {
+	body, err := p.readRawBody()
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(p.previewOut(), "The following body will be sent verbatim to %s (customer facing):\n%s\n", clusterID, bytes.TrimSpace(body))
+	if p.isDryRun {
+		if p.validateSchema {
+			return nil, p.validateAgainstSchema(connection, body)
+		}
+		return nil, nil
+	}
+
+	if !p.clusterPrepared {
+		if err := p.prepareCluster(connection, clusterID); err != nil {
+			return nil, err
+		}
+	}
+	data := confirmData{ClusterID: p.cluster.ID(), ClusterName: p.cluster.Name()}
+	if reason, err := cmv1.UnmarshalLimitedSupportReason(body); err == nil {
+		data.Summary = reason.Summary()
+	}
+	if ok, err := p.confirmPost(data); !ok {
+		return nil, err
+	}
+
+	// Like the reasons built from templates, the post is only retried when OCM refused it
+	var response *sdk.Response
+	var reason *cmv1.LimitedSupportReason
+	err = retryOCMCreate(p.retries, func() error {
+		request, err := createReasonRequest(connection, reasonRequestOptions{
+			method:    http.MethodPost,
+			clusterID: p.cluster.ID(),
+		})
+		if err != nil {
+			return err
+		}
+		request.Bytes(body)
+		response, err = ctlutil.SendRequest(request)
+		if err != nil {
+			return err
+		}
+		reason, err = checkRawPost(response)
+		return err
+	})
+	var received []byte
+	if response != nil {
+		received = response.Bytes()
+	}
+	p.saveIO(p.cluster.ID(), body, received, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to post the raw body: %w", err)
+	}
+	p.recordHistory(p.cluster.ID(), reason)
+
+	return &PostResult{
+		ClusterID: p.cluster.ID(),
+		ReasonID:  reason.ID(),
+		Status:    response.Status(),
+		CreatedAt: reason.CreationTimestamp(),
+		RawBody:   bytes.TrimSpace(response.Bytes()),
+	}, nil
+}
+
+// checkRawPost checks the response to a raw body post and returns the reason created by OCM.
+// A refused post fails with the OCM error of its status, so that it can be retried.
+func checkRawPost(response *sdk.Response) (*cmv1.LimitedSupportReason, error) {
+	body := response.Bytes()
+	switch response.Status() {
+	case http.StatusOK, http.StatusCreated:
+		if err := ctlutil.CheckJSONResponse(response); err != nil {
+			return nil, err
+		}
+		reason, err := cmv1.UnmarshalLimitedSupportReason(body)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse the created limited support reason: %w", err)
+		}
+		return reason, nil
+	}
+
+	ocmErr, err := ocmerrors.UnmarshalErrorStatus(body, response.Status())
+	if err == nil {
+		return nil, ocmErr
+	}
+	// The error isn't an OCM one, eg. a page of a proxy
+	reason := fmt.Sprintf("cannot parse the error JSON message: %v", err)
+	if jsonErr := ctlutil.CheckJSONResponse(response); jsonErr != nil {
+		reason = jsonErr.Error()
+	}
+	ocmErr, err = ocmerrors.NewError().Status(response.Status()).Reason(reason).Build()
+	if err != nil {
+		return nil, err
+	}
+	return nil, ocmErr
+}
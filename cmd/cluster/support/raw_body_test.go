@@ -0,0 +1,99 @@
+package support
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/spf13/viper"
+)
+
+func Test_readRawBody(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.json")
+	if err := os.WriteFile(valid, []byte(`{"summary":"${NOT_A_PARAM}","details":"Details"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"summary":`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := (&Post{RawBodyFile: valid}).readRawBody()
+	if err != nil {
+		t.Fatalf("readRawBody() error = %v", err)
+	}
+	if string(body) != `{"summary":"${NOT_A_PARAM}","details":"Details"}` {
+		t.Errorf("readRawBody() = %s, want the file contents untouched", body)
+	}
+	if _, err := (&Post{RawBodyFile: invalid}).readRawBody(); err == nil {
+		t.Errorf("readRawBody() expected an error for malformed JSON")
+	}
+}
+
+func Test_postRawBodyRetriesRefusedPosts(t *testing.T) {
+	viper.Set(HistoryFileKey, filepath.Join(t.TempDir(), "history.jsonl"))
+	defer viper.Set(HistoryFileKey, "")
+	retrySleep = func(time.Duration) {}
+	defer func() { retrySleep = time.Sleep }()
+	bodyFile := filepath.Join(t.TempDir(), "body.json")
+	if err := os.WriteFile(bodyFile, []byte(`{"summary":"Summary","details":"Details"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+	if _, err := w.Write([]byte("y\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	const reasons = "POST /api/clusters_mgmt/v1/clusters/cluster-id/limited_support_reasons"
+	var posts int
+	fake, connection := newFakeOCM(t, map[string]http.HandlerFunc{
+		reasons: func(w http.ResponseWriter, r *http.Request) {
+			posts++
+			if posts == 1 {
+				respond(http.StatusTooManyRequests, `{"kind":"Error","reason":"Too many requests"}`)(w, r)
+				return
+			}
+			respond(http.StatusCreated, `{"kind":"LimitedSupportReason","id":"reason-id","summary":"Summary","details":"Details"}`)(w, r)
+		},
+	})
+	cluster, err := cmv1.NewCluster().ID("cluster-id").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Post{RawBodyFile: bodyFile, cluster: cluster, clusterPrepared: true, retries: &retryBudget{remaining: 1}}
+	result, err := p.postRawBody(connection, "cluster-id")
+	if err != nil {
+		t.Fatalf("postRawBody() error = %v", err)
+	}
+	if result.ReasonID != "reason-id" || fake.count(reasons) != 2 {
+		t.Errorf("postRawBody() = %+v after %d post(s), want the rate limited post to be retried", result, fake.count(reasons))
+	}
+
+	// The cluster key is checked before anything is sent, even with --trusted-input
+	for _, trusted := range []bool{false, true} {
+		p = &Post{RawBodyFile: bodyFile, trustedInput: trusted}
+		if _, err := p.postRawBody(connection, "bad'key"); err == nil {
+			t.Errorf("postRawBody() with trusted input %v expected an error for an invalid cluster key", trusted)
+		}
+	}
+}
+
+func Test_checkRawBodyFlags(t *testing.T) {
+	if err := (&Post{RawBodyFile: "body.json"}).check(); err != nil {
+		t.Errorf("check() error = %v, want nil for --raw-body-file alone", err)
+	}
+	if err := (&Post{RawBodyFile: "body.json", Template: "template.json"}).check(); err == nil {
+		t.Errorf("check() expected an error for --raw-body-file together with --template")
+	}
+}
@@ -0,0 +1,183 @@
+package support
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	"github.com/openshift/osdctl/internal/utils/globalflags"
+	ctlutil "github.com/openshift/osdctl/pkg/utils"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+type replayOptions struct {
+	historyFile string
+	clusterID   string
+	incidentID  string
+	isDryRun    bool
+
+	genericclioptions.IOStreams
+	GlobalOptions *globalflags.GlobalOptions
+}
+
+func newCmdreplay(streams genericclioptions.IOStreams, globalOpts *globalflags.GlobalOptions) *cobra.Command {
+	ops := newReplayOptions(streams, globalOpts)
+	replayCmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Re-post limited support reasons recorded in the local post history",
+		Long: `Re-posts the limited support reasons recorded in the local post history, eg. after they were cleared by mistake.
+Every reason to be posted is printed first, and the caller will be prompted to continue before anything is sent.`,
+		Example: `# Re-post every reason posted for an incident
+osdctl cluster support replay --from ~/.config/osdctl-support-history.jsonl --incident-id OHSS-1234`,
+		Args:              cobra.NoArgs,
+		DisableAutoGenTag: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(ops.complete(cmd, args))
+			cmdutil.CheckErr(ops.run())
+		},
+	}
+
+	replayCmd.Flags().StringVar(&ops.historyFile, "from", "", "History file to replay. Defaults to the local post history")
+	replayCmd.Flags().StringVarP(&ops.clusterID, "cluster-id", "C", "", "Only replay the reasons posted to this cluster (internal ID)")
+	replayCmd.Flags().StringVar(&ops.incidentID, "incident-id", "", "Only replay the reasons posted with this --incident-id")
+	replayCmd.Flags().BoolVarP(&ops.isDryRun, "dry-run", "d", false, "Dry-run - print the limited support reasons about to be sent but don't send them.")
+
+	return replayCmd
+}
+
+func newReplayOptions(streams genericclioptions.IOStreams, globalOpts *globalflags.GlobalOptions) *replayOptions {
+	return &replayOptions{
+		IOStreams:     streams,
+		GlobalOptions: globalOpts,
+	}
+}
+
+func (o *replayOptions) complete(cmd *cobra.Command, _ []string) error {
+	if o.historyFile == "" {
+		path, err := historyFilePath()
+		if err != nil {
+			return fmt.Errorf("cannot determine the local history file: %w", err)
+		}
+		o.historyFile = path
+	}
+	if o.clusterID != "" {
+		if err := ctlutil.IsValidClusterKey(o.clusterID); err != nil {
+			return cmdutil.UsageErrorf(cmd, err.Error())
+		}
+	}
+	return nil
+}
+
+// selectEntries returns the history entries matching the --cluster-id and --incident-id filters
+func (o *replayOptions) selectEntries(entries []historyEntry) []historyEntry {
+	var selected []historyEntry
+	for _, entry := range entries {
+		if o.clusterID != "" && entry.ClusterID != o.clusterID {
+			continue
+		}
+		if o.incidentID != "" && entry.IncidentID != o.incidentID {
+			continue
+		}
+		selected = append(selected, entry)
+	}
+	return selected
+}
+
+func (o *replayOptions) run() error {
+	file, err := os.Open(filepath.Clean(o.historyFile))
+	if err != nil {
+		return fmt.Errorf("cannot read history file %s: %w", o.historyFile, err)
+	}
+	defer file.Close()
+
+	entries, err := readHistory(file)
+	if err != nil {
+		return fmt.Errorf("cannot parse history file %s: %w", o.historyFile, err)
+	}
+	entries = o.selectEntries(entries)
+	if len(entries) == 0 {
+		return fmt.Errorf("no reasons recorded in %s match the given filters", o.historyFile)
+	}
+
+	allowlist, err := loadReasonAllowlist()
+	if err != nil {
+		return err
+	}
+	// Always preview everything which is about to be re-posted
+	for _, entry := range entries {
+		reason, err := entry.limitedSupportReason()
+		if err != nil {
+			return fmt.Errorf("cannot rebuild reason %s: %w", entry.ReasonID, err)
+		}
+		if err := allowlist.check(reason); err != nil {
+			return fmt.Errorf("cannot re-post reason %s: %w", entry.ReasonID, err)
+		}
+		fmt.Printf("Originally posted to %s on %s as %s:\n", entry.ClusterID, entry.Timestamp.Format("2006-01-02 15:04:05 MST"), entry.ReasonID)
+		if err := printLimitedSupportReason(os.Stdout, reason); err != nil {
+			return fmt.Errorf("failed to print limited support reason: %w", err)
+		}
+	}
+
+	// Stop here if dry-run
+	if o.isDryRun {
+		return nil
+	}
+
+	fmt.Printf("%d limited support reason(s) will be posted again\n", len(entries))
+	if ok, err := confirm(); !ok {
+		return err
+	}
+
+	connection, err := createConnection()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := connection.Close(); err != nil {
+			fmt.Printf("Cannot close the connection: %q\n", err)
+			os.Exit(1)
+		}
+	}()
+
+	return replayEntries(connection, entries)
+}
+
+func replayEntries(connection *sdk.Connection, entries []historyEntry) error {
+	var failed int
+	for _, entry := range entries {
+		if err := replayEntry(connection, entry); err != nil {
+			failed++
+			logger().Error("Failed to re-post reason", "reason_id", entry.ReasonID, "cluster", entry.ClusterID, "error", err)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to re-post %d of %d reason(s)", failed, len(entries))
+	}
+	return nil
+}
+
+// replayEntry posts the reason recorded by the entry again, and records the new reason in the history
+func replayEntry(connection *sdk.Connection, entry historyEntry) error {
+	reason, err := entry.limitedSupportReason()
+	if err != nil {
+		return err
+	}
+	response, err := sendLimitedSupportPostRequest(context.TODO(), connection, entry.ClusterID, reason)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Re-posted reason %s to %s with ID %s\n", entry.ReasonID, entry.ClusterID, response.Body().ID())
+
+	replayed := entry
+	replayed.Timestamp = time.Now().UTC()
+	replayed.ReasonID = response.Body().ID()
+	if err := appendHistory(replayed); err != nil {
+		logger().Warn("Could not record the reason in the local history", "error", err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package support
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	ocmerrors "github.com/openshift-online/ocm-sdk-go/errors"
+	slv1 "github.com/openshift-online/ocm-sdk-go/servicelogs/v1"
+	ctlutil "github.com/openshift/osdctl/pkg/utils"
+)
+
+// serviceLogIOSuffix follows the cluster ID in the --save-io file names of the internal service log
+const serviceLogIOSuffix = "-servicelog"
+
+// saveIO records the body sent to OCM and the one received in --save-io, as DIR/<name>-request.json and
+// DIR/<name>-response.json, where name is the cluster ID, followed by serviceLogIOSuffix for the internal
+// service log. postErr is recorded as the response when the post failed without a reply. The post already
+// happened, so failing to save is only a warning.
+func (p *Post) saveIO(name string, request []byte, response []byte, postErr error) {
+	if p.saveIODir == "" {
+		return
+	}
+	if postErr != nil && len(bytes.TrimSpace(response)) == 0 {
+		response = errorBody(postErr)
+	}
+	if err := writeIOFiles(p.saveIODir, name, request, response); err != nil {
+		logger().Warn("Could not save the request and response", "name", name, "error", err)
+	}
+}
+
+// capturedBodies returns the bodies of the last attempt to send a request, as sent and received. The request
+// defaults to the body which was to be sent, when it didn't reach the connection.
+func capturedBodies(capture *ctlutil.BodyCapture, request []byte) ([]byte, []byte) {
+	sent, received := capture.Bodies()
+	if sent == nil {
+		sent = request
+	}
+	return sent, received
+}
+
+// writeIOFiles writes the request and response files of the name, readable by the current user only
+func writeIOFiles(dir string, name string, request []byte, response []byte) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("cannot create the directory %s: %w", dir, err)
+	}
+	// Cluster keys are validated, the base name is only a safety net against escaping the directory
+	base := filepath.Join(dir, filepath.Base(name))
+	for path, content := range map[string][]byte{base + "-request.json": request, base + "-response.json": response} {
+		if err := os.WriteFile(filepath.Clean(path), append(bytes.TrimSpace(content), '\n'), 0600); err != nil {
+			return fmt.Errorf("cannot write %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// marshalReason returns the JSON body sent to OCM for the reason
+func marshalReason(reason *cmv1.LimitedSupportReason) []byte {
+	buf := bytes.Buffer{}
+	if err := cmv1.MarshalLimitedSupportReason(reason, &buf); err != nil {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// marshalServiceLog returns the JSON body sent to OCM for the service log
+func marshalServiceLog(log *slv1.LogEntry) []byte {
+	buf := bytes.Buffer{}
+	if err := slv1.MarshalLogEntry(log, &buf); err != nil {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// errorBody returns the error OCM replied with, or the error itself when the request didn't get a reply
+func errorBody(err error) []byte {
+	var ocmErr *ocmerrors.Error
+	if errors.As(err, &ocmErr) {
+		buf := bytes.Buffer{}
+		if marshalErr := ocmerrors.MarshalError(ocmErr, &buf); marshalErr == nil {
+			return buf.Bytes()
+		}
+	}
+	body, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return body
+}
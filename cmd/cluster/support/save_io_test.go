@@ -0,0 +1,99 @@
+package support
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/spf13/viper"
+)
+
+func Test_saveIO(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "io")
+	p := &Post{saveIODir: dir}
+
+	p.saveIO("cluster-a", []byte(`{"summary":"Summary"}`), []byte(`{"id":"reason-1"}`), nil)
+	p.saveIO("cluster-b", []byte(`{"summary":"Summary"}`), nil, errors.New("connection refused"))
+
+	want := map[string]string{
+		"cluster-a-request.json":  "{\"summary\":\"Summary\"}\n",
+		"cluster-a-response.json": "{\"id\":\"reason-1\"}\n",
+		"cluster-b-request.json":  "{\"summary\":\"Summary\"}\n",
+	}
+	for name, content := range want {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("%s = %q, want %q", name, data, content)
+		}
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("%s has mode %v, want 0600", name, info.Mode().Perm())
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "cluster-b-response.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failure map[string]string
+	if err := json.Unmarshal(data, &failure); err != nil || failure["error"] != "connection refused" {
+		t.Errorf("cluster-b-response.json = %s, want the post error", data)
+	}
+}
+
+func Test_saveIOReceivedBodies(t *testing.T) {
+	viper.Set(HistoryFileKey, filepath.Join(t.TempDir(), "history.jsonl"))
+	defer viper.Set(HistoryFileKey, "")
+
+	// The replies have fields and formatting the SDK would drop when marshaling them back
+	const reasonReply = `{ "kind": "LimitedSupportReason", "id": "reason-1", "summary": "Summary", "new_field": "kept" }`
+	const serviceLogReply = `{ "kind": "ClusterLog", "id": "log-1", "new_field": "kept" }`
+	_, connection := newFakeOCM(t, map[string]http.HandlerFunc{
+		"POST /api/clusters_mgmt/v1/clusters/cluster-id/limited_support_reasons": respond(http.StatusCreated, reasonReply),
+		"POST /api/service_logs/v1/cluster_logs":                                 respond(http.StatusCreated, serviceLogReply),
+	})
+	cluster, err := cmv1.NewCluster().ID("cluster-id").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	p := &Post{
+		Template:        "template.json",
+		templateBytes:   []byte(`{"summary":"Summary","details":"Details","detection_type":"manual"}`),
+		Evidence:        "OHSS-1234",
+		cluster:         cluster,
+		clusterPrepared: true,
+		saveIODir:       dir,
+	}
+	if _, err := p.postToCluster(connection, "cluster-id", nil, false); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	if got := read("cluster-id-response.json"); got != reasonReply+"\n" {
+		t.Errorf("cluster-id-response.json = %q, want the body received", got)
+	}
+	if got := read("cluster-id-request.json"); !strings.Contains(got, `"summary": "Summary"`) {
+		t.Errorf("cluster-id-request.json = %q, want the body sent", got)
+	}
+	if got := read("cluster-id-servicelog-response.json"); got != serviceLogReply+"\n" {
+		t.Errorf("cluster-id-servicelog-response.json = %q, want the body received", got)
+	}
+	if got := read("cluster-id-servicelog-request.json"); !strings.Contains(got, "reason-1 - OHSS-1234") {
+		t.Errorf("cluster-id-servicelog-request.json = %q, want the service log sent", got)
+	}
+}
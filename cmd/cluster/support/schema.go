@@ -0,0 +1,221 @@
+package support
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	ctlutil "github.com/openshift/osdctl/pkg/utils"
+)
+
+const (
+	// clustersMgmtOpenAPIPath serves the OpenAPI specification of the OCM version the connection talks to
+	clustersMgmtOpenAPIPath = "/api/clusters_mgmt/v1/openapi"
+	// reasonSchemaName is the OpenAPI schema of limited support reasons
+	reasonSchemaName = "LimitedSupportReason"
+
+	// schemaCacheFileName is keyed by the OCM API URL, so that the specifications of the OCM environments (eg.
+	// stage and production) are cached side by side
+	schemaCacheFileName = "osdctl-clusters-mgmt-openapi-%s.json"
+	// schemaCacheTTL is how long the fetched specification is used before being fetched again
+	schemaCacheTTL = 24 * time.Hour
+)
+
+// openAPISchema is the subset of an OpenAPI schema needed to validate the fields of a reason
+type openAPISchema struct {
+	Ref        string                   `json:"$ref"`
+	Type       string                   `json:"type"`
+	Enum       []string                 `json:"enum"`
+	ReadOnly   bool                     `json:"readOnly"`
+	Properties map[string]openAPISchema `json:"properties"`
+}
+
+type openAPISpec struct {
+	Components struct {
+		Schemas map[string]openAPISchema `json:"schemas"`
+	} `json:"components"`
+}
+
+// reasonSchema is the limited support reason schema of OCM, with its references resolved
+type reasonSchema struct {
+	properties map[string]openAPISchema
+}
+
+// parseReasonSchema extracts the limited support reason schema from an OpenAPI specification
+func parseReasonSchema(spec []byte) (*reasonSchema, error) {
+	var parsed openAPISpec
+	if err := json.Unmarshal(spec, &parsed); err != nil {
+		return nil, fmt.Errorf("cannot parse the OpenAPI specification: %w", err)
+	}
+	schema, ok := parsed.Components.Schemas[reasonSchemaName]
+	if !ok || len(schema.Properties) == 0 {
+		return nil, fmt.Errorf("the OpenAPI specification has no %s schema", reasonSchemaName)
+	}
+
+	properties := map[string]openAPISchema{}
+	for name, property := range schema.Properties {
+		// Only references to the schemas of the specification itself can be resolved
+		if ref := strings.TrimPrefix(property.Ref, "#/components/schemas/"); ref != property.Ref {
+			if resolved, ok := parsed.Components.Schemas[ref]; ok {
+				resolved.ReadOnly = resolved.ReadOnly || property.ReadOnly
+				property = resolved
+			}
+		}
+		properties[name] = property
+	}
+	return &reasonSchema{properties: properties}, nil
+}
+
+// validate checks the fields of the JSON reason against the schema and returns every violation
+func (s *reasonSchema) validate(body []byte) ([]string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, fmt.Errorf("the reason is not a JSON object: %w", err)
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []string
+	for _, name := range names {
+		property, ok := s.properties[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("field '%s' is not part of the OCM schema", name))
+			continue
+		}
+		// The SDK always sends its 'kind', OCM ignores the read-only fields it didn't set
+		if property.ReadOnly || name == "kind" {
+			continue
+		}
+		switch property.Type {
+		case "string":
+			var value string
+			if err := json.Unmarshal(fields[name], &value); err != nil {
+				problems = append(problems, fmt.Sprintf("field '%s' must be a string", name))
+				continue
+			}
+			if len(property.Enum) > 0 && !slices.Contains(property.Enum, value) {
+				problems = append(problems, fmt.Sprintf("field '%s' is %q, OCM accepts: %s", name, value, strings.Join(property.Enum, ", ")))
+			}
+		case "object":
+			if !strings.HasPrefix(strings.TrimSpace(string(fields[name])), "{") {
+				problems = append(problems, fmt.Sprintf("field '%s' must be an object", name))
+			}
+		}
+	}
+	return problems, nil
+}
+
+// schemaCachePath returns where the OpenAPI specification fetched from the OCM API URL is cached
+func schemaCachePath(apiURL string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(strings.TrimSuffix(apiURL, "/")))
+	return filepath.Join(dir, "osdctl", fmt.Sprintf(schemaCacheFileName, hex.EncodeToString(sum[:8]))), nil
+}
+
+// loadOpenAPISpec returns the cached specification while it is fresh, and fetches it otherwise.
+// A stale cache is still used when fetching fails, so that the validation works offline.
+func loadOpenAPISpec(path string, now time.Time, fetch func() ([]byte, error)) ([]byte, error) {
+	cached, readErr := os.ReadFile(filepath.Clean(path))
+	if readErr == nil {
+		if info, err := os.Stat(path); err == nil && now.Sub(info.ModTime()) < schemaCacheTTL {
+			return cached, nil
+		}
+	}
+
+	spec, err := fetch()
+	if err != nil {
+		if readErr == nil {
+			logger().Warn("Could not fetch the OCM OpenAPI specification, using the cached one", "path", path, "error", err)
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	if err := writeSchemaCache(path, spec); err != nil {
+		logger().Warn("Could not cache the OCM OpenAPI specification", "path", path, "error", err)
+	}
+	return spec, nil
+}
+
+func writeSchemaCache(path string, spec []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, spec, 0600)
+}
+
+// fetchOpenAPISpec downloads the clusters_mgmt OpenAPI specification from OCM
+func fetchOpenAPISpec(connection *sdk.Connection) ([]byte, error) {
+	if connection == nil {
+		return nil, errors.New("there is no connection to OCM")
+	}
+	response, err := ctlutil.SendRequest(connection.Get().Path(clustersMgmtOpenAPIPath))
+	if err != nil {
+		return nil, err
+	}
+	if response.Status() != http.StatusOK {
+		return nil, fmt.Errorf("OCM replied with HTTP status %d", response.Status())
+	}
+	return response.Bytes(), nil
+}
+
+// loadReasonSchema returns the limited support reason schema of OCM, once per run.
+// It is nil when the schema is unavailable, the reason is then only validated locally.
+func (p *Post) loadReasonSchema(connection *sdk.Connection) *reasonSchema {
+	if p.schemaLoaded {
+		return p.schema
+	}
+	p.schemaLoaded = true
+
+	apiURL := ""
+	if connection != nil {
+		apiURL = connection.URL()
+	}
+	path, err := schemaCachePath(apiURL)
+	if err != nil {
+		logger().Warn("Cannot locate the OCM schema cache, falling back to local validation", "error", err)
+		return nil
+	}
+	spec, err := loadOpenAPISpec(path, time.Now(), func() ([]byte, error) { return fetchOpenAPISpec(connection) })
+	if err == nil {
+		p.schema, err = parseReasonSchema(spec)
+	}
+	if err != nil {
+		logger().Warn("The OCM schema is unavailable, falling back to local validation", "error", err)
+	}
+	return p.schema
+}
+
+// validateAgainstSchema checks the JSON reason against the schema of OCM for --validate-schema
+func (p *Post) validateAgainstSchema(connection *sdk.Connection, body []byte) error {
+	schema := p.loadReasonSchema(connection)
+	if schema == nil {
+		return nil
+	}
+	problems, err := schema.validate(body)
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("the reason does not match the OCM schema:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	fmt.Fprintln(p.previewOut(), "The reason matches the OCM schema")
+	return nil
+}
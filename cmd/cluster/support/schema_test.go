@@ -0,0 +1,128 @@
+package support
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testOpenAPISpec = `{
+  "components": {
+    "schemas": {
+      "DetectionType": {"type": "string", "enum": ["auto", "manual"]},
+      "LimitedSupportReason": {
+        "properties": {
+          "kind": {"type": "string"},
+          "id": {"type": "string", "readOnly": true},
+          "summary": {"type": "string"},
+          "details": {"type": "string"},
+          "detection_type": {"$ref": "#/components/schemas/DetectionType"},
+          "override": {"type": "object"}
+        }
+      }
+    }
+  }
+}`
+
+func Test_reasonSchema(t *testing.T) {
+	schema, err := parseReasonSchema([]byte(testOpenAPISpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			name: "valid reason",
+			body: `{"kind":"LimitedSupportReason","id":"1","summary":"Summary","details":"Details","detection_type":"manual"}`,
+		},
+		{
+			name: "unknown field",
+			body: `{"summary":"Summary","severity":"Major"}`,
+			want: []string{"field 'severity' is not part of the OCM schema"},
+		},
+		{
+			name: "invalid enum and types",
+			body: `{"detection_type":"other","override":"yes","summary":1}`,
+			want: []string{
+				`field 'detection_type' is "other", OCM accepts: auto, manual`,
+				"field 'override' must be an object",
+				"field 'summary' must be a string",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := schema.validate([]byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseReasonSchema([]byte(`{"components":{"schemas":{}}}`)); err == nil {
+		t.Error("parseReasonSchema() should fail without a LimitedSupportReason schema")
+	}
+}
+
+func Test_loadOpenAPISpec(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", fmt.Sprintf(schemaCacheFileName, "key"))
+	fetched := 0
+	fetch := func() ([]byte, error) {
+		fetched++
+		return []byte("fetched"), nil
+	}
+	now := time.Now()
+
+	// Nothing is cached yet
+	if spec, err := loadOpenAPISpec(path, now, fetch); err != nil || string(spec) != "fetched" || fetched != 1 {
+		t.Fatalf("loadOpenAPISpec() = %q, %v after %d fetches", spec, err, fetched)
+	}
+	// The fresh cache is used
+	if err := os.WriteFile(path, []byte("cached"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if spec, err := loadOpenAPISpec(path, now, fetch); err != nil || string(spec) != "cached" || fetched != 1 {
+		t.Errorf("loadOpenAPISpec() = %q, %v after %d fetches, want the cached spec", spec, err, fetched)
+	}
+	// A stale cache is fetched again, and used when fetching fails
+	later := now.Add(2 * schemaCacheTTL)
+	if spec, err := loadOpenAPISpec(path, later, fetch); err != nil || string(spec) != "fetched" || fetched != 2 {
+		t.Errorf("loadOpenAPISpec() = %q, %v after %d fetches, want a fetched spec", spec, err, fetched)
+	}
+	failing := func() ([]byte, error) { return nil, errors.New("unreachable") }
+	if spec, err := loadOpenAPISpec(path, later, failing); err != nil || string(spec) != "fetched" {
+		t.Errorf("loadOpenAPISpec() = %q, %v, want the stale cache", spec, err)
+	}
+	if _, err := loadOpenAPISpec(filepath.Join(t.TempDir(), "missing"), now, failing); err == nil {
+		t.Error("loadOpenAPISpec() should fail without a cache nor OCM")
+	}
+}
+
+func Test_schemaCachePath(t *testing.T) {
+	stage, err := schemaCachePath("https://api.stage.openshift.com")
+	if err != nil {
+		t.Skipf("no user cache directory: %v", err)
+	}
+	production, err := schemaCachePath("https://api.openshift.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The OCM environments don't share their specification
+	if stage == production {
+		t.Errorf("schemaCachePath() = %q for both stage and production", stage)
+	}
+	if again, _ := schemaCachePath("https://api.stage.openshift.com/"); again != stage {
+		t.Errorf("schemaCachePath() = %q, want %q for the same API URL", again, stage)
+	}
+}
@@ -4,6 +4,7 @@ import sdk "github.com/openshift-online/ocm-sdk-go"
 
 type SDKConnection interface {
 	Post() *sdk.Request
+	Patch() *sdk.Request
 	Delete() *sdk.Request
 }
 
@@ -21,6 +22,11 @@ func (m *MockClient) Post() *sdk.Request {
 	return &sdk.Request{}
 }
 
+// Mock PATCH request to the API for unit tests
+func (m *MockClient) Patch() *sdk.Request {
+	return &sdk.Request{}
+}
+
 // Mock Delete request to the API for unit tests
 func (m *MockClient) Delete() *sdk.Request {
 	return &sdk.Request{}
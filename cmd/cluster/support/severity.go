@@ -0,0 +1,64 @@
+package support
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/spf13/viper"
+)
+
+// SeverityDetectionTypesKey can be set in the osdctl configuration file to a map of severities to detection
+// types, which adds to and overrides defaultSeverityDetectionTypes
+const SeverityDetectionTypesKey = "support_severity_detection_types"
+
+// severityPlaceholder is the template parameter whose value selects the detection_type of the reason, eg. with
+// '-p SEVERITY=critical'
+const severityPlaceholder = "${SEVERITY}"
+
+// defaultSeverityDetectionTypes sends the reasons an SRE has to look into as manual, and the others as auto
+var defaultSeverityDetectionTypes = map[string]cmv1.DetectionType{
+	"critical": cmv1.DetectionTypeManual,
+	"major":    cmv1.DetectionTypeManual,
+	"minor":    cmv1.DetectionTypeAuto,
+	"warning":  cmv1.DetectionTypeAuto,
+	"info":     cmv1.DetectionTypeAuto,
+}
+
+// severityDetectionTypes returns the mapping of severities to detection types, with the overrides of the
+// osdctl configuration
+func severityDetectionTypes() (map[string]cmv1.DetectionType, error) {
+	mapping := make(map[string]cmv1.DetectionType, len(defaultSeverityDetectionTypes))
+	for severity, detectionType := range defaultSeverityDetectionTypes {
+		mapping[severity] = detectionType
+	}
+	for severity, detectionType := range viper.GetStringMapString(SeverityDetectionTypesKey) {
+		switch cmv1.DetectionType(detectionType) {
+		case cmv1.DetectionTypeManual, cmv1.DetectionTypeAuto:
+			mapping[strings.ToLower(severity)] = cmv1.DetectionType(detectionType)
+		default:
+			return nil, fmt.Errorf("severity %q of '%s' in the osdctl configuration file maps to %q, it must be '%s' or '%s'",
+				severity, SeverityDetectionTypesKey, detectionType, cmv1.DetectionTypeManual, cmv1.DetectionTypeAuto)
+		}
+	}
+	return mapping, nil
+}
+
+// severityDetectionType returns the detection type of the severity, which is case insensitive
+func severityDetectionType(severity string) (cmv1.DetectionType, error) {
+	mapping, err := severityDetectionTypes()
+	if err != nil {
+		return "", err
+	}
+	if detectionType, ok := mapping[strings.ToLower(severity)]; ok {
+		return detectionType, nil
+	}
+	severities := make([]string, 0, len(mapping))
+	for known := range mapping {
+		severities = append(severities, known)
+	}
+	sort.Strings(severities)
+	return "", fmt.Errorf("unknown severity %q, the known severities are %s. Severities can be added with '%s' in the osdctl configuration file",
+		severity, strings.Join(severities, ", "), SeverityDetectionTypesKey)
+}
@@ -0,0 +1,79 @@
+package support
+
+import (
+	"testing"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/spf13/viper"
+)
+
+func Test_buildLimitedSupportTemplateSeverity(t *testing.T) {
+	viper.Set(SeverityDetectionTypesKey, map[string]string{"Minor": "manual", "page": "manual"})
+	defer viper.Set(SeverityDetectionTypesKey, nil)
+
+	tests := []struct {
+		name     string
+		params   []string
+		template string
+		want     cmv1.DetectionType
+		wantErr  bool
+	}{
+		{
+			name:     "The template's detection_type is kept without a severity",
+			template: `{"summary":"Summary","details":"Details","detection_type":"auto"}`,
+			want:     cmv1.DetectionTypeAuto,
+		},
+		{
+			name:     "A default severity overrides the template",
+			params:   []string{"SEVERITY=Critical"},
+			template: `{"summary":"Summary","details":"Details","detection_type":"auto"}`,
+			want:     cmv1.DetectionTypeManual,
+		},
+		{
+			name:     "The configuration overrides the defaults",
+			params:   []string{"SEVERITY=minor"},
+			template: `{"summary":"Summary","details":"Details","detection_type":"auto"}`,
+			want:     cmv1.DetectionTypeManual,
+		},
+		{
+			name:     "The configuration adds severities",
+			params:   []string{"SEVERITY=page"},
+			template: `{"summary":"Summary","details":"Details"}`,
+			want:     cmv1.DetectionTypeManual,
+		},
+		{
+			name:     "Templates can use the severity in their details",
+			params:   []string{"SEVERITY=info"},
+			template: `{"summary":"Summary","details":"Severity: ${SEVERITY}","detection_type":"manual"}`,
+			want:     cmv1.DetectionTypeAuto,
+		},
+		{
+			name:     "Rejects an unknown severity",
+			params:   []string{"SEVERITY=unknown"},
+			template: `{"summary":"Summary","details":"Details"}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Post{Template: "template.json", TemplateParams: tt.params, templateBytes: []byte(tt.template)}
+			reason, err := p.buildLimitedSupportTemplate(nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildLimitedSupportTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && reason.DetectionType() != tt.want {
+				t.Errorf("detection_type = %q, want %q", reason.DetectionType(), tt.want)
+			}
+		})
+	}
+}
+
+func Test_severityDetectionTypesInvalidConfiguration(t *testing.T) {
+	viper.Set(SeverityDetectionTypesKey, map[string]string{"critical": "urgent"})
+	defer viper.Set(SeverityDetectionTypesKey, nil)
+
+	if _, err := severityDetectionType("critical"); err == nil {
+		t.Errorf("severityDetectionType() expected an error for a detection type which isn't manual or auto")
+	}
+}
@@ -0,0 +1,144 @@
+package support
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// batchCompleted is true for the statuses of the clusters which mustn't be posted to again when an interrupted
+// batch is resumed
+func batchCompleted(status string) bool {
+	return status == batchPosted || status == batchSkipped
+}
+
+// stateRecord is a line of a --state-file, written once a cluster of the batch is done
+type stateRecord struct {
+	// Key is the cluster as selected for the batch, which may be a name or an external ID
+	Key       string    `json:"key"`
+	ClusterID string    `json:"cluster_id"`
+	Status    string    `json:"status"`
+	ReasonID  string    `json:"reason_id,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// batchState is the --state-file of a batch: the clusters which are done are appended to it one line at a
+// time, so that running the batch again skips them.
+type batchState struct {
+	path string
+	// done has both the keys and the IDs of the clusters, so that a cluster selected by its name in one run
+	// is still skipped when selected by its ID in the next one
+	done map[string]bool
+}
+
+// loadBatchState reads the clusters which are done from the state file, which may not exist yet
+func loadBatchState(path string) (*batchState, error) {
+	state := &batchState{path: path, done: map[string]bool{}}
+	file, err := os.Open(filepath.Clean(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the state file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var record stateRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			// The last line is partial when the batch was killed while writing it, the cluster is then
+			// posted to again
+			logger().Warn("Ignoring a line of the state file which cannot be parsed", "state_file", fmt.Sprintf("%s:%d", path, lineNumber), "error", err)
+			continue
+		}
+		if batchCompleted(record.Status) {
+			state.done[record.Key] = true
+			state.done[record.ClusterID] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read the state file %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// record appends the cluster to the state file once it is done. The line is synced to disk before the
+// next cluster is processed.
+func (s *batchState) record(key string, result batchResult) error {
+	if !batchCompleted(result.Status) {
+		return nil
+	}
+	data, err := json.Marshal(stateRecord{Key: key, ClusterID: result.ClusterID, Status: result.Status, ReasonID: result.ReasonID, Time: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("cannot open the state file %s: %w", s.path, err)
+	}
+	defer file.Close()
+	// A batch killed while writing leaves a partial line behind, the record mustn't be merged into it
+	partial, err := endsWithPartialLine(file)
+	if err != nil {
+		return fmt.Errorf("cannot read the state file %s: %w", s.path, err)
+	}
+	if partial {
+		data = append([]byte{'\n'}, data...)
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("cannot write to the state file %s: %w", s.path, err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("cannot write to the state file %s: %w", s.path, err)
+	}
+	s.done[key] = true
+	s.done[result.ClusterID] = true
+	return nil
+}
+
+// endsWithPartialLine is true when the last line of the file isn't terminated by a newline
+func endsWithPartialLine(file *os.File) (bool, error) {
+	info, err := file.Stat()
+	if err != nil || info.Size() == 0 {
+		return false, err
+	}
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, info.Size()-1); err != nil {
+		return false, err
+	}
+	return last[0] != '\n', nil
+}
+
+// stateSource skips the clusters of the wrapped source which are done according to the state file
+type stateSource struct {
+	clusterSource
+	state *batchState
+	// resumed counts the clusters which were skipped
+	resumed int
+}
+
+func (s *stateSource) next() (*clusterEntry, error) {
+	for {
+		entry, err := s.clusterSource.next()
+		if err != nil {
+			return entry, err
+		}
+		if s.state.done[entry.ClusterID] {
+			s.resumed++
+			continue
+		}
+		return entry, nil
+	}
+}
@@ -0,0 +1,80 @@
+package support
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_batchStateRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.jsonl")
+	state, err := loadBatchState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, result := range map[string]batchResult{
+		"cluster-name": {ClusterID: "cluster-id", Status: batchPosted, ReasonID: "reason"},
+		"skipped":      {ClusterID: "skipped-id", Status: batchSkipped},
+		"failed":       {ClusterID: "failed-id", Status: batchFailed},
+		"rendered":     {ClusterID: "rendered-id", Status: batchRendered},
+	} {
+		if err := state.record(key, result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A batch killed while writing leaves a partial line behind
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteString(`{"key":"partial","status":"pos`); err != nil {
+		t.Fatal(err)
+	}
+	_ = file.Close()
+
+	// The batch is resumed, the cluster done after the partial line is still recorded
+	resumed, err := loadBatchState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := resumed.record("after-resume", batchResult{ClusterID: "after-resume-id", Status: batchPosted}); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := loadBatchState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"cluster-name", "cluster-id", "skipped", "skipped-id", "after-resume", "after-resume-id"} {
+		if !reloaded.done[key] {
+			t.Errorf("%s isn't done after reloading the state file", key)
+		}
+	}
+	for _, key := range []string{"failed", "failed-id", "rendered", "rendered-id", "partial"} {
+		if reloaded.done[key] {
+			t.Errorf("%s is done after reloading the state file, it should be posted to again", key)
+		}
+	}
+}
+
+func Test_runBatchStateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.jsonl")
+	if err := os.WriteFile(path, []byte(`{"key":"done-by-name","cluster_id":"done-id","status":"posted"}`+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// The clusters which are done would fail, as they don't set the parameter of the template
+	source := &sliceSource{origin: "for the test", entries: []*clusterEntry{
+		{ClusterID: "done-by-name"},
+		{ClusterID: "remaining", Params: map[string]string{"NAME": "foo"}},
+		{ClusterID: "done-id"},
+	}}
+	p := &Post{
+		Template:      "template.json",
+		isDryRun:      true,
+		StateFile:     path,
+		templateBytes: []byte(`{"summary":"Summary","details":"Remove ${NAME}","detection_type":"manual"}`),
+	}
+	if err := p.runBatch(nil, source); err != nil {
+		t.Errorf("runBatch() = %v, want the clusters recorded as done to be skipped", err)
+	}
+}
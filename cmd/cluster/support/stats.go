@@ -0,0 +1,255 @@
+package support
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/openshift/osdctl/internal/utils/globalflags"
+	"github.com/openshift/osdctl/pkg/printer"
+	ctlutil "github.com/openshift/osdctl/pkg/utils"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+type statsOptions struct {
+	output         string
+	clusterIDsFile string
+	search         string
+	concurrency    int
+	// columns are the names of the columns given with --columns, table holds them once resolved
+	columns []string
+	table   columnSet[statsRow]
+
+	genericclioptions.IOStreams
+	GlobalOptions *globalflags.GlobalOptions
+}
+
+// fleetStats summarizes the limited support state of a fleet of clusters
+type fleetStats struct {
+	Clusters                 int          `json:"clusters"`
+	ClustersInLimitedSupport int          `json:"clusters_in_limited_support"`
+	FailedClusters           []string     `json:"failed_clusters,omitempty"`
+	ByDetectionType          []statsCount `json:"by_detection_type"`
+	BySummary                []statsCount `json:"by_summary"`
+}
+
+// statsCount is the number of clusters having at least one reason with the given key
+type statsCount struct {
+	Key      string `json:"key"`
+	Clusters int    `json:"clusters"`
+}
+
+// newCmdstats implements the stats command to summarize the limited support state of a fleet of clusters
+func newCmdstats(streams genericclioptions.IOStreams, globalOpts *globalflags.GlobalOptions) *cobra.Command {
+	ops := newStatsOptions(streams, globalOpts)
+	statsCmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Count the clusters in limited support, grouped by detection type and by reason summary",
+		Example: `  # Summarize the clusters listed in clusters.txt
+  osdctl cluster support stats --cluster-ids-file clusters.txt
+
+  # Summarize every ready OSD cluster, as JSON
+  osdctl cluster support stats --search "product.id='osd' and state='ready'" -o json
+
+  # Print the counts as a single table, with the share of the clusters in limited support
+  osdctl cluster support stats --cluster-ids-file clusters.txt -o table --columns key,clusters,percent`,
+		Args:              cobra.NoArgs,
+		DisableAutoGenTag: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(ops.complete(cmd, args))
+			cmdutil.CheckErr(ops.run())
+		},
+	}
+
+	statsCmd.Flags().StringVar(&ops.clusterIDsFile, "cluster-ids-file", "", "File listing one cluster ID, name or external ID per line. Empty lines and lines starting with '#' are ignored")
+	statsCmd.Flags().StringVar(&ops.search, "search", "", "OCM cluster search query selecting the clusters to summarize (eg. \"product.id='osd'\")")
+	statsCmd.Flags().StringSliceVar(&ops.columns, "columns", nil, fmt.Sprintf("Comma separated columns of the '-o table' output, which lists the counts by detection type and by summary in a single table. Available columns: %s", statsColumns.names()))
+	statsCmd.Flags().IntVar(&ops.concurrency, "concurrency", defaultExportConcurrency, "Number of clusters to query in parallel")
+
+	return statsCmd
+}
+
+func newStatsOptions(streams genericclioptions.IOStreams, globalOpts *globalflags.GlobalOptions) *statsOptions {
+	return &statsOptions{
+		IOStreams:     streams,
+		GlobalOptions: globalOpts,
+	}
+}
+
+func (o *statsOptions) complete(cmd *cobra.Command, _ []string) error {
+	if (o.clusterIDsFile == "") == (o.search == "") {
+		return cmdutil.UsageErrorf(cmd, "Provide exactly one of --cluster-ids-file and --search")
+	}
+	if o.concurrency < 1 {
+		return cmdutil.UsageErrorf(cmd, "--concurrency must be at least 1")
+	}
+
+	o.output = o.GlobalOptions.Output
+	if err := checkColumnsOutput(o.output, o.columns); err != nil {
+		return cmdutil.UsageErrorf(cmd, "%s", err)
+	}
+	// --columns alone selects the table output
+	if len(o.columns) > 0 {
+		o.output = tableOutput
+	}
+	table, err := statsColumns.pick(o.columns, "group", "key", "clusters")
+	if err != nil {
+		return cmdutil.UsageErrorf(cmd, "%s", err)
+	}
+	o.table = table
+
+	return nil
+}
+
+func (o *statsOptions) run() error {
+	var clusterKeys []string
+	if o.clusterIDsFile != "" {
+		var err error
+		clusterKeys, err = readClusterIDsFile(o.clusterIDsFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	//create connection to sdk
+	connection, err := createConnection()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := connection.Close(); err != nil {
+			fmt.Printf("Cannot close the connection: %q\n", err)
+			os.Exit(1)
+		}
+	}()
+
+	if o.search != "" {
+		var clusters []*cmv1.Cluster
+		err := retryOCM(nil, func() error {
+			var err error
+			clusters, err = ctlutil.ApplyFilters(connection, []string{o.search})
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("cannot search clusters: %w", err)
+		}
+		for _, cluster := range clusters {
+			clusterKeys = append(clusterKeys, cluster.ID())
+		}
+	}
+	if len(clusterKeys) == 0 {
+		return errors.New("no clusters to summarize")
+	}
+
+	stats, err := computeStats(exportClusters(connection, clusterKeys, o.concurrency))
+	if err != nil {
+		return err
+	}
+
+	if o.output == "json" {
+		encoder := json.NewEncoder(o.Out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(stats)
+	}
+	if o.output == tableOutput {
+		return o.table.printTable(o.Out, statsRows(stats))
+	}
+	return printStats(stats)
+}
+
+// computeStats aggregates the exported reasons of every cluster. A cluster is counted once per detection
+// type and once per summary, however many reasons it has with them.
+func computeStats(clusters []clusterExport) (fleetStats, error) {
+	stats := fleetStats{Clusters: len(clusters)}
+	byDetectionType := map[string]int{}
+	bySummary := map[string]int{}
+
+	for _, cluster := range clusters {
+		if cluster.Error != "" {
+			stats.FailedClusters = append(stats.FailedClusters, cluster.ClusterKey)
+			continue
+		}
+		if len(cluster.Reasons) == 0 {
+			continue
+		}
+		stats.ClustersInLimitedSupport++
+
+		detectionTypes := map[string]bool{}
+		summaries := map[string]bool{}
+		for _, raw := range cluster.Reasons {
+			var reason struct {
+				Summary       string `json:"summary"`
+				DetectionType string `json:"detection_type"`
+			}
+			if err := json.Unmarshal(raw, &reason); err != nil {
+				return fleetStats{}, fmt.Errorf("cannot parse limited support reason of cluster %s: %w", cluster.ClusterKey, err)
+			}
+			detectionTypes[reason.DetectionType] = true
+			summaries[reason.Summary] = true
+		}
+		for detectionType := range detectionTypes {
+			byDetectionType[detectionType]++
+		}
+		for summary := range summaries {
+			bySummary[summary]++
+		}
+	}
+
+	stats.ByDetectionType = sortedCounts(byDetectionType)
+	stats.BySummary = sortedCounts(bySummary)
+	return stats, nil
+}
+
+// statsRows flattens the counts by detection type and by summary into the rows of the '-o table' output
+func statsRows(stats fleetStats) []statsRow {
+	rows := make([]statsRow, 0, len(stats.ByDetectionType)+len(stats.BySummary))
+	for _, count := range stats.ByDetectionType {
+		rows = append(rows, statsRow{group: "detection_type", statsCount: count, inLimitedSupport: stats.ClustersInLimitedSupport})
+	}
+	for _, count := range stats.BySummary {
+		rows = append(rows, statsRow{group: "summary", statsCount: count, inLimitedSupport: stats.ClustersInLimitedSupport})
+	}
+	return rows
+}
+
+// sortedCounts orders the counts from the most to the least common key
+func sortedCounts(counts map[string]int) []statsCount {
+	result := make([]statsCount, 0, len(counts))
+	for key, clusters := range counts {
+		result = append(result, statsCount{Key: key, Clusters: clusters})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Clusters != result[j].Clusters {
+			return result[i].Clusters > result[j].Clusters
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+func printStats(stats fleetStats) error {
+	fmt.Printf("%d of %d cluster(s) are in limited support\n", stats.ClustersInLimitedSupport, stats.Clusters)
+	if len(stats.FailedClusters) > 0 {
+		fmt.Printf("%d cluster(s) could not be queried: %v\n", len(stats.FailedClusters), stats.FailedClusters)
+	}
+
+	table := printer.NewTablePrinter(os.Stdout, 20, 1, 3, ' ')
+	table.AddRow([]string{"Detection Type", "Clusters"})
+	for _, count := range stats.ByDetectionType {
+		table.AddRow([]string{count.Key, fmt.Sprintf("%d", count.Clusters)})
+	}
+	// Add empty row for readability
+	table.AddRow([]string{})
+	table.AddRow([]string{"Summary", "Clusters"})
+	for _, count := range stats.BySummary {
+		table.AddRow([]string{count.Key, fmt.Sprintf("%d", count.Clusters)})
+	}
+	// Add empty row for readability
+	table.AddRow([]string{})
+	return table.Flush()
+}
@@ -0,0 +1,34 @@
+package support
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func Test_computeStats(t *testing.T) {
+	reason := func(summary, detectionType string) json.RawMessage {
+		return json.RawMessage(`{"summary":"` + summary + `","detection_type":"` + detectionType + `"}`)
+	}
+
+	stats, err := computeStats([]clusterExport{
+		{ClusterKey: "a", Reasons: []json.RawMessage{reason("cloud", "manual"), reason("cloud", "manual")}},
+		{ClusterKey: "b", Reasons: []json.RawMessage{reason("cloud", "auto"), reason("cluster", "manual")}},
+		{ClusterKey: "c", Reasons: []json.RawMessage{}},
+		{ClusterKey: "d", Error: "not found"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := fleetStats{
+		Clusters:                 4,
+		ClustersInLimitedSupport: 2,
+		FailedClusters:           []string{"d"},
+		ByDetectionType:          []statsCount{{Key: "manual", Clusters: 2}, {Key: "auto", Clusters: 1}},
+		BySummary:                []statsCount{{Key: "cloud", Clusters: 2}, {Key: "cluster", Clusters: 1}},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("computeStats() = %+v, want %+v", stats, want)
+	}
+}
@@ -1,11 +1,19 @@
 package support
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
+	amv1 "github.com/openshift-online/ocm-sdk-go/accountsmgmt/v1"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/openshift/osdctl/cmd/getoutput"
 	"github.com/openshift/osdctl/internal/utils/globalflags"
 	"github.com/openshift/osdctl/pkg/printer"
+	ctlutil "github.com/openshift/osdctl/pkg/utils"
 	"github.com/spf13/cobra"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 
@@ -13,9 +21,15 @@ import (
 )
 
 type statusOptions struct {
-	output    string
-	verbose   bool
-	clusterID string
+	output      string
+	verbose     bool
+	summaryOnly bool
+	clusterIDs  []string
+	// org lists the clusters of an organization which are in limited support
+	org string
+	// columns are the names of the columns given with --columns, table holds them once resolved
+	columns []string
+	table   columnSet[reasonRow]
 
 	genericclioptions.IOStreams
 	GlobalOptions *globalflags.GlobalOptions
@@ -25,9 +39,15 @@ type statusOptions struct {
 func newCmdstatus(streams genericclioptions.IOStreams, globalOpts *globalflags.GlobalOptions) *cobra.Command {
 	ops := newStatusOptions(streams, globalOpts)
 	statusCmd := &cobra.Command{
-		Use:               "status",
-		Short:             "Shows the support status of a specified cluster",
-		Args:              cobra.ExactArgs(1),
+		Use:     "status [CLUSTER_ID|-]",
+		Aliases: []string{"list"},
+		Short:   "Shows the support status of a specified cluster",
+		Long: `Shows the support status of a specified cluster. Cluster IDs can also be piped on stdin, one or more per line, by passing '-' or no cluster ID.
+With --org, lists every managed cluster of the organization which has limited support reasons.
+With '-o json' or '-o yaml', the reasons of each given cluster are printed as a document instead of a table.`,
+		Example: `  # List the clusters of an organization which are in limited support
+  osdctl cluster support list --org 1a2B3c4DefghIjkLMNOpQrSTUV5`,
+		Args:              cobra.MaximumNArgs(1),
 		DisableAutoGenTag: true,
 		Run: func(cmd *cobra.Command, args []string) {
 			cmdutil.CheckErr(ops.complete(cmd, args))
@@ -35,6 +55,9 @@ func newCmdstatus(streams genericclioptions.IOStreams, globalOpts *globalflags.G
 		},
 	}
 	statusCmd.Flags().BoolVarP(&ops.verbose, "verbose", "", false, "Verbose output")
+	statusCmd.Flags().BoolVar(&ops.summaryOnly, "summary-only", false, "Only print the cluster ID, reason ID and summary of each limited support reason")
+	statusCmd.Flags().StringSliceVar(&ops.columns, "columns", nil, fmt.Sprintf("Comma separated columns of the table, in order (eg. 'id,summary,created_at'). Available columns: %s", reasonColumns.names()))
+	statusCmd.Flags().StringVar(&ops.org, "org", "", "List the clusters of this OCM organization ID which have limited support reasons")
 
 	return statusCmd
 }
@@ -47,23 +70,91 @@ func newStatusOptions(streams genericclioptions.IOStreams, globalOpts *globalfla
 }
 
 func (o *statusOptions) complete(cmd *cobra.Command, args []string) error {
-	if len(args) != 1 {
+	o.output = o.GlobalOptions.Output
+	if err := checkColumnsOutput(o.output, o.columns); err != nil {
+		return cmdutil.UsageErrorf(cmd, "%s", err)
+	}
+	defaults := []string{"id", "summary", "details"}
+	if o.summaryOnly || o.org != "" {
+		defaults = []string{"cluster_id", "id", "summary"}
+	}
+	table, err := reasonColumns.pick(o.columns, defaults...)
+	if err != nil {
+		return cmdutil.UsageErrorf(cmd, "%s", err)
+	}
+	o.table = table
+
+	if o.org != "" {
+		if len(args) != 0 {
+			return cmdutil.UsageErrorf(cmd, "A cluster ID cannot be given together with --org")
+		}
+		// The organization ID is used in a search query, make sure it is safe
+		if !ctlutil.IsValidKey(o.org) {
+			return cmdutil.UsageErrorf(cmd, "Organization ID '%s' isn't valid: it must contain only letters, digits, dashes and underscores", o.org)
+		}
+		return nil
+	}
+
+	clusterIDs, err := clusterIDArgs(args, os.Stdin)
+	if err != nil {
+		return err
+	}
+	if len(clusterIDs) == 0 {
 		return cmdutil.UsageErrorf(cmd, "Provide exactly one cluster ID")
 	}
 
-	o.clusterID = args[0]
-	o.output = o.GlobalOptions.Output
+	o.clusterIDs = clusterIDs
 
 	return nil
 }
 
 func (o *statusOptions) run() error {
+	if o.org != "" {
+		return o.runOrg()
+	}
+
+	var errs []error
+	for _, clusterID := range o.clusterIDs {
+		if len(o.clusterIDs) > 1 && !o.summaryOnly && !isDocumentOutput(o.output) {
+			fmt.Printf("Cluster %s:\n", clusterID)
+		}
+		if err := o.runCluster(clusterID); err != nil {
+			if o.output == "json" {
+				reportError(o.output, clusterID, err)
+			}
+			errs = append(errs, fmt.Errorf("cluster %s: %w", clusterID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
 
-	clusterLimitedSupportReasons, err := getLimitedSupportReasons(o.clusterID)
+func (o *statusOptions) runCluster(clusterID string) error {
+	resolvedID, clusterLimitedSupportReasons, err := o.clusterReasons(clusterID)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to get limited support reasons: %v\n", err)
+		logger().Error("Failed to get limited support reasons", "cluster", clusterID, "error", err)
 		return err
 	}
+	return o.printReasons(resolvedID, clusterLimitedSupportReasons)
+}
+
+// clusterReasons returns the ID of the cluster along with its limited support reasons, without duplicates
+func (o *statusOptions) clusterReasons(clusterID string) (string, []*cmv1.LimitedSupportReason, error) {
+	cluster, reasons, err := getLimitedSupportReasons(clusterID)
+	if err != nil {
+		return "", nil, err
+	}
+	return cluster.ID(), dedupeReasons(cluster.ID(), reasons), nil
+}
+
+// printReasons prints the limited support reasons of the cluster as a document with '-o json' or '-o yaml',
+// or as a table of the selected columns otherwise
+func (o *statusOptions) printReasons(clusterID string, clusterLimitedSupportReasons []*cmv1.LimitedSupportReason) error {
+	if isDocumentOutput(o.output) {
+		if err := getoutput.PrintResponse(o.output, newReasonsOutput(clusterID, clusterLimitedSupportReasons)); err != nil {
+			return fmt.Errorf("failed to print the limited support reasons: %w", err)
+		}
+		return nil
+	}
 
 	// No reasons found, cluster is fully supported
 	if len(clusterLimitedSupportReasons) == 0 {
@@ -71,14 +162,11 @@ func (o *statusOptions) run() error {
 		return nil
 	}
 
-	table := printer.NewTablePrinter(os.Stdout, 20, 1, 3, ' ')
-	table.AddRow([]string{"Reason ID", "Summary", "Details"})
+	rows := make([]reasonRow, 0, len(clusterLimitedSupportReasons))
 	for _, clusterLimitedSupportReason := range clusterLimitedSupportReasons {
-		table.AddRow([]string{clusterLimitedSupportReason.ID(), clusterLimitedSupportReason.Summary(), clusterLimitedSupportReason.Details()})
+		rows = append(rows, reasonRow{clusterID: clusterID, reason: clusterLimitedSupportReason})
 	}
-	// Add empty row for readability
-	table.AddRow([]string{})
-	err = table.Flush()
+	err := o.table.printTable(os.Stdout, rows)
 	if err != nil {
 		fmt.Println("error while flushing table: ", err.Error())
 		return err
@@ -86,3 +174,157 @@ func (o *statusOptions) run() error {
 
 	return nil
 }
+
+// runOrg lists the managed clusters of the organization which have limited support reasons
+func (o *statusOptions) runOrg() error {
+	connection, err := createConnection()
+	if err != nil {
+		if o.output == "json" {
+			reportError(o.output, "", err)
+		}
+		return err
+	}
+	defer func() {
+		if err := connection.Close(); err != nil {
+			fmt.Printf("Cannot close the connection: %q\n", err)
+			os.Exit(1)
+		}
+	}()
+
+	var subscriptions []*amv1.Subscription
+	err = retryOCM(nil, func() error {
+		var err error
+		subscriptions, err = ctlutil.SearchSubscriptions(connection, fmt.Sprintf("organization_id='%s' and status='Active' and managed=true", o.org))
+		return err
+	})
+	if err != nil {
+		err = fmt.Errorf("cannot search the clusters of organization %s: %w", o.org, err)
+		if o.output == "json" {
+			reportError(o.output, "", err)
+		}
+		return err
+	}
+	var clusterKeys []string
+	for _, subscription := range subscriptions {
+		if clusterID := subscription.ClusterID(); clusterID != "" {
+			clusterKeys = append(clusterKeys, clusterID)
+		}
+	}
+
+	rows, failures := orgStatusRows(exportClusters(connection, clusterKeys, defaultExportConcurrency))
+	var errs []error
+	for _, failure := range failures {
+		if o.output == "json" {
+			reportError(o.output, failure.clusterKey, failure.err)
+		} else {
+			logger().Error("Failed to get limited support reasons", "cluster", failure.clusterKey, "error", failure.err)
+		}
+		errs = append(errs, fmt.Errorf("cluster %s: %w", failure.clusterKey, failure.err))
+	}
+	if len(rows) == 0 {
+		fmt.Printf("No cluster of organization %s is in limited support (%d cluster(s) checked)\n", o.org, len(clusterKeys))
+		return errors.Join(errs...)
+	}
+
+	if err := o.table.printTable(os.Stdout, rows); err != nil {
+		fmt.Println("error while flushing table: ", err.Error())
+		return err
+	}
+
+	return errors.Join(errs...)
+}
+
+// clusterFailure is a cluster of an organization whose limited support reasons couldn't be listed
+type clusterFailure struct {
+	clusterKey string
+	err        error
+}
+
+// reasonsOutput describes the limited support reasons of a cluster, it is printed according to '-o'
+type reasonsOutput struct {
+	ClusterID string         `json:"cluster_id" yaml:"cluster_id"`
+	Reasons   []reasonOutput `json:"limited_support_reasons" yaml:"limited_support_reasons"`
+}
+
+type reasonOutput struct {
+	ID        string    `json:"id" yaml:"id"`
+	Summary   string    `json:"summary" yaml:"summary"`
+	Details   string    `json:"details" yaml:"details"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+}
+
+func newReasonsOutput(clusterID string, reasons []*cmv1.LimitedSupportReason) reasonsOutput {
+	output := reasonsOutput{ClusterID: clusterID, Reasons: []reasonOutput{}}
+	for _, reason := range reasons {
+		output.Reasons = append(output.Reasons, reasonOutput{
+			ID:        reason.ID(),
+			Summary:   reason.Summary(),
+			Details:   reason.Details(),
+			CreatedAt: reason.CreationTimestamp(),
+		})
+	}
+	return output
+}
+
+func (o reasonsOutput) String() string {
+	if len(o.Reasons) == 0 {
+		return "Cluster is fully supported"
+	}
+	var buf bytes.Buffer
+	table := printer.NewTablePrinter(&buf, 20, 1, 3, ' ')
+	table.AddRow([]string{"Reason ID", "Summary", "Details"})
+	for _, reason := range o.Reasons {
+		table.AddRow([]string{reason.ID, reason.Summary, reason.Details})
+	}
+	_ = table.Flush()
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// isDocumentOutput tells whether '-o' prints a JSON or YAML document rather than text
+func isDocumentOutput(output string) bool {
+	return output == "json" || output == "yaml"
+}
+
+// orgStatusRows returns a row for each reason of the exported clusters, along with the clusters which
+// couldn't be queried
+func orgStatusRows(clusters []clusterExport) ([]reasonRow, []clusterFailure) {
+	var rows []reasonRow
+	var failures []clusterFailure
+	for _, cluster := range clusters {
+		if cluster.Error != "" {
+			failures = append(failures, clusterFailure{clusterKey: cluster.ClusterKey, err: errors.New(cluster.Error)})
+			continue
+		}
+		var reasons []*cmv1.LimitedSupportReason
+		for _, raw := range cluster.Reasons {
+			reason, err := cmv1.UnmarshalLimitedSupportReason([]byte(raw))
+			if err != nil {
+				failures = append(failures, clusterFailure{clusterKey: cluster.ClusterKey, err: fmt.Errorf("cannot parse limited support reason: %w", err)})
+				continue
+			}
+			reasons = append(reasons, reason)
+		}
+		for _, reason := range dedupeReasons(cluster.ClusterID, reasons) {
+			rows = append(rows, reasonRow{clusterID: cluster.ClusterID, reason: reason})
+		}
+	}
+	return rows, failures
+}
+
+// dedupeReasons drops the reasons whose ID was already listed, keeping the first of each. OCM may rarely return
+// the same reason twice, which would make audits count it twice.
+func dedupeReasons(clusterID string, reasons []*cmv1.LimitedSupportReason) []*cmv1.LimitedSupportReason {
+	seen := map[string]bool{}
+	deduped := make([]*cmv1.LimitedSupportReason, 0, len(reasons))
+	for _, reason := range reasons {
+		if seen[reason.ID()] {
+			continue
+		}
+		seen[reason.ID()] = true
+		deduped = append(deduped, reason)
+	}
+	if duplicates := len(reasons) - len(deduped); duplicates > 0 {
+		logger().Warn("OCM returned duplicate limited support reasons, only the first of each is listed", "cluster", clusterID, "duplicates", duplicates)
+	}
+	return deduped
+}
@@ -0,0 +1,126 @@
+package support
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"gopkg.in/yaml.v2"
+)
+
+func Test_orgStatusRows(t *testing.T) {
+	clusters := []clusterExport{
+		{ClusterKey: "a", ClusterID: "a-id", Reasons: []json.RawMessage{
+			json.RawMessage(`{"id":"r1","summary":"first"}`),
+			json.RawMessage(`{"id":"r2","summary":"second"}`),
+		}},
+		{ClusterKey: "b", ClusterID: "b-id", Reasons: []json.RawMessage{}},
+		{ClusterKey: "c", Error: "not found"},
+		{ClusterKey: "d", ClusterID: "d-id", Reasons: []json.RawMessage{json.RawMessage(`{`)}},
+	}
+
+	rows, failures := orgStatusRows(clusters)
+	var got [][]string
+	for _, row := range rows {
+		got = append(got, []string{row.clusterID, row.reason.ID(), row.reason.Summary()})
+	}
+	want := [][]string{{"a-id", "r1", "first"}, {"a-id", "r2", "second"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("orgStatusRows() rows = %v, want %v", got, want)
+	}
+	var failed []string
+	for _, failure := range failures {
+		failed = append(failed, failure.clusterKey)
+	}
+	// The failures keep the cluster key, for the '-o json' error lines
+	if want := []string{"c", "d"}; !reflect.DeepEqual(failed, want) {
+		t.Errorf("orgStatusRows() failed clusters = %v, want %v", failed, want)
+	}
+}
+
+func Test_dedupeReasons(t *testing.T) {
+	// OCM lists the reasons r1 and r2 twice
+	const response = `{"kind":"LimitedSupportReasonList","page":1,"size":4,"total":4,"items":[
+		{"kind":"LimitedSupportReason","id":"r1","summary":"first"},
+		{"kind":"LimitedSupportReason","id":"r2","summary":"second"},
+		{"kind":"LimitedSupportReason","id":"r1","summary":"first"},
+		{"kind":"LimitedSupportReason","id":"r2","summary":"second"}]}`
+	_, connection := newFakeOCM(t, map[string]http.HandlerFunc{
+		"GET /api/accounts_mgmt/v1/subscriptions": respond(http.StatusOK, `{"kind":"SubscriptionList","page":1,"size":1,"total":1,"items":[
+			{"kind":"Subscription","id":"sub-id","cluster_id":"cluster-id"}]}`),
+		"GET /api/clusters_mgmt/v1/clusters/cluster-id":                         respond(http.StatusOK, `{"kind":"Cluster","id":"cluster-id"}`),
+		"GET /api/clusters_mgmt/v1/clusters/cluster-id/limited_support_reasons": respond(http.StatusOK, response),
+	})
+	cluster, reasons, err := listLimitedSupportReasons(connection, "cluster-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, reason := range dedupeReasons(cluster.ID(), reasons) {
+		ids = append(ids, reason.ID())
+	}
+	if want := []string{"r1", "r2"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("dedupeReasons() = %v, want %v", ids, want)
+	}
+
+	// The reasons of an organization's clusters are deduped per cluster
+	var list struct {
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(response), &list); err != nil {
+		t.Fatal(err)
+	}
+	rows, errs := orgStatusRows([]clusterExport{
+		{ClusterKey: "a", ClusterID: "a-id", Reasons: list.Items},
+		{ClusterKey: "b", ClusterID: "b-id", Reasons: list.Items[:1]},
+	})
+	if len(errs) != 0 || len(rows) != 3 {
+		t.Errorf("orgStatusRows() = %d row(s), %v, want a row for each distinct reason of each cluster", len(rows), errs)
+	}
+}
+
+func Test_reasonsOutput(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	reason, err := cmv1.NewLimitedSupportReason().ID("reason-1").Summary("Summary").Details("Details").CreationTimestamp(created).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := newReasonsOutput("cluster-1", []*cmv1.LimitedSupportReason{reason})
+	want := reasonsOutput{ClusterID: "cluster-1", Reasons: []reasonOutput{{ID: "reason-1", Summary: "Summary", Details: "Details", CreatedAt: created}}}
+	if !reflect.DeepEqual(output, want) {
+		t.Errorf("newReasonsOutput() = %+v, want %+v", output, want)
+	}
+	if s := output.String(); !strings.Contains(s, "reason-1") || !strings.Contains(s, "Summary") || strings.HasSuffix(s, "\n") {
+		t.Errorf("String() = %q, want a table of the reasons", s)
+	}
+
+	if s := newReasonsOutput("cluster-1", nil).String(); s != "Cluster is fully supported" {
+		t.Errorf("String() = %q for a cluster without reasons", s)
+	}
+
+	// --then-list prints the posted reason and the reasons of the cluster as a single document
+	data, err := yaml.Marshal(postThenListOutput{postOutput: postOutput{ClusterID: "cluster-1", ReasonID: "reason-1", CreatedAt: created}, Reasons: output.Reasons})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		t.Fatal(err)
+	}
+	if document["reason_id"] != "reason-1" || document["cluster_id"] != "cluster-1" || len(document["limited_support_reasons"].([]interface{})) != 1 {
+		t.Errorf("postThenListOutput = %s, want the posted reason and the reasons of the cluster", data)
+	}
+	data, err = json.Marshal(postThenListOutput{postOutput: postOutput{ReasonID: "reason-1"}, Reasons: output.Reasons})
+	if err != nil {
+		t.Fatal(err)
+	}
+	document = nil
+	if err := json.Unmarshal(data, &document); err != nil || document["reason_id"] != "reason-1" || document["limited_support_reasons"] == nil {
+		t.Errorf("postThenListOutput = %s, want the posted reason and the reasons of the cluster", data)
+	}
+}
@@ -0,0 +1,129 @@
+package support
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/openshift/osdctl/internal/utils/globalflags"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+type sweepOptions struct {
+	clusterIDsFile string
+	olderThan      time.Duration
+	isDryRun       bool
+
+	genericclioptions.IOStreams
+	GlobalOptions *globalflags.GlobalOptions
+}
+
+// newCmdsweep implements the sweep command to delete the limited support reasons older than a duration
+func newCmdsweep(streams genericclioptions.IOStreams, globalOpts *globalflags.GlobalOptions) *cobra.Command {
+	ops := newSweepOptions(streams, globalOpts)
+	sweepCmd := &cobra.Command{
+		Use:   "sweep --older-than DURATION --cluster-ids-file FILE",
+		Short: "Delete the limited support reasons created longer ago than a duration",
+		Long: `Lists the limited support reasons of every cluster of the file and deletes the ones created longer ago than --older-than.
+The stale reasons are printed and the caller is prompted to continue before they are deleted.`,
+		Example: `  # Show the reasons older than 30 days of the clusters listed in clusters.txt
+  osdctl cluster support sweep --older-than 720h --cluster-ids-file clusters.txt --dry-run`,
+		Args:              cobra.NoArgs,
+		DisableAutoGenTag: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(ops.complete(cmd, args))
+			cmdutil.CheckErr(ops.run())
+		},
+	}
+
+	sweepCmd.Flags().StringVar(&ops.clusterIDsFile, "cluster-ids-file", "", "File listing one cluster ID, name or external ID per line. Empty lines and lines starting with '#' are ignored")
+	sweepCmd.Flags().DurationVar(&ops.olderThan, "older-than", 0, "Delete the reasons created longer ago than this duration (eg. 720h)")
+	sweepCmd.Flags().BoolVarP(&ops.isDryRun, "dry-run", "d", false, "Dry-run - print the reasons about to be deleted but don't delete them.")
+	_ = sweepCmd.MarkFlagRequired("cluster-ids-file")
+	_ = sweepCmd.MarkFlagRequired("older-than")
+
+	return sweepCmd
+}
+
+func newSweepOptions(streams genericclioptions.IOStreams, globalOpts *globalflags.GlobalOptions) *sweepOptions {
+	return &sweepOptions{
+		IOStreams:     streams,
+		GlobalOptions: globalOpts,
+	}
+}
+
+func (o *sweepOptions) complete(cmd *cobra.Command, _ []string) error {
+	if o.olderThan <= 0 {
+		return cmdutil.UsageErrorf(cmd, "--older-than must be a positive duration")
+	}
+	return nil
+}
+
+func (o *sweepOptions) run() error {
+	clusterKeys, err := readClusterIDsFile(o.clusterIDsFile)
+	if err != nil {
+		return err
+	}
+	if len(clusterKeys) == 0 {
+		return fmt.Errorf("no clusters found in %s", o.clusterIDsFile)
+	}
+
+	//create connection to sdk
+	connection, err := createConnection()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := connection.Close(); err != nil {
+			fmt.Printf("Cannot close the connection: %q\n", err)
+			os.Exit(1)
+		}
+	}()
+
+	cutoff := time.Now().Add(-o.olderThan)
+	plans := make([]clusterPlan, 0, len(clusterKeys))
+	var errs []error
+	for _, clusterKey := range clusterKeys {
+		cluster, reasons, err := listLimitedSupportReasons(connection, clusterKey)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cluster %s: %w", clusterKey, err))
+			continue
+		}
+		plans = append(plans, clusterPlan{cluster: cluster, toDelete: staleReasons(reasons, cutoff)})
+	}
+
+	if plansEmpty(plans) {
+		fmt.Printf("No limited support reason was created before %s\n", cutoff.Format(time.RFC3339))
+		return errors.Join(errs...)
+	}
+
+	if err := printPlans(plans); err != nil {
+		return err
+	}
+
+	// Stop here if dry-run
+	if o.isDryRun {
+		return errors.Join(errs...)
+	}
+
+	if ok, err := confirm(); !ok {
+		return err
+	}
+
+	return errors.Join(append(errs, applyPlans(connection, plans))...)
+}
+
+// staleReasons returns the reasons created before cutoff. Reasons without a creation timestamp are kept.
+func staleReasons(reasons []*cmv1.LimitedSupportReason, cutoff time.Time) []*cmv1.LimitedSupportReason {
+	var stale []*cmv1.LimitedSupportReason
+	for _, reason := range reasons {
+		if created, ok := reason.GetCreationTimestamp(); ok && !created.IsZero() && created.Before(cutoff) {
+			stale = append(stale, reason)
+		}
+	}
+	return stale
+}
@@ -0,0 +1,38 @@
+package support
+
+import (
+	"testing"
+	"time"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+)
+
+func Test_staleReasons(t *testing.T) {
+	cutoff := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	reason := func(id string, created time.Time) *cmv1.LimitedSupportReason {
+		builder := cmv1.NewLimitedSupportReason().ID(id)
+		if !created.IsZero() {
+			builder = builder.CreationTimestamp(created)
+		}
+		r, err := builder.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	stale := staleReasons([]*cmv1.LimitedSupportReason{
+		reason("old", cutoff.Add(-time.Hour)),
+		reason("new", cutoff.Add(time.Hour)),
+		reason("no-timestamp", time.Time{}),
+		reason("older", cutoff.Add(-48*time.Hour)),
+	}, cutoff)
+
+	var ids []string
+	for _, r := range stale {
+		ids = append(ids, r.ID())
+	}
+	if len(ids) != 2 || ids[0] != "old" || ids[1] != "older" {
+		t.Errorf("staleReasons() = %v, want [old older]", ids)
+	}
+}
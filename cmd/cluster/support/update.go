@@ -0,0 +1,215 @@
+package support
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/openshift/osdctl/internal/support"
+	"github.com/openshift/osdctl/internal/utils/globalflags"
+	ctlutil "github.com/openshift/osdctl/pkg/utils"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+type updateOptions struct {
+	clusterID              string
+	limitedSupportReasonID string
+	template               string
+	templateParams         []string
+	isDryRun               bool
+
+	genericclioptions.IOStreams
+	GlobalOptions *globalflags.GlobalOptions
+}
+
+// errUpdateNotSupported is returned when OCM does not allow updating limited support reasons in place
+var errUpdateNotSupported = errors.New("OCM does not support updating limited support reasons in place")
+
+// newCmdupdate implements the update command to modify an existing limited support reason
+func newCmdupdate(streams genericclioptions.IOStreams, globalOpts *globalflags.GlobalOptions) *cobra.Command {
+	ops := newUpdateOptions(streams, globalOpts)
+	updateCmd := &cobra.Command{
+		Use:   "update CLUSTER_ID",
+		Short: "Update the summary, details and detection type of an existing limited support reason",
+		Long: `Updates an existing limited support reason in place with the content of a template, preserving its ID.
+If OCM does not support updating limited support reasons, the reason is replaced instead: the new reason is posted, then the old one is deleted.`,
+		Example: `  # Replace the content of a reason with new.json
+  osdctl cluster support update 1a2B3c4DefghIjkLMNOpQrSTUV5 --reason-id 2Ab3CdEfgHIJ -t new.json -p FOO=BAR`,
+		Args:              cobra.ExactArgs(1),
+		DisableAutoGenTag: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(ops.complete(cmd, args))
+			cmdutil.CheckErr(ops.run())
+		},
+	}
+
+	updateCmd.Flags().StringVarP(&ops.limitedSupportReasonID, "reason-id", "i", "", "ID of the limited support reason to update")
+	updateCmd.Flags().StringVarP(&ops.template, "template", "t", "", "Message template file or URL with the new content of the reason")
+	updateCmd.Flags().StringArrayVarP(&ops.templateParams, "param", "p", nil, "Specify a key-value pair (eg. -p FOO=BAR) to set/override a parameter value in the template.")
+	updateCmd.Flags().BoolVarP(&ops.isDryRun, "dry-run", "d", false, "Dry-run - print the updated limited support reason but don't send it.")
+	_ = updateCmd.MarkFlagRequired("reason-id")
+	_ = updateCmd.MarkFlagRequired("template")
+
+	return updateCmd
+}
+
+func newUpdateOptions(streams genericclioptions.IOStreams, globalOpts *globalflags.GlobalOptions) *updateOptions {
+	return &updateOptions{
+		IOStreams:     streams,
+		GlobalOptions: globalOpts,
+	}
+}
+
+func (o *updateOptions) complete(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return cmdutil.UsageErrorf(cmd, "Provide exactly one cluster ID")
+	}
+	if o.limitedSupportReasonID == "" || o.template == "" {
+		return cmdutil.UsageErrorf(cmd, "--reason-id and --template are required")
+	}
+
+	o.clusterID = args[0]
+
+	return nil
+}
+
+func (o *updateOptions) run() error {
+	// Check that the cluster key (name, identifier or external identifier) given by the user
+	// is reasonably safe so that there is no risk of SQL injection
+	if err := ctlutil.IsValidClusterKey(o.clusterID); err != nil {
+		return err
+	}
+
+	// The new content is rendered exactly like 'post' does
+	p := &Post{Template: o.template, TemplateParams: o.templateParams}
+	updated, err := p.buildLimitedSupportTemplate(nil)
+	if err != nil {
+		return err
+	}
+	if err := p.checkAllowlist(updated); err != nil {
+		return err
+	}
+
+	//create connection to sdk
+	connection, err := createConnection()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := connection.Close(); err != nil {
+			fmt.Printf("Cannot close the connection: %q\n", err)
+			os.Exit(1)
+		}
+	}()
+
+	cluster, reasons, err := listLimitedSupportReasons(connection, o.clusterID)
+	if err != nil {
+		return err
+	}
+	var current *cmv1.LimitedSupportReason
+	for _, reason := range reasons {
+		if reason.ID() == o.limitedSupportReasonID {
+			current = reason
+		}
+	}
+	if current == nil {
+		return fmt.Errorf("cluster %s has no limited support reason with ID %s", cluster.ID(), o.limitedSupportReasonID)
+	}
+
+	fmt.Printf("Limited support reason %s currently is:\n", current.ID())
+	if err := printLimitedSupportReason(os.Stdout, current); err != nil {
+		return fmt.Errorf("failed to print limited support reason: %w", err)
+	}
+	fmt.Printf("It will be updated to:\n")
+	if err := printLimitedSupportReason(os.Stdout, updated); err != nil {
+		return fmt.Errorf("failed to print limited support reason: %w", err)
+	}
+
+	// Stop here if dry-run
+	if o.isDryRun {
+		return nil
+	}
+
+	if ok, err := confirm(); !ok {
+		return err
+	}
+
+	err = updateLimitedSupportReason(context.Background(), connection, cluster, current.ID(), updated)
+	if !errors.Is(err, errUpdateNotSupported) {
+		if err == nil {
+			fmt.Printf("Limited support reason %s updated successfully\n", current.ID())
+		}
+		return err
+	}
+
+	logger().Warn("Replacing the reason with a new one instead, its ID will change", "reason_id", current.ID(), "error", err)
+	return replaceLimitedSupportReason(connection, cluster, current.ID(), updated)
+}
+
+// updateLimitedSupportReason patches the reason with the summary, details and detection type of updated.
+// errUpdateNotSupported is returned when OCM does not allow it.
+func updateLimitedSupportReason(ctx context.Context, connection *sdk.Connection, cluster *cmv1.Cluster, reasonID string, updated *cmv1.LimitedSupportReason) error {
+	request, err := createReasonRequest(connection, reasonRequestOptions{
+		method:    http.MethodPatch,
+		clusterID: cluster.ID(),
+		reasonID:  reasonID,
+	})
+	if err != nil {
+		return err
+	}
+
+	body := bytes.Buffer{}
+	if err := cmv1.MarshalLimitedSupportReason(updated, &body); err != nil {
+		return fmt.Errorf("cannot marshal limited support reason: %w", err)
+	}
+	request.Bytes(body.Bytes())
+
+	response, err := ctlutil.SendRequestContext(ctx, request)
+	if err != nil {
+		return err
+	}
+	return checkUpdate(response)
+}
+
+// checkUpdate checks the response from the update API call
+func checkUpdate(response *sdk.Response) error {
+	switch response.Status() {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return errUpdateNotSupported
+	}
+
+	if err := ctlutil.CheckJSONResponse(response); err != nil {
+		return err
+	}
+	body := response.Bytes()
+	var badReply support.BadReply
+	if err := json.Unmarshal(body, &badReply); err != nil {
+		return fmt.Errorf("cannot parse the error JSON message: %q", err)
+	}
+	return fmt.Errorf("server returned status %d: %s", response.Status(), badReply.Reason)
+}
+
+// replaceLimitedSupportReason posts updated before deleting the old reason, so the cluster never leaves limited support
+func replaceLimitedSupportReason(connection *sdk.Connection, cluster *cmv1.Cluster, reasonID string, updated *cmv1.LimitedSupportReason) error {
+	response, err := sendLimitedSupportPostRequest(context.TODO(), connection, cluster.ID(), updated)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Posted the updated reason with ID %s\n", response.Body().ID())
+	(&Post{}).recordHistory(cluster.ID(), response.Body())
+
+	if err := deleteLimitedSupportReason(connection, cluster, reasonID); err != nil {
+		return fmt.Errorf("the updated reason was posted with ID %s, but the old reason %s could not be deleted: %w", response.Body().ID(), reasonID, err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package support
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	ctlutil "github.com/openshift/osdctl/pkg/utils"
+)
+
+// upsertReason updates the reason of the cluster identified by --upsert when the cluster still has it, and posts
+// limitedSupport otherwise. OCM assigns the IDs of limited support reasons and has no natural key for them, so the
+// ID has to be one returned by an earlier post. A reason which already has the content is left untouched.
+func (p *Post) upsertReason(connection *sdk.Connection, limitedSupport *cmv1.LimitedSupportReason) (*PostResult, error) {
+	clusterID := p.cluster.ID()
+	current, err := p.getReason(connection, clusterID, p.upsertReasonID)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		logger().Info("The reason to upsert doesn't exist, posting a new one", "cluster", clusterID, "reason_id", p.upsertReasonID)
+		return p.postNewReason(connection, limitedSupport)
+	}
+
+	if sameReasonContent(current, limitedSupport) {
+		logger().Info("The reason is already up to date", "cluster", clusterID, "reason_id", current.ID())
+		result, err := newPostResult(clusterID, http.StatusOK, current)
+		if err != nil {
+			return nil, err
+		}
+		result.Unchanged = true
+		return result, nil
+	}
+
+	ctx, capture := ctlutil.WithBodyCapture(context.Background())
+	err = updateLimitedSupportReason(ctx, connection, p.cluster, current.ID(), limitedSupport)
+	if errors.Is(err, errUpdateNotSupported) {
+		// The cluster never leaves limited support, the new reason is posted before the old one is deleted
+		logger().Warn("Replacing the reason with a new one instead, its ID will change", "reason_id", current.ID(), "error", err)
+		result, err := p.postNewReason(connection, limitedSupport)
+		if err != nil {
+			return nil, err
+		}
+		if err := deleteLimitedSupportReason(connection, p.cluster, current.ID()); err != nil {
+			return nil, fmt.Errorf("the updated reason was posted with ID %s, but the old reason %s could not be deleted: %w", result.ReasonID, current.ID(), err)
+		}
+		return result, nil
+	}
+	if err != nil {
+		request, response := capturedBodies(capture, marshalReason(limitedSupport))
+		p.saveIO(clusterID, request, response, err)
+		return nil, fmt.Errorf("failed to update limited support reason %s: %w", current.ID(), err)
+	}
+
+	updated, err := p.getReason(connection, clusterID, current.ID())
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, fmt.Errorf("limited support reason %s disappeared after being updated", current.ID())
+	}
+	result, err := newPostResult(clusterID, http.StatusOK, updated)
+	if err != nil {
+		return nil, err
+	}
+	request, response := capturedBodies(capture, marshalReason(limitedSupport))
+	p.saveIO(clusterID, request, response, nil)
+	p.recordHistory(clusterID, updated)
+	return result, nil
+}
+
+// postNewReason posts the reason to the cluster and records it
+func (p *Post) postNewReason(connection *sdk.Connection, limitedSupport *cmv1.LimitedSupportReason) (*PostResult, error) {
+	var response *cmv1.LimitedSupportReasonsAddResponse
+	ctx, capture := ctlutil.WithBodyCapture(context.Background())
+	err := retryOCMCreate(p.retries, func() error {
+		var err error
+		response, err = sendLimitedSupportPostRequest(ctx, connection, p.cluster.ID(), limitedSupport)
+		return err
+	})
+	request, received := capturedBodies(capture, marshalReason(limitedSupport))
+	if err != nil {
+		p.saveIO(p.cluster.ID(), request, received, err)
+		return nil, fmt.Errorf("failed to post limited support reason: %w", err)
+	}
+	result, err := newPostResult(p.cluster.ID(), response.Status(), response.Body())
+	if err != nil {
+		return nil, err
+	}
+	p.saveIO(p.cluster.ID(), request, received, nil)
+	p.recordHistory(p.cluster.ID(), response.Body())
+	return result, nil
+}
+
+// getReason returns the limited support reason of the cluster with the ID, or nil when the cluster doesn't have it
+func (p *Post) getReason(connection *sdk.Connection, clusterID string, reasonID string) (*cmv1.LimitedSupportReason, error) {
+	var response *cmv1.LimitedSupportReasonGetResponse
+	err := retryOCM(p.retries, func() error {
+		var err error
+		response, err = connection.ClustersMgmt().V1().Clusters().Cluster(clusterID).LimitedSupportReasons().LimitedSupportReason(reasonID).Get().SendContext(context.Background())
+		return err
+	})
+	if response != nil && response.Status() == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get limited support reason %s: %w", reasonID, err)
+	}
+	return response.Body(), nil
+}
+
+// sameReasonContent tells whether the reasons have the same customer facing content
+func sameReasonContent(a *cmv1.LimitedSupportReason, b *cmv1.LimitedSupportReason) bool {
+	return a.Summary() == b.Summary() && a.Details() == b.Details() && a.DetectionType() == b.DetectionType()
+}
@@ -0,0 +1,84 @@
+package support
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/spf13/viper"
+)
+
+func Test_sameReasonContent(t *testing.T) {
+	reason := func(id string, summary string, details string, detectionType cmv1.DetectionType) *cmv1.LimitedSupportReason {
+		r, err := cmv1.NewLimitedSupportReason().ID(id).Summary(summary).Details(details).DetectionType(detectionType).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	current := reason("reason-1", "Summary", "Details", cmv1.DetectionTypeManual)
+
+	// The ID is assigned by OCM, only the content matters
+	if !sameReasonContent(current, reason("", "Summary", "Details", cmv1.DetectionTypeManual)) {
+		t.Error("sameReasonContent() = false for reasons with the same content")
+	}
+	for _, other := range []*cmv1.LimitedSupportReason{
+		reason("reason-1", "Other", "Details", cmv1.DetectionTypeManual),
+		reason("reason-1", "Summary", "Other", cmv1.DetectionTypeManual),
+		reason("reason-1", "Summary", "Details", cmv1.DetectionTypeAuto),
+	} {
+		if sameReasonContent(current, other) {
+			t.Errorf("sameReasonContent() = true for %s/%s/%s", other.Summary(), other.Details(), other.DetectionType())
+		}
+	}
+
+	p := &Post{upsertReasonID: "reason-1", RawBodyFile: "body.json"}
+	if err := p.check(); err == nil {
+		t.Error("check() should reject --upsert with --raw-body-file")
+	}
+}
+
+func Test_upsertUnchangedSkipsServiceLog(t *testing.T) {
+	viper.Set(HistoryFileKey, filepath.Join(t.TempDir(), "history.jsonl"))
+	defer viper.Set(HistoryFileKey, "")
+
+	const reasonPath = "/api/clusters_mgmt/v1/clusters/cluster-id/limited_support_reasons/reason-id"
+	const serviceLogs = "POST /api/service_logs/v1/cluster_logs"
+	fake, connection := newFakeOCM(t, map[string]http.HandlerFunc{
+		"GET " + reasonPath: respond(http.StatusOK, `{"kind":"LimitedSupportReason","id":"reason-id","summary":"Summary","details":"Details","detection_type":"manual"}`),
+		serviceLogs:         respond(http.StatusCreated, `{"kind":"ClusterLog","id":"log-id"}`),
+	})
+	cluster, err := cmv1.NewCluster().ID("cluster-id").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Post{
+		Template:        "template.json",
+		templateBytes:   []byte(`{"summary":"Summary","details":"Details","detection_type":"manual"}`),
+		upsertReasonID:  "reason-id",
+		Evidence:        "See OHSS-1234",
+		cluster:         cluster,
+		clusterPrepared: true,
+	}
+	result, err := p.postToCluster(connection, "cluster-id", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Unchanged || result.ReasonID != "reason-id" {
+		t.Errorf("postToCluster() = %+v, want the reason to be unchanged", result)
+	}
+	if n := fake.count(serviceLogs); n != 0 {
+		t.Errorf("%d internal service log(s) were sent for an unchanged reason, want none", n)
+	}
+
+	// A reason which changes is still sent with its evidence
+	p.templateBytes = []byte(`{"summary":"Summary","details":"New details","detection_type":"manual"}`)
+	fake.routes["PATCH "+reasonPath] = respond(http.StatusOK, `{"kind":"LimitedSupportReason","id":"reason-id","summary":"Summary","details":"New details","detection_type":"manual"}`)
+	if _, err := p.postToCluster(connection, "cluster-id", nil, false); err != nil {
+		t.Fatal(err)
+	}
+	if n := fake.count(serviceLogs); n != 1 {
+		t.Errorf("%d internal service log(s) were sent for an updated reason, want 1", n)
+	}
+}
@@ -0,0 +1,267 @@
+package support
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/openshift/osdctl/cmd/getoutput"
+	"github.com/openshift/osdctl/internal/utils/globalflags"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+)
+
+type validateAllOptions struct {
+	dir            string
+	validateSchema bool
+	output         string
+
+	genericclioptions.IOStreams
+	GlobalOptions *globalflags.GlobalOptions
+}
+
+// templateValidation is the result of the validation of a template, it passes when there is no problem
+type templateValidation struct {
+	Template string   `json:"template" yaml:"template"`
+	Problems []string `json:"problems,omitempty" yaml:"problems,omitempty"`
+}
+
+// validationReport lists the validation of every template of a directory, it is printed according to '-o'
+type validationReport struct {
+	Templates []templateValidation `json:"templates" yaml:"templates"`
+	Failed    int                  `json:"failed" yaml:"failed"`
+}
+
+func (r validationReport) String() string {
+	var out strings.Builder
+	for _, validation := range r.Templates {
+		if len(validation.Problems) == 0 {
+			fmt.Fprintf(&out, "PASS %s\n", validation.Template)
+			continue
+		}
+		fmt.Fprintf(&out, "FAIL %s\n", validation.Template)
+		for _, problem := range validation.Problems {
+			fmt.Fprintf(&out, "  - %s\n", problem)
+		}
+	}
+	fmt.Fprintf(&out, "%d template(s) validated, %d failed", len(r.Templates), r.Failed)
+	return out.String()
+}
+
+// newCmdvalidateAll implements the validate-all command to check every template of a directory, eg. in CI
+func newCmdvalidateAll(streams genericclioptions.IOStreams, globalOpts *globalflags.GlobalOptions) *cobra.Command {
+	ops := newValidateAllOptions(streams, globalOpts)
+	validateAllCmd := &cobra.Command{
+		Use:   "validate-all DIRECTORY",
+		Short: "Validate every template of a directory",
+		Long: `Walks the directory and validates every JSON (.json) and YAML (.yaml, .yml) template it contains, without posting anything.
+Each template is parsed, its fields are checked against the template format, and its '${...}' placeholders are checked against the parameters it declares.
+Hidden directories, such as .git or .github, are skipped. The command exits with an error when any template fails, so that it can gate a template repository in CI.`,
+		Example: `  # Validate a template repository
+  osdctl cluster support validate-all ./templates/
+
+  # Also check the templates against the limited support reason schema of OCM
+  osdctl cluster support validate-all ./templates/ --validate-schema`,
+		Args:              cobra.ExactArgs(1),
+		DisableAutoGenTag: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdutil.CheckErr(ops.complete(cmd, args))
+			cmdutil.CheckErr(ops.run())
+		},
+	}
+
+	validateAllCmd.Flags().BoolVar(&ops.validateSchema, "validate-schema", false, "Also check every template against the limited support reason schema published by OCM. Requires access to OCM, the schema is cached for a day.")
+
+	return validateAllCmd
+}
+
+func newValidateAllOptions(streams genericclioptions.IOStreams, globalOpts *globalflags.GlobalOptions) *validateAllOptions {
+	return &validateAllOptions{
+		IOStreams:     streams,
+		GlobalOptions: globalOpts,
+	}
+}
+
+func (o *validateAllOptions) complete(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return cmdutil.UsageErrorf(cmd, "Provide the directory of the templates")
+	}
+	o.dir = args[0]
+	o.output = o.GlobalOptions.Output
+	return nil
+}
+
+func (o *validateAllOptions) run() error {
+	paths, err := templatePaths(o.dir)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no JSON or YAML template found in %s", o.dir)
+	}
+
+	var schema *reasonSchema
+	if o.validateSchema {
+		connection, err := createConnection()
+		if err != nil {
+			return fmt.Errorf("cannot connect to OCM to fetch its schema: %w", err)
+		}
+		defer func() {
+			if err := connection.Close(); err != nil {
+				fmt.Printf("Cannot close the connection: %q\n", err)
+				os.Exit(1)
+			}
+		}()
+		if schema = fetchReasonSchema(connection); schema == nil {
+			return fmt.Errorf("the OCM schema is unavailable, retry later or validate without --validate-schema")
+		}
+	}
+
+	report := validationReport{}
+	for _, path := range paths {
+		validation := templateValidation{Template: path}
+		contents, err := os.ReadFile(filepath.Clean(path))
+		if err != nil {
+			validation.Problems = []string{err.Error()}
+		} else {
+			validation.Problems = validateTemplate(path, contents, schema)
+		}
+		if len(validation.Problems) > 0 {
+			report.Failed++
+		}
+		report.Templates = append(report.Templates, validation)
+	}
+
+	if err := getoutput.PrintResponse(o.output, report); err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d templates failed validation", report.Failed, len(report.Templates))
+	}
+	return nil
+}
+
+// fetchReasonSchema returns the limited support reason schema of OCM, or nil when it is unavailable
+func fetchReasonSchema(connection *sdk.Connection) *reasonSchema {
+	p := &Post{}
+	return p.loadReasonSchema(connection)
+}
+
+// templatePaths returns the JSON and YAML files under the directory, in lexical order, skipping hidden directories
+func templatePaths(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".json", ".yaml", ".yml":
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot walk %s: %w", dir, err)
+	}
+	return paths, nil
+}
+
+// validateTemplate returns every problem of the template, without rendering it. The template is checked against
+// the schema of OCM too when one is given.
+func validateTemplate(path string, contents []byte, schema *reasonSchema) []string {
+	converted, err := templateJSON(path, contents)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var t TemplateFile
+	decoder := json.NewDecoder(bytes.NewReader(converted))
+	// Misspelled fields would silently be ignored by 'post'
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&t); err != nil {
+		return []string{fmt.Sprintf("cannot parse the template: %v", err)}
+	}
+
+	var problems []string
+	if strings.TrimSpace(t.Summary) == "" {
+		problems = append(problems, "the summary is empty")
+	}
+	if strings.TrimSpace(t.Details) == "" {
+		problems = append(problems, "the details are empty")
+	}
+	switch t.Detection_type {
+	case "", cmv1.DetectionTypeManual, cmv1.DetectionTypeAuto:
+	default:
+		problems = append(problems, fmt.Sprintf("the detection_type is %q, it must be '%s' or '%s'", t.Detection_type, cmv1.DetectionTypeManual, cmv1.DetectionTypeAuto))
+	}
+	if t.Provider != "" {
+		if err := checkCloudProviderName(t.Provider); err != nil {
+			problems = append(problems, fmt.Sprintf("the provider is invalid: %v", err))
+		}
+	}
+	problems = append(problems, placeholderProblems(&t)...)
+
+	if schema != nil {
+		reason, err := cmv1.NewLimitedSupportReason().Summary(t.Summary).Details(t.Details).DetectionType(t.Detection_type).Build()
+		if err != nil {
+			return append(problems, fmt.Sprintf("cannot build the limited support reason: %v", err))
+		}
+		schemaProblems, err := schema.validate(marshalReason(reason))
+		if err != nil {
+			return append(problems, err.Error())
+		}
+		for _, problem := range schemaProblems {
+			problems = append(problems, "OCM schema: "+problem)
+		}
+	}
+	return problems
+}
+
+// placeholderProblems checks the '${...}' placeholders of the template against the parameters it declares
+func placeholderProblems(t *TemplateFile) []string {
+	p := &Post{}
+	var problems []string
+	placeholders := p.findLeftovers(t.Details)
+	if strings.Count(t.Details, "${") > len(placeholders) {
+		problems = append(problems, "the details have a '${' which doesn't start a valid '${NAME}' placeholder")
+	}
+	for _, placeholder := range placeholders {
+		if placeholder == "${}" {
+			problems = append(problems, "the details have a '${}' placeholder without a name")
+			break
+		}
+	}
+	// Only the details are rendered, placeholders in the summary would be posted verbatim
+	for _, placeholder := range p.findLeftovers(t.Summary) {
+		problems = append(problems, fmt.Sprintf("the summary uses %s, but only the details are rendered", placeholder))
+	}
+
+	declared := map[string]bool{}
+	for _, param := range t.Parameters {
+		if param.Name == "" {
+			problems = append(problems, "a parameter is declared without a name")
+			continue
+		}
+		if declared[param.Name] {
+			problems = append(problems, fmt.Sprintf("parameter %s is declared more than once", param.Name))
+			continue
+		}
+		declared[param.Name] = true
+		if !usesParam(t.Details, "${"+param.Name+"}") {
+			problems = append(problems, fmt.Sprintf("parameter %s is declared but the details don't use ${%s}", param.Name, param.Name))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,106 @@
+package support
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_templatePaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.json", "a/c.yaml", "a/d.yml", "README.md", ".github/workflows/ci.yaml"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("{}"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	paths, err := templatePaths(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "a/c.yaml"), filepath.Join(dir, "a/d.yml"), filepath.Join(dir, "b.json")}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("templatePaths() = %v, want %v", paths, want)
+	}
+}
+
+func Test_validateTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		contents string
+		want     []string
+	}{
+		{
+			name:     "valid JSON",
+			path:     "valid.json",
+			contents: `{"summary":"Summary","details":"Restore ${RULE}","detection_type":"manual","parameters":[{"name":"RULE","required":true}]}`,
+		},
+		{
+			name:     "valid YAML",
+			path:     "valid.yaml",
+			contents: "summary: Summary\ndetails: Restore ${RULE}\ndetection_type: auto\n",
+		},
+		{
+			name:     "invalid JSON",
+			path:     "invalid.json",
+			contents: `{"summary":`,
+			want:     []string{"cannot parse the template"},
+		},
+		{
+			name:     "unknown field",
+			path:     "typo.json",
+			contents: `{"summary":"Summary","detials":"Details"}`,
+			want:     []string{`unknown field "detials"`},
+		},
+		{
+			name:     "empty fields and detection type",
+			path:     "empty.json",
+			contents: `{"summary":"","details":" ","detection_type":"automatic"}`,
+			want:     []string{"the summary is empty", "the details are empty", `the detection_type is "automatic"`},
+		},
+		{
+			name:     "placeholders",
+			path:     "placeholders.json",
+			contents: `{"summary":"About ${RULE}","details":"Restore ${RULE} and ${} then ${BROKEN","parameters":[{"name":"UNUSED"},{"name":"RULE"},{"name":"RULE"}]}`,
+			want: []string{
+				"doesn't start a valid",
+				"'${}' placeholder without a name",
+				"the summary uses ${RULE}",
+				"parameter UNUSED is declared but the details don't use ${UNUSED}",
+				"parameter RULE is declared more than once",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := validateTemplate(tt.path, []byte(tt.contents), nil)
+			if len(problems) != len(tt.want) {
+				t.Fatalf("validateTemplate() = %q, want %d problem(s)", problems, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(problems[i], want) {
+					t.Errorf("problem %d = %q, want it to contain %q", i, problems[i], want)
+				}
+			}
+		})
+	}
+}
+
+func Test_validateTemplateSchema(t *testing.T) {
+	schema := &reasonSchema{properties: map[string]openAPISchema{
+		"kind":           {Type: "string"},
+		"summary":        {Type: "string"},
+		"details":        {Type: "string"},
+		"detection_type": {Type: "string", Enum: []string{"auto", "manual"}},
+	}}
+	if problems := validateTemplate("valid.json", []byte(`{"summary":"Summary","details":"Details","detection_type":"manual"}`), schema); len(problems) != 0 {
+		t.Errorf("validateTemplate() = %q, want no problem", problems)
+	}
+}
@@ -64,6 +64,9 @@ func NewCmdRoot(streams genericclioptions.IOStreams) *cobra.Command {
 				os.Exit(1)
 			}
 			viper.Set(aws.NoProxyFlag, noAwsProxy)
+			viper.Set(utils.OCMCommandPathKey, cmd.CommandPath())
+			// Only when opted in with 'telemetry_enabled' in the config file
+			utils.RecordUsage(cmd)
 
 			skipVersionCheck, err := cmd.Flags().GetBool("skip-version-check")
 			if err != nil {
@@ -79,6 +82,12 @@ func NewCmdRoot(streams genericclioptions.IOStreams) *cobra.Command {
 	}
 
 	globalflags.AddGlobalFlags(rootCmd, globalOpts)
+	// OCM service account credentials, used by every command connecting to OCM instead of the offline token
+	// from 'ocm login' when set
+	rootCmd.PersistentFlags().String("client-id", "", "OCM service account client ID. Takes precedence over OCM_CLIENT_ID, OCM_TOKEN and the OCM configuration file")
+	rootCmd.PersistentFlags().String("client-secret", "", "OCM service account client secret. Takes precedence over OCM_CLIENT_SECRET")
+	_ = viper.BindPFlag(utils.OCMClientIDKey, rootCmd.PersistentFlags().Lookup("client-id"))
+	_ = viper.BindPFlag(utils.OCMClientSecretKey, rootCmd.PersistentFlags().Lookup("client-secret"))
 	kubeFlags := globalflags.GetFlags(rootCmd)
 
 	kubeClient := k8s.NewClient(kubeFlags)
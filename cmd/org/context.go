@@ -196,7 +196,9 @@ func Context(orgId string) ([]ClusterInfo, error) {
 	}
 	defer ocmClient.Close()
 
-	var orgClustersInfo []ClusterInfo
+	// The clusters are fetched in parallel, they are kept in the order of their subscriptions
+	orgClustersInfo := make([]ClusterInfo, clusterSubscriptionsCount)
+	plain := printer.PlainOutput()
 
 	eg, ctx := errgroup.WithContext(context.Background())
 	var mutex sync.Mutex
@@ -204,8 +206,11 @@ func Context(orgId string) ([]ClusterInfo, error) {
 
 	// Print these to stderr so the actual results can be piped to different parsers without worrying about these lines
 	_, _ = fmt.Fprintf(os.Stderr, "Fetching data for %v clusters in org %v...\n", clusterSubscriptionsCount, orgId)
-	_, _ = fmt.Fprintf(os.Stderr, "Fetched data for 0 of %v clusters...\n", clusterSubscriptionsCount)
-	for _, subscription := range clusterSubscriptions {
+	if !plain {
+		_, _ = fmt.Fprintf(os.Stderr, "Fetched data for 0 of %v clusters...\n", clusterSubscriptionsCount)
+	}
+	for i, subscription := range clusterSubscriptions {
+		index := i
 		sub := subscription
 		eg.Go(func() error {
 			defer ctx.Done()
@@ -259,20 +264,31 @@ func Context(orgId string) ([]ClusterInfo, error) {
 			}
 
 			mutex.Lock()
-			_, _ = fmt.Fprintf(os.Stderr, "\033[1A\033[K")
 			count++
-			_, _ = fmt.Fprintf(os.Stderr, "Fetched data for %v of %v clusters...\n", count, clusterSubscriptionsCount)
-			orgClustersInfo = append(orgClustersInfo, clusterInfo)
+			// The progress line is redrawn in place, which only makes sense on a terminal
+			if !plain {
+				_, _ = fmt.Fprintf(os.Stderr, "\033[1A\033[K")
+				_, _ = fmt.Fprintf(os.Stderr, "Fetched data for %v of %v clusters...\n", count, clusterSubscriptionsCount)
+			}
+			orgClustersInfo[index] = clusterInfo
 			mutex.Unlock()
 
 			return nil
 		})
 	}
 
-	if err := eg.Wait(); err != nil {
-		return orgClustersInfo, fmt.Errorf("failed to get context data: %w", err)
+	err = eg.Wait()
+	// The clusters which couldn't be fetched are left out
+	fetched := orgClustersInfo[:0]
+	for _, clusterInfo := range orgClustersInfo {
+		if clusterInfo.ID != "" {
+			fetched = append(fetched, clusterInfo)
+		}
+	}
+	if err != nil {
+		return fetched, fmt.Errorf("failed to get context data: %w", err)
 	}
-	return orgClustersInfo, nil
+	return fetched, nil
 }
 
 func addLimitedSupportReasons(clusterInfo *ClusterInfo, ocmClient *sdk.Connection) error {
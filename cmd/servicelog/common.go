@@ -1,13 +1,20 @@
 package servicelog
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
 	sdk "github.com/openshift-online/ocm-sdk-go"
 	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
 	v1 "github.com/openshift-online/ocm-sdk-go/servicelogs/v1"
 	"github.com/openshift/osdctl/internal/servicelog"
 	"github.com/openshift/osdctl/pkg/utils"
+	log "github.com/sirupsen/logrus"
 	"time"
 )
 
@@ -19,43 +26,122 @@ const (
 	// in case you want to see the swagger code gen, you can look at
 	// https://api.openshift.com/?urls.primaryName=Service%20logs#/default/post_api_service_logs_v1_cluster_logs
 	targetAPIPath = "/api/service_logs/v1/cluster_logs"
+
+	// idempotencyKeyHeader is sent with every post so that a retried request is not
+	// recorded twice by OCM
+	idempotencyKeyHeader = "Idempotency-Key"
 )
 
-func validateGoodResponse(body []byte, clusterMessage servicelog.Message) (goodReply *servicelog.GoodReply, err error) {
-	if !json.Valid(body) {
-		return nil, fmt.Errorf("server returned invalid JSON")
+// idempotencyKey returns a deterministic key derived from the cluster ID and the
+// message body. Posting the same message to the same cluster always yields the same key.
+func idempotencyKey(clusterID string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(clusterID))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// unexpectedFields returns the sorted top-level keys of the JSON object in body
+// that have no matching json tag on the struct v points to. Unknown keys are
+// ignored when unmarshalling, so this only exists to surface OCM schema changes.
+func unexpectedFields(body []byte, v interface{}) []string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil
 	}
 
-	if err = json.Unmarshal(body, &goodReply); err != nil {
-		return nil, fmt.Errorf("cannot not parse the JSON template.\nError: %q", err)
+	known := map[string]bool{}
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" {
+			name = t.Field(i).Name
+		}
+		known[name] = true
 	}
 
-	if goodReply.Severity != clusterMessage.Severity {
-		return nil, fmt.Errorf("message sent, but wrong severity information was passed (wanted %q, got %q)", clusterMessage.Severity, goodReply.Severity)
+	var unexpected []string
+	for name := range fields {
+		if !known[name] {
+			unexpected = append(unexpected, name)
+		}
 	}
-	if goodReply.ServiceName != clusterMessage.ServiceName {
-		return nil, fmt.Errorf("message sent, but wrong service_name information was passed (wanted %q, got %q)", clusterMessage.ServiceName, goodReply.ServiceName)
+	sort.Strings(unexpected)
+	return unexpected
+}
+
+// warnUnexpectedFields logs the fields of an OCM reply that osdctl does not know about
+func warnUnexpectedFields(body []byte, v interface{}, verbose bool) {
+	if !verbose {
+		return
 	}
-	if goodReply.ClusterUUID != clusterMessage.ClusterUUID {
-		return nil, fmt.Errorf("message sent, but to different cluster (wanted %q, got %q)", clusterMessage.ClusterUUID, goodReply.ClusterUUID)
+	if fields := unexpectedFields(body, v); len(fields) > 0 {
+		log.Warnf("OCM reply contains fields unknown to osdctl, ignoring them: %s", strings.Join(fields, ", "))
 	}
-	if goodReply.Summary != clusterMessage.Summary {
-		return nil, fmt.Errorf("message sent, but wrong summary information was passed (wanted %q, got %q)", clusterMessage.Summary, goodReply.Summary)
+}
+
+func validateGoodResponse(response *sdk.Response, clusterMessage servicelog.Message, verbose bool) (goodReply *servicelog.GoodReply, err error) {
+	if err := utils.CheckJSONResponse(response); err != nil {
+		return nil, err
 	}
-	if goodReply.Description != clusterMessage.Description {
-		return nil, fmt.Errorf("message sent, but wrong description information was passed (wanted %q, got %q)", clusterMessage.Description, goodReply.Description)
+	body := response.Bytes()
+
+	if err = json.Unmarshal(body, &goodReply); err != nil {
+		return nil, fmt.Errorf("cannot not parse the JSON template.\nError: %q", err)
 	}
+	warnUnexpectedFields(body, goodReply, verbose)
 
+	if err := checkGoodReply(body, goodReply, clusterMessage); err != nil {
+		return nil, err
+	}
 	return goodReply, nil
 }
 
-func validateBadResponse(body []byte) (badReply *servicelog.BadReply, err error) {
-	if ok := json.Valid(body); !ok {
-		return nil, fmt.Errorf("server returned invalid JSON")
+// checkGoodReply checks that the fields of the reply match the message sent. Only the fields present in
+// the reply are compared, a known field missing from it is a warning: OCM may have renamed it.
+func checkGoodReply(body []byte, goodReply *servicelog.GoodReply, clusterMessage servicelog.Message) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return fmt.Errorf("cannot not parse the JSON template.\nError: %q", err)
+	}
+
+	checks := []struct {
+		field    string
+		mismatch string
+		wanted   string
+		got      string
+	}{
+		{"severity", "wrong severity information was passed", clusterMessage.Severity, goodReply.Severity},
+		{"service_name", "wrong service_name information was passed", clusterMessage.ServiceName, goodReply.ServiceName},
+		{"cluster_uuid", "to different cluster", clusterMessage.ClusterUUID, goodReply.ClusterUUID},
+		{"summary", "wrong summary information was passed", clusterMessage.Summary, goodReply.Summary},
+		{"description", "wrong description information was passed", clusterMessage.Description, goodReply.Description},
+	}
+	for _, check := range checks {
+		if _, ok := fields[check.field]; !ok {
+			log.Warnf("OCM reply has no %q field, cannot check that the message was sent with the right one", check.field)
+			continue
+		}
+		if check.got != check.wanted {
+			return fmt.Errorf("message sent, but %s (wanted %q, got %q)", check.mismatch, check.wanted, check.got)
+		}
+	}
+	return nil
+}
+
+func validateBadResponse(response *sdk.Response, verbose bool) (badReply *servicelog.BadReply, err error) {
+	if err := utils.CheckJSONResponse(response); err != nil {
+		return nil, err
 	}
+	body := response.Bytes()
 	if err = json.Unmarshal(body, &badReply); err != nil {
 		return nil, fmt.Errorf("cannot parse the error JSON message %q", err)
 	}
+	warnUnexpectedFields(body, badReply, verbose)
 
 	return badReply, nil
 }
@@ -0,0 +1,73 @@
+package servicelog
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/openshift/osdctl/internal/servicelog"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	body := []byte(`{"summary":"test"}`)
+
+	first := idempotencyKey("cluster-a", body)
+	if first != idempotencyKey("cluster-a", body) {
+		t.Errorf("idempotencyKey() is not deterministic for the same input")
+	}
+	if first == idempotencyKey("cluster-b", body) {
+		t.Errorf("idempotencyKey() returned the same key for different clusters")
+	}
+	if first == idempotencyKey("cluster-a", []byte(`{"summary":"other"}`)) {
+		t.Errorf("idempotencyKey() returned the same key for different bodies")
+	}
+}
+
+func TestUnexpectedFields(t *testing.T) {
+	body := []byte(`{"id":"1","severity":"Info","summary":"s","cluster_uuid":"u","log_type":"x","extra":{"a":1}}`)
+
+	got := unexpectedFields(body, &servicelog.GoodReply{})
+	if want := []string{"extra", "log_type"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpectedFields() = %v, want %v", got, want)
+	}
+	if got := unexpectedFields([]byte(`{"id":"1","reason":"r"}`), &servicelog.BadReply{}); len(got) != 0 {
+		t.Errorf("unexpectedFields() = %v, want none", got)
+	}
+	if got := unexpectedFields([]byte(`[]`), &servicelog.BadReply{}); got != nil {
+		t.Errorf("unexpectedFields() = %v for a non-object body, want nil", got)
+	}
+}
+
+func TestCheckGoodReply(t *testing.T) {
+	message := servicelog.Message{Severity: "Info", ServiceName: "SREManualAction", ClusterUUID: "u", Summary: "s", Description: "d"}
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{
+			name: "Matching reply",
+			body: `{"severity":"Info","service_name":"SREManualAction","cluster_uuid":"u","summary":"s","description":"d"}`,
+		},
+		{
+			name:    "Mismatching summary",
+			body:    `{"severity":"Info","service_name":"SREManualAction","cluster_uuid":"u","summary":"other","description":"d"}`,
+			wantErr: true,
+		},
+		{
+			name: "Renamed field",
+			body: `{"severity":"Info","service":"SREManualAction","cluster_uuid":"u","summary":"s","description":"d"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reply servicelog.GoodReply
+			if err := json.Unmarshal([]byte(tt.body), &reply); err != nil {
+				t.Fatal(err)
+			}
+			if err := checkGoodReply([]byte(tt.body), &reply, message); (err != nil) != tt.wantErr {
+				t.Errorf("checkGoodReply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -40,13 +40,21 @@ type PostCmdOptions struct {
 	clustersFile    string
 	internalOnly    bool
 	ClusterId       string
+	maxClusters     int
+	output          string
+	verbose         bool
 
 	// Messaged clusters
 	successfulClusters map[string]string
 	failedClusters     map[string]string
 }
 
-const documentationBaseURL = "https://docs.openshift.com"
+const (
+	documentationBaseURL = "https://docs.openshift.com"
+
+	// defaultMaxClusters is the number of clusters a single run may post to unless raised with --max-clusters
+	defaultMaxClusters = 100
+)
 
 func newPostCmd() *cobra.Command {
 	var opts = PostCmdOptions{}
@@ -72,6 +80,7 @@ func newPostCmd() *cobra.Command {
 			if len(args) > 0 {
 				opts.ClusterId = args[0]
 			}
+			opts.output, _ = cmd.Flags().GetString("output")
 			return opts.Run()
 		},
 	}
@@ -85,6 +94,8 @@ func newPostCmd() *cobra.Command {
 	postCmd.Flags().StringArrayVarP(&opts.filterFiles, "query-file", "f", []string{}, "File containing search queries to apply. All lines in the file will be concatenated into a single query. If this flag is called multiple times, every file's search query will be combined with logical AND.")
 	postCmd.Flags().StringVarP(&opts.clustersFile, "clusters-file", "c", "", `Read a list of clusters to post the servicelog to. the format of the file is: {"clusters":["$CLUSTERID"]}`)
 	postCmd.Flags().BoolVarP(&opts.internalOnly, "internal", "i", false, "Internal only service log. Use MESSAGE for template parameter (eg. -p MESSAGE='My super secret message').")
+	postCmd.Flags().IntVar(&opts.maxClusters, "max-clusters", defaultMaxClusters, "Abort before posting if the given filters match more than this number of clusters.")
+	postCmd.Flags().BoolVarP(&opts.verbose, "verbose", "", false, "Verbose output")
 
 	return postCmd
 }
@@ -101,6 +112,9 @@ func (o *PostCmdOptions) Validate() error {
 	if o.ClusterId == "" && len(o.filterParams) == 0 && o.clustersFile == "" {
 		return fmt.Errorf("no cluster identifier has been found")
 	}
+	if o.maxClusters < 1 {
+		return fmt.Errorf("--max-clusters must be a positive number, got %d", o.maxClusters)
+	}
 	return nil
 }
 
@@ -197,6 +211,8 @@ func (o *PostCmdOptions) Run() error {
 		return fmt.Errorf("failed to search for clusters with provided filters (%v): %v", o.filterParams, err)
 	} else if len(clusters) < 1 {
 		return fmt.Errorf("no clusters match the given filters (%v)", o.filterParams)
+	} else if len(clusters) > o.maxClusters {
+		return fmt.Errorf("the given filters match %d clusters, which exceeds the limit of %d. Narrow the filters or raise the limit with '--max-clusters'", len(clusters), o.maxClusters)
 	}
 
 	log.Infoln("The following clusters match the given parameters:")
@@ -245,7 +261,13 @@ func (o *PostCmdOptions) Run() error {
 	// cluster type for which documentation link is provided in servicelog description
 	docClusterType := getDocClusterType(o.Message.Description)
 
+	// Only show progress for batches on an interactive terminal, never in machine readable mode
+	showProgress := len(clusters) > 1 && o.output != "json" && term.IsTerminal(int(os.Stdout.Fd())) && term.IsTerminal(int(os.Stderr.Fd()))
+	progress := ocmutils.StartProgressTracker(os.Stderr, showProgress, "Posting service logs", len(clusters))
+
 	for _, cluster := range clusters {
+		progress.Increment()
+
 		request, err := o.createPostRequest(ocmClient, cluster)
 		if err != nil {
 			o.failedClusters[cluster.ExternalID()] = err.Error()
@@ -258,11 +280,14 @@ func (o *PostCmdOptions) Run() error {
 			clusterType := cluster.Product().ID()
 
 			if docClusterType != clusterType {
+				progress.Clear()
 				log.Warn("The documentation mentioned in the servicelog is for '", docClusterType, "' while the product is '", clusterType, "'.")
 				if !ocmutils.ConfirmPrompt() {
 					log.Info("Skipping cluster ID: ", cluster.ID(), ", Name: ", cluster.Name())
+					progress.Redraw()
 					continue
 				}
+				progress.Redraw()
 			}
 		}
 
@@ -274,8 +299,12 @@ func (o *PostCmdOptions) Run() error {
 
 		o.check(response, o.Message)
 	}
+	progress.End()
 
 	o.printPostOutput()
+	if len(o.failedClusters) > 0 {
+		return fmt.Errorf("failed to post the service log to %d cluster(s)", len(o.failedClusters))
+	}
 	return nil
 }
 
@@ -302,16 +331,15 @@ func getDocClusterType(message string) string {
 }
 
 func (o *PostCmdOptions) check(response *sdk.Response, clusterMessage servicelog.Message) {
-	body := response.Bytes()
 	if response.Status() < 400 {
-		_, err := validateGoodResponse(body, clusterMessage)
+		goodReply, err := validateGoodResponse(response, clusterMessage, o.verbose)
 		if err != nil {
 			o.failedClusters[clusterMessage.ClusterUUID] = err.Error()
 		} else {
-			o.successfulClusters[clusterMessage.ClusterUUID] = fmt.Sprintf("Message has been successfully sent to %s", clusterMessage.ClusterUUID)
+			o.successfulClusters[clusterMessage.ClusterUUID] = fmt.Sprintf("Message has been successfully sent to %s at %s", clusterMessage.ClusterUUID, goodReply.CreatedAt.Format(time.RFC3339))
 		}
 	} else {
-		badReply, err := validateBadResponse(body)
+		badReply, err := validateBadResponse(response, o.verbose)
 		if err != nil {
 			o.failedClusters[clusterMessage.ClusterUUID] = err.Error()
 		} else {
@@ -513,6 +541,7 @@ func (o *PostCmdOptions) createPostRequest(ocmClient *sdk.Connection, cluster *v
 		return nil, fmt.Errorf("cannot marshal template to json: %v", err)
 	}
 
+	request.Header(idempotencyKeyHeader, idempotencyKey(cluster.ID(), messageBytes))
 	request.Bytes(messageBytes)
 	return request, nil
 }
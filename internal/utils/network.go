@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -60,3 +61,36 @@ func CurlThis(webpage string) (body []byte, err error) {
 	}
 	return body, err
 }
+
+// ErrTooLarge is returned when reading more than the allowed number of bytes
+var ErrTooLarge = errors.New("content is too large")
+
+// ReadLimited reads r entirely, unless it holds more than maxBytes, in which case ErrTooLarge is returned
+// without reading further
+func ReadLimited(r io.Reader, maxBytes int64) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > maxBytes {
+		return nil, fmt.Errorf("%w, it exceeds %d bytes", ErrTooLarge, maxBytes)
+	}
+	return body, nil
+}
+
+// CurlThisLimited works like CurlThis, but stops downloading and returns ErrTooLarge once the body
+// exceeds maxBytes
+func CurlThisLimited(webpage string, maxBytes int64) ([]byte, error) {
+	resp, err := http.Get(webpage) //#nosec G107 -- url cannot be constant
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil
+	}
+	if resp.ContentLength > maxBytes {
+		return nil, fmt.Errorf("%w, it is %d bytes which exceeds %d bytes", ErrTooLarge, resp.ContentLength, maxBytes)
+	}
+	return ReadLimited(resp.Body, maxBytes)
+}
@@ -0,0 +1,28 @@
+package printer
+
+import (
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// PlainOutput is true when NO_COLOR (https://no-color.org) or CI is set in the environment. Colors, progress
+// lines and other terminal control sequences are then left out, so that the output is stable in logs.
+// CI set to 'false' or '0' doesn't count, as some runners export it that way outside of pipelines.
+func PlainOutput() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return true
+	}
+	switch strings.ToLower(os.Getenv("CI")) {
+	case "", "false", "0":
+		return false
+	}
+	return true
+}
+
+func init() {
+	if PlainOutput() {
+		color.NoColor = true
+	}
+}
@@ -0,0 +1,29 @@
+package printer
+
+import "testing"
+
+func TestPlainOutput(t *testing.T) {
+	testCases := []struct {
+		title   string
+		noColor string
+		ci      string
+		plain   bool
+	}{
+		{title: "neither is set"},
+		{title: "NO_COLOR is set", noColor: "1", plain: true},
+		{title: "CI is set", ci: "true", plain: true},
+		{title: "CI is set by a runner", ci: "woodpecker", plain: true},
+		{title: "CI is disabled", ci: "false"},
+		{title: "CI is zero", ci: "0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tc.noColor)
+			t.Setenv("CI", tc.ci)
+			if plain := PlainOutput(); plain != tc.plain {
+				t.Errorf("PlainOutput() = %v, want %v", plain, tc.plain)
+			}
+		})
+	}
+}
@@ -31,8 +31,11 @@ func (p *printer) Flush() error {
 	return p.w.Flush()
 }
 
-// ClearScreen clears all output on screen.
+// ClearScreen clears all output on screen, unless the output is plain.
 func (p *printer) ClearScreen() {
+	if PlainOutput() {
+		return
+	}
 	fmt.Fprint(os.Stdout, "\033[2J")
 	fmt.Fprint(os.Stdout, "\033[H")
 }
@@ -1,23 +1,57 @@
 package utils
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/google/uuid"
 	sdk "github.com/openshift-online/ocm-sdk-go"
+	amv1 "github.com/openshift-online/ocm-sdk-go/accountsmgmt/v1"
 	cmv1 "github.com/openshift-online/ocm-sdk-go/clustersmgmt/v1"
+	"github.com/spf13/viper"
 )
 
 const ClusterServiceClusterSearch = "id = '%s' or name = '%s' or external_id = '%s'"
 
+const (
+	// OCMClientIDKey and OCMClientSecretKey hold the OCM service account credentials given on the command line
+	OCMClientIDKey     = "ocm_client_id"
+	OCMClientSecretKey = "ocm_client_secret"
+
+	// OCMClientCertKey, OCMClientKeyKey and OCMCACertKey hold the files used for mutual TLS with the OCM gateway
+	OCMClientCertKey = "ocm_client_cert"
+	OCMClientKeyKey  = "ocm_client_key"
+	OCMCACertKey     = "ocm_ca_cert"
+
+	// OCMMaxIdleConnsKey and OCMIdleConnTimeoutKey tune the pool of idle connections kept open to the OCM
+	// gateway, zero keeps the defaults of the OCM SDK
+	OCMMaxIdleConnsKey    = "ocm_max_idle_conns"
+	OCMIdleConnTimeoutKey = "ocm_idle_conn_timeout"
+
+	// OCMCommandPathKey holds the osdctl subcommand being run, it is reported in the User-Agent of OCM requests
+	OCMCommandPathKey = "ocm_command_path"
+
+	// OCMProfileKey holds the name of the OCM profile to connect with, profiles are defined under
+	// OCMProfilesKey in the osdctl configuration file
+	OCMProfileKey  = "ocm_profile"
+	OCMProfilesKey = "ocm_profiles"
+)
+
 const (
 	productionURL    = "https://api.openshift.com"
 	stagingURL       = "https://api.stage.openshift.com"
@@ -59,6 +93,24 @@ type Config struct {
 	Pager        string   `json:"pager,omitempty" doc:"Pager command, for example 'less'. If empty no pager will be used."`
 }
 
+// OCMProfile is a named OCM environment defined in the osdctl configuration file, eg.
+//
+//	ocm_profiles:
+//	  stage:
+//	    url: staging
+//	    client_id: my-service-account
+//	    client_secret: my-secret
+//	  prod:
+//	    url: production
+//	    ocm_config: ~/.config/ocm/ocm.prod.json
+type OCMProfile struct {
+	URL          string `mapstructure:"url"`
+	ClientID     string `mapstructure:"client_id"`
+	ClientSecret string `mapstructure:"client_secret"`
+	// OCMConfig is the OCM configuration file holding the tokens of this environment, as written by 'ocm login' with OCM_CONFIG set
+	OCMConfig string `mapstructure:"ocm_config"`
+}
+
 // GetClusterAnyStatus returns an OCM cluster object given an OCM connection and cluster id
 // (internal id, external id, and name all supported).
 func GetClusterAnyStatus(conn *sdk.Connection, clusterId string) (*cmv1.Cluster, error) {
@@ -124,25 +176,57 @@ func ApplyFilters(ocmClient *sdk.Connection, filters []string) ([]*cmv1.Cluster,
 	requestSize := 50
 	full_filters := strings.Join(filters, " and ")
 
-	request := ocmClient.ClustersMgmt().V1().Clusters().List().Search(full_filters).Size(requestSize)
-	response, err := request.Send()
-	if err != nil {
-		return nil, err
-	}
-
-	items := response.Items().Slice()
-	for response.Size() >= requestSize {
-		request.Page(response.Page() + 1)
-		response, err = request.Send()
+	request := ocmClient.ClustersMgmt().V1().Clusters().List().Search(full_filters)
+	items, _, err := collectPages(requestSize, 0, func(page int, size int) ([]*cmv1.Cluster, int, error) {
+		response, err := request.Page(page).Size(size).Send()
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
-		items = append(items, response.Items().Slice()...)
-	}
+		return response.Items().Slice(), response.Total(), nil
+	})
+	return items, err
+}
 
+// SearchSubscriptions retrieves all subscriptions in OCM which match the search query given
+func SearchSubscriptions(ocmClient *sdk.Connection, search string) ([]*amv1.Subscription, error) {
+	items, _, err := SearchSubscriptionsLimit(ocmClient, search, 0)
 	return items, err
 }
 
+// SearchSubscriptionsLimit is SearchSubscriptions returning at most maxResults subscriptions, zero means no
+// limit. truncated is true when more subscriptions match the search than were returned.
+func SearchSubscriptionsLimit(ocmClient *sdk.Connection, search string, maxResults int) (items []*amv1.Subscription, truncated bool, err error) {
+	request := ocmClient.AccountsMgmt().V1().Subscriptions().List().Search(search)
+	return collectPages(100, maxResults, func(page int, size int) ([]*amv1.Subscription, int, error) {
+		response, err := request.Page(page).Size(size).Send()
+		if err != nil {
+			return nil, 0, err
+		}
+		return response.Items().Slice(), response.Total(), nil
+	})
+}
+
+// collectPages calls fetch for every page of a paginated OCM list until all total matches were returned or a
+// page comes back short, so that large result sets are complete. With maxResults > 0 it stops once that
+// many items were collected and reports whether more items matched.
+func collectPages[T any](pageSize int, maxResults int, fetch func(page int, size int) ([]T, int, error)) (items []T, truncated bool, err error) {
+	for page := 1; ; page++ {
+		pageItems, total, err := fetch(page, pageSize)
+		if err != nil {
+			return nil, false, err
+		}
+		items = append(items, pageItems...)
+
+		if maxResults > 0 && len(items) >= maxResults {
+			return items[:maxResults], len(items) > maxResults || total > maxResults, nil
+		}
+		// OCM reports the total number of matches, a short page is a safety net against an inconsistent total
+		if len(pageItems) < pageSize || (total > 0 && len(items) >= total) {
+			return items, false, nil
+		}
+	}
+}
+
 // GenerateQuery returns an OCM search query to retrieve all clusters matching an expression (ie- "foo%")
 func GenerateQuery(clusterIdentifier string) string {
 	// Based on the format of the clusterIdentifier, we can know what it is, so we can simplify ocm query and make it quicker
@@ -188,13 +272,18 @@ func getOCMConfigLocation() (string, error) {
 // Loads the OCM Configuration file
 // Taken wholesale from	openshift-online/ocm-cli
 func loadOCMConfig() (*Config, error) {
-	var err error
-
 	file, err := getOCMConfigLocation()
 	if err != nil {
 		return nil, err
 	}
 
+	return loadOCMConfigFile(file)
+}
+
+// Loads the given OCM Configuration file
+func loadOCMConfigFile(file string) (*Config, error) {
+	var err error
+
 	_, err = os.Stat(file)
 	if os.IsNotExist(err) {
 		cfg := &Config{}
@@ -228,11 +317,29 @@ func loadOCMConfig() (*Config, error) {
 	return cfg, nil
 }
 
+// getOcmConfiguration resolves the OCM configuration from the environment and the OCM configuration file.
+//
+// Credentials are picked in the following order:
+//  1. service account client ID and secret given with --client-id/--client-secret
+//  2. service account client ID and secret from OCM_CLIENT_ID/OCM_CLIENT_SECRET
+//  3. tokens from OCM_TOKEN/OCM_REFRESH_TOKEN
+//  4. the OCM configuration file, as written by 'ocm login'
+//
+// When service account credentials are found, any token is ignored so that the client-credentials
+// grant is used.
 func getOcmConfiguration(ocmConfigLoader func() (*Config, error)) (*Config, error) {
 	tokenEnv := os.Getenv("OCM_TOKEN")
 	urlEnv := os.Getenv("OCM_URL")
 	refreshTokenEnv := os.Getenv("OCM_REFRESH_TOKEN") // Unlikely to be set, but check anyway
 
+	clientID, clientSecret := viper.GetString(OCMClientIDKey), viper.GetString(OCMClientSecretKey)
+	if clientID == "" && clientSecret == "" {
+		clientID, clientSecret = os.Getenv("OCM_CLIENT_ID"), os.Getenv("OCM_CLIENT_SECRET")
+	}
+	if (clientID == "") != (clientSecret == "") {
+		return nil, fmt.Errorf("both a client ID and a client secret are required to use an OCM service account")
+	}
+
 	config := &Config{}
 
 	// If missing required data, load from the config file.
@@ -257,20 +364,132 @@ func getOcmConfiguration(ocmConfigLoader func() (*Config, error)) (*Config, erro
 		config.RefreshToken = refreshTokenEnv
 	}
 
+	if clientID != "" {
+		config.ClientID = clientID
+		config.ClientSecret = clientSecret
+		config.AccessToken = ""
+		config.RefreshToken = ""
+	}
+
 	return config, nil
 }
 
-func CreateConnection() (*sdk.Connection, error) {
+// getOCMProfile returns the OCM profile called name from the osdctl configuration file, or nil if name is empty
+func getOCMProfile(name string) (*OCMProfile, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	profiles := map[string]OCMProfile{}
+	if err := viper.UnmarshalKey(OCMProfilesKey, &profiles); err != nil {
+		return nil, fmt.Errorf("can't parse '%s' in the osdctl configuration file: %v", OCMProfilesKey, err)
+	}
+
+	// viper lowercases the keys of the configuration file
+	profile, ok := profiles[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("OCM profile '%s' is not defined under '%s' in the osdctl configuration file", name, OCMProfilesKey)
+	}
+	if (profile.ClientID == "") != (profile.ClientSecret == "") {
+		return nil, fmt.Errorf("OCM profile '%s' needs both a client ID and a client secret to use an OCM service account", name)
+	}
+
+	if strings.HasPrefix(profile.OCMConfig, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		profile.OCMConfig = filepath.Join(home, profile.OCMConfig[2:])
+	}
+
+	return &profile, nil
+}
+
+// applyOCMProfile overrides the OCM URL and service account credentials of config with the ones of the profile.
+// Service account credentials given with --client-id/--client-secret still take precedence over the profile.
+func applyOCMProfile(config *Config, profile *OCMProfile) {
+	if profile.URL != "" {
+		config.URL = profile.URL
+	}
+
+	if profile.ClientID != "" && viper.GetString(OCMClientIDKey) == "" {
+		config.ClientID = profile.ClientID
+		config.ClientSecret = profile.ClientSecret
+		config.AccessToken = ""
+		config.RefreshToken = ""
+	}
+}
+
+// ConnectionOption customizes the OCM connection built by CreateConnection
+type ConnectionOption func(*connectionOptions)
+
+type connectionOptions struct {
+	withoutSDKRetries bool
+	retryAfterJitter  float64
+}
+
+// apply configures the connection builder with the options which are handled by the OCM SDK
+func (o connectionOptions) apply(connectionBuilder *sdk.ConnectionBuilder) {
+	if o.withoutSDKRetries {
+		connectionBuilder.RetryLimit(0)
+	}
+}
+
+// WithoutSDKRetries disables the retries of the OCM SDK, for the commands retrying failed requests on their
+// own. Otherwise the attempts of both multiply.
+func WithoutSDKRetries() ConnectionOption {
+	return func(o *connectionOptions) {
+		o.withoutSDKRetries = true
+	}
+}
+
+// WithRetryAfterJitter spreads the requests held back by a 429 Too Many Requests over the jitter fraction of the
+// Retry-After period, once it is over. 1 spreads them over a whole period, 0 resumes them all at once.
+func WithRetryAfterJitter(jitter float64) ConnectionOption {
+	return func(o *connectionOptions) {
+		o.retryAfterJitter = jitter
+	}
+}
+
+func CreateConnection(options ...ConnectionOption) (*sdk.Connection, error) {
 	ocmConfigError := "Unable to load OCM config\nLogin with 'ocm login' or set OCM_TOKEN, OCM_URL and OCM_REFRESH_TOKEN environment variables"
 
+	var opts connectionOptions
+	for _, option := range options {
+		option(&opts)
+	}
+
+	if dir := viper.GetString(OCMReplayDirKey); dir != "" {
+		return createReplayConnection(dir, opts)
+	}
+
 	connectionBuilder := sdk.NewConnectionBuilder()
+	opts.apply(connectionBuilder)
 
-	config, err := getOcmConfiguration(loadOCMConfig)
+	profile, err := getOCMProfile(viper.GetString(OCMProfileKey))
 	if err != nil {
-		return nil, errors.New(ocmConfigError)
+		return nil, err
+	}
+
+	configLoader := loadOCMConfig
+	if profile != nil && profile.OCMConfig != "" {
+		configLoader = func() (*Config, error) {
+			return loadOCMConfigFile(profile.OCMConfig)
+		}
+	}
+
+	config, err := getOcmConfiguration(configLoader)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", ocmConfigError, err)
+	}
+	if profile != nil {
+		applyOCMProfile(config, profile)
 	}
 
-	connectionBuilder.Tokens(config.AccessToken, config.RefreshToken)
+	// Service accounts authenticate with the client-credentials grant only
+	if config.AccessToken != "" || config.RefreshToken != "" || config.ClientID == "" {
+		connectionBuilder.Tokens(config.AccessToken, config.RefreshToken)
+	}
 
 	if config.URL == "" {
 		return nil, errors.New(ocmConfigError)
@@ -284,6 +503,26 @@ func CreateConnection() (*sdk.Connection, error) {
 	connectionBuilder.URL(gatewayURL)
 
 	connectionBuilder.Client(config.ClientID, config.ClientSecret)
+	// Like the 429 wrapper, it has to be added before the wrappers which expect to wrap the HTTP transport directly
+	if dir := viper.GetString(OCMRecordDirKey); dir != "" {
+		wrapper, err := recordWrapper(dir)
+		if err != nil {
+			return nil, err
+		}
+		connectionBuilder.TransportWrapper(wrapper)
+	}
+	// Keeps the bodies of the requests sent with WithBodyCapture
+	connectionBuilder.TransportWrapper(BodyCaptureWrapper)
+	// A 429 on any request pauses every request sent through the connection for the Retry-After period.
+	// It has to be added before the TLS wrapper, which expects to wrap the HTTP transport directly.
+	connectionBuilder.TransportWrapper(retryAfterWrapper(os.Stderr, opts.retryAfterJitter))
+	if err := configureTLS(connectionBuilder, viper.GetString(OCMClientCertKey), viper.GetString(OCMClientKeyKey), viper.GetString(OCMCACertKey)); err != nil {
+		return nil, err
+	}
+	if err := configureConnectionPool(connectionBuilder, viper.GetInt(OCMMaxIdleConnsKey), viper.GetDuration(OCMIdleConnTimeoutKey)); err != nil {
+		return nil, err
+	}
+	connectionBuilder.Agent(userAgent(Version, viper.GetString(OCMCommandPathKey)))
 
 	connection, err := connectionBuilder.Build()
 
@@ -297,6 +536,83 @@ func CreateConnection() (*sdk.Connection, error) {
 	return connection, nil
 }
 
+// configureTLS sets up mutual TLS with the OCM gateway when a client certificate is given, and trusts the
+// given CA bundle on top of the system ones. The client certificate and key must be given together.
+func configureTLS(connectionBuilder *sdk.ConnectionBuilder, certFile string, keyFile string, caFile string) error {
+	if (certFile == "") != (keyFile == "") {
+		return errors.New("both a client certificate and a client key are required to use mutual TLS with OCM")
+	}
+
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("can't load the OCM client certificate '%s' and key '%s': %v", certFile, keyFile, err)
+		}
+		connectionBuilder.TransportWrapper(func(wrapped http.RoundTripper) http.RoundTripper {
+			if transport, ok := wrapped.(*http.Transport); ok && transport.TLSClientConfig != nil {
+				transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+			}
+			return wrapped
+		})
+	}
+
+	if caFile != "" {
+		if _, err := os.Stat(caFile); err != nil {
+			return fmt.Errorf("can't read the OCM CA certificate '%s': %v", caFile, err)
+		}
+		connectionBuilder.TrustedCAFile(caFile)
+	}
+
+	return nil
+}
+
+// configureConnectionPool sets how many idle connections to the OCM gateway are kept open for reuse and for how
+// long. The SDK keeps 2 idle connections per host without a timeout, which causes connection churn when a batch
+// sends many requests in a row. Zero values keep the SDK defaults.
+func configureConnectionPool(connectionBuilder *sdk.ConnectionBuilder, maxIdleConns int, idleConnTimeout time.Duration) error {
+	if maxIdleConns < 0 {
+		return errors.New("the maximum number of idle OCM connections cannot be negative")
+	}
+	if idleConnTimeout < 0 {
+		return errors.New("the idle OCM connection timeout cannot be negative")
+	}
+	if maxIdleConns == 0 && idleConnTimeout == 0 {
+		return nil
+	}
+	// Like the TLS wrapper, it expects to wrap the HTTP transport directly
+	connectionBuilder.TransportWrapper(connectionPoolWrapper(maxIdleConns, idleConnTimeout))
+	return nil
+}
+
+func connectionPoolWrapper(maxIdleConns int, idleConnTimeout time.Duration) func(http.RoundTripper) http.RoundTripper {
+	return func(wrapped http.RoundTripper) http.RoundTripper {
+		if transport, ok := wrapped.(*http.Transport); ok {
+			if maxIdleConns > 0 {
+				// Every request goes to the OCM gateway, the limit per host is the one that matters
+				transport.MaxIdleConns = maxIdleConns
+				transport.MaxIdleConnsPerHost = maxIdleConns
+			}
+			if idleConnTimeout > 0 {
+				transport.IdleConnTimeout = idleConnTimeout
+			}
+		}
+		return wrapped
+	}
+}
+
+// userAgent returns the User-Agent sent with OCM requests, so that OCM can attribute traffic to
+// osdctl versions and subcommands, eg. "osdctl/0.20.0 (osdctl cluster support post)"
+func userAgent(version string, commandPath string) string {
+	if version == "" {
+		version = "dev"
+	}
+	agent := "osdctl/" + version
+	if commandPath != "" {
+		agent += " (" + commandPath + ")"
+	}
+	return agent
+}
+
 func GetSupportRoleArnForCluster(ocmClient *sdk.Connection, clusterID string) (string, error) {
 
 	clusterResponse, err := ocmClient.ClustersMgmt().V1().Clusters().Cluster(clusterID).Get().Send()
@@ -454,8 +770,83 @@ func GetManagementCluster(clusterId string) (*cmv1.Cluster, error) {
 	return nil, fmt.Errorf("no management cluster found for %s", clusterId)
 }
 
+// maxResponseSnippet is the number of bytes of an unexpected response body shown to the user
+const maxResponseSnippet = 200
+
+// InvalidJSONResponseError returns the error to report when a response body is not valid JSON.
+// HTML bodies usually mean the request never reached OCM (a proxy error page or a wrong OCM URL),
+// so the HTTP status and the beginning of the body are included to make that obvious.
+func InvalidJSONResponseError(status int, contentType string, body []byte) error {
+	trimmed := strings.TrimSpace(string(body))
+	isHTML := strings.Contains(contentType, "text/html") || strings.HasPrefix(strings.ToLower(trimmed), "<!doctype html") || strings.HasPrefix(strings.ToLower(trimmed), "<html")
+	if isHTML {
+		return fmt.Errorf("server returned an HTML page instead of JSON (HTTP status %d), this is likely a proxy or OCM endpoint issue rather than a template problem. Response starts with: %q", status, responseSnippet(trimmed))
+	}
+	if trimmed == "" {
+		return fmt.Errorf("server returned an empty body instead of JSON (HTTP status %d)", status)
+	}
+	return fmt.Errorf("server returned invalid JSON (HTTP status %d). Response starts with: %q", status, responseSnippet(trimmed))
+}
+
+// responseSnippet returns the beginning of the body, cut at most maxResponseSnippet bytes in without splitting a
+// UTF-8 character
+func responseSnippet(body string) string {
+	return Truncate(body, maxResponseSnippet)
+}
+
+// Truncate cuts s to at most length bytes followed by "...", on a character boundary so that a multi-byte
+// character is never split
+func Truncate(s string, length int) string {
+	if len(s) <= length {
+		return s
+	}
+	end := length
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end] + "..."
+}
+
+// ErrIncompleteResponse is wrapped by the errors reporting a response body which was cut short, eg. by a
+// connection interrupted while the body was being received, as opposed to a body which isn't JSON
+var ErrIncompleteResponse = errors.New("incomplete response")
+
+// CheckJSONResponse returns an error when the body of the response isn't complete, valid JSON. The SDK reads the
+// whole body, chunked or not, before returning the response, so a body shorter than its Content-Length or ending
+// in the middle of a JSON value was truncated and is reported with ErrIncompleteResponse rather than as invalid JSON.
+func CheckJSONResponse(response *sdk.Response) error {
+	return checkJSONBody(response.Status(), response.Header, response.Bytes())
+}
+
+func checkJSONBody(status int, header func(string) string, body []byte) error {
+	if length, err := strconv.Atoi(header("Content-Length")); err == nil && len(body) < length {
+		return fmt.Errorf("%w from the server (HTTP status %d): received %d of %d bytes, retry the command", ErrIncompleteResponse, status, len(body), length)
+	}
+	if json.Valid(body) {
+		return nil
+	}
+	if truncatedJSON(body) {
+		return fmt.Errorf("%w from the server (HTTP status %d): the JSON body ends after %d bytes, retry the command", ErrIncompleteResponse, status, len(body))
+	}
+	return InvalidJSONResponseError(status, header("Content-Type"), body)
+}
+
+// truncatedJSON tells whether the body is the beginning of a JSON value which ends prematurely
+func truncatedJSON(body []byte) bool {
+	var value json.RawMessage
+	return errors.Is(json.NewDecoder(bytes.NewReader(body)).Decode(&value), io.ErrUnexpectedEOF)
+}
+
 func SendRequest(request *sdk.Request) (*sdk.Response, error) {
-	response, err := request.Send()
+	return SendRequestContext(context.Background(), request)
+}
+
+// SendRequestContext sends the request like SendRequest, with the context
+func SendRequestContext(ctx context.Context, request *sdk.Request) (*sdk.Response, error) {
+	response, err := request.SendContext(ctx)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("%w from the server, the connection was closed before the whole body was received: %v", ErrIncompleteResponse, err)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("cannot send request: %q", err)
 	}
@@ -1,8 +1,17 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
 	"os"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	"github.com/spf13/viper"
 )
 
 func resetEnvVars(t *testing.T) {
@@ -179,3 +188,307 @@ func TestGenerateQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestGetOCMConfigurationWithClientCredentials(t *testing.T) {
+	resetEnvVars(t)
+	defer resetEnvVars(t)
+	defer os.Unsetenv("OCM_CLIENT_ID")
+	defer os.Unsetenv("OCM_CLIENT_SECRET")
+
+	if err := os.Setenv("OCM_CLIENT_ID", "my-client"); err != nil {
+		t.Fatal("Error setting OCM_CLIENT_ID")
+	}
+	if err := os.Setenv("OCM_CLIENT_SECRET", "my-secret"); err != nil {
+		t.Fatal("Error setting OCM_CLIENT_SECRET")
+	}
+
+	config, err := getOcmConfiguration(func() (*Config, error) {
+		return &Config{
+			URL:          "https://example.com",
+			AccessToken:  "asdf",
+			RefreshToken: "fdsa",
+		}, nil
+	})
+	assertConfigValues(t, config, err, "https://example.com", "", "")
+	if config.ClientID != "my-client" || config.ClientSecret != "my-secret" {
+		t.Errorf("Expected client credentials from the environment, got %q/%q", config.ClientID, config.ClientSecret)
+	}
+
+	if err := os.Unsetenv("OCM_CLIENT_SECRET"); err != nil {
+		t.Fatal("Error unsetting OCM_CLIENT_SECRET")
+	}
+	if _, err := getOcmConfiguration(func() (*Config, error) { return &Config{}, nil }); err == nil {
+		t.Errorf("Expected an error when only the client ID is set")
+	}
+}
+
+func TestInvalidJSONResponseError(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantHTML    bool
+	}{
+		{"html content type", "text/html; charset=utf-8", "Bad Gateway", true},
+		{"html body without content type", "", "<!DOCTYPE html><html><body>Proxy error</body></html>", true},
+		{"truncated json", "application/json", `{"kind":`, false},
+		{"plain text", "text/plain", "upstream connect error", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := InvalidJSONResponseError(502, tt.contentType, []byte(tt.body))
+			if err == nil {
+				t.Fatal("InvalidJSONResponseError() returned nil")
+			}
+			if gotHTML := strings.Contains(err.Error(), "HTML page"); gotHTML != tt.wantHTML {
+				t.Errorf("InvalidJSONResponseError() = %q, want HTML detection %t", err, tt.wantHTML)
+			}
+			if !strings.Contains(err.Error(), "502") {
+				t.Errorf("InvalidJSONResponseError() = %q, want the HTTP status", err)
+			}
+			// Any body which isn't JSON is shown, not only HTML pages
+			if !strings.Contains(err.Error(), fmt.Sprintf("%q", tt.body)) {
+				t.Errorf("InvalidJSONResponseError() = %q, want the body", err)
+			}
+		})
+	}
+
+	// The snippet is cut on a character boundary
+	body := strings.Repeat("a", maxResponseSnippet-1) + strings.Repeat("é", 10)
+	snippet := responseSnippet(body)
+	if !utf8.ValidString(snippet) || snippet != strings.Repeat("a", maxResponseSnippet-1)+"..." {
+		t.Errorf("responseSnippet() = %q, want the body cut before the split character", snippet)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s      string
+		length int
+		want   string
+	}{
+		{"short", 10, "short"},
+		{"abcdef", 3, "abc..."},
+		// "é" is 2 bytes, the 3rd byte is in the middle of the 2nd one
+		{"éé", 3, "é..."},
+		{"日本", 2, "..."},
+	}
+	for _, tt := range tests {
+		if got := Truncate(tt.s, tt.length); got != tt.want || !utf8.ValidString(got) {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.s, tt.length, got, tt.want)
+		}
+	}
+}
+
+func TestCheckJSONBody(t *testing.T) {
+	tests := []struct {
+		name           string
+		header         map[string]string
+		body           string
+		wantErr        bool
+		wantIncomplete bool
+	}{
+		{"complete json", map[string]string{"Content-Length": "19"}, `{"kind":"Reason"}  `, false, false},
+		{"chunked json", nil, `{"kind":"Reason"}`, false, false},
+		{"shorter than content length", map[string]string{"Content-Length": "100"}, `{"kind":"Reason"}`, true, true},
+		{"json cut in an object", nil, `{"kind":"Reas`, true, true},
+		{"json cut in a literal", nil, `{"enabled":tr`, true, true},
+		{"not json", nil, `Service Unavailable`, true, false},
+		{"html", map[string]string{"Content-Type": "text/html"}, `<html><body>Bad Gateway</body></html>`, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkJSONBody(502, func(name string) string { return tt.header[name] }, []byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkJSONBody() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if gotIncomplete := errors.Is(err, ErrIncompleteResponse); gotIncomplete != tt.wantIncomplete {
+				t.Errorf("checkJSONBody() = %v, want incomplete %t", err, tt.wantIncomplete)
+			}
+		})
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	if got := userAgent("1.2.3", "osdctl cluster support post"); got != "osdctl/1.2.3 (osdctl cluster support post)" {
+		t.Errorf("userAgent() = %q", got)
+	}
+	if got := userAgent("", ""); got != "osdctl/dev" {
+		t.Errorf("userAgent() = %q", got)
+	}
+}
+
+func TestOCMProfile(t *testing.T) {
+	defer viper.Reset()
+	viper.Set(OCMProfilesKey, map[string]interface{}{
+		"stage": map[string]interface{}{
+			"url":           "staging",
+			"client_id":     "stage-client",
+			"client_secret": "stage-secret",
+		},
+		"prod": map[string]interface{}{
+			"url":        "production",
+			"ocm_config": "/tmp/ocm.prod.json",
+		},
+		"broken": map[string]interface{}{
+			"client_id": "no-secret",
+		},
+	})
+
+	if profile, err := getOCMProfile(""); profile != nil || err != nil {
+		t.Errorf("Expected no profile when none is selected, got %v/%v", profile, err)
+	}
+	if _, err := getOCMProfile("missing"); err == nil {
+		t.Errorf("Expected an error for an undefined profile")
+	}
+	if _, err := getOCMProfile("broken"); err == nil {
+		t.Errorf("Expected an error for a profile with a client ID but no client secret")
+	}
+
+	profile, err := getOCMProfile("prod")
+	if err != nil {
+		t.Fatalf("Unexpected error %q", err)
+	}
+	if profile.OCMConfig != "/tmp/ocm.prod.json" {
+		t.Errorf("Expected the profile OCM configuration file, got %q", profile.OCMConfig)
+	}
+	config := &Config{URL: "integration", AccessToken: "asdf", RefreshToken: "fdsa"}
+	applyOCMProfile(config, profile)
+	assertConfigValues(t, config, nil, "production", "asdf", "fdsa")
+
+	profile, err = getOCMProfile("Stage")
+	if err != nil {
+		t.Fatalf("Unexpected error %q", err)
+	}
+	config = &Config{URL: "integration", AccessToken: "asdf", RefreshToken: "fdsa"}
+	applyOCMProfile(config, profile)
+	assertConfigValues(t, config, nil, "staging", "", "")
+	if config.ClientID != "stage-client" || config.ClientSecret != "stage-secret" {
+		t.Errorf("Expected client credentials from the profile, got %q/%q", config.ClientID, config.ClientSecret)
+	}
+
+	viper.Set(OCMClientIDKey, "flag-client")
+	config = &Config{ClientID: "flag-client", ClientSecret: "flag-secret"}
+	applyOCMProfile(config, profile)
+	if config.ClientID != "flag-client" || config.ClientSecret != "flag-secret" {
+		t.Errorf("Expected client credentials from the flags to take precedence, got %q/%q", config.ClientID, config.ClientSecret)
+	}
+}
+
+func TestConfigureTLS(t *testing.T) {
+	tests := []struct {
+		name     string
+		certFile string
+		keyFile  string
+		caFile   string
+		wantErr  bool
+	}{
+		{
+			name: "No TLS settings",
+		},
+		{
+			name:     "Client certificate without a key",
+			certFile: "client.crt",
+			wantErr:  true,
+		},
+		{
+			name:    "Client key without a certificate",
+			keyFile: "client.key",
+			wantErr: true,
+		},
+		{
+			name:     "Unreadable client certificate",
+			certFile: "/nonexistent/client.crt",
+			keyFile:  "/nonexistent/client.key",
+			wantErr:  true,
+		},
+		{
+			name:    "Unreadable CA certificate",
+			caFile:  "/nonexistent/ca.crt",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := configureTLS(sdk.NewConnectionBuilder(), tt.certFile, tt.keyFile, tt.caFile)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("configureTLS() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConnectionPool(t *testing.T) {
+	for _, tt := range []struct {
+		name            string
+		maxIdleConns    int
+		idleConnTimeout time.Duration
+	}{
+		{name: "Negative idle connections", maxIdleConns: -1},
+		{name: "Negative idle timeout", idleConnTimeout: -time.Second},
+	} {
+		if err := configureConnectionPool(sdk.NewConnectionBuilder(), tt.maxIdleConns, tt.idleConnTimeout); err == nil {
+			t.Errorf("%s: configureConnectionPool() should fail", tt.name)
+		}
+	}
+
+	// Zero values keep the defaults of the transport
+	transport := &http.Transport{MaxIdleConns: 100, IdleConnTimeout: time.Minute}
+	connectionPoolWrapper(0, 0)(transport)
+	if transport.MaxIdleConns != 100 || transport.MaxIdleConnsPerHost != 0 || transport.IdleConnTimeout != time.Minute {
+		t.Errorf("zero values changed the transport: %+v", transport)
+	}
+
+	if wrapped := connectionPoolWrapper(50, 90*time.Second)(transport); wrapped != transport {
+		t.Error("the wrapper should return the transport itself")
+	}
+	if transport.MaxIdleConns != 50 || transport.MaxIdleConnsPerHost != 50 || transport.IdleConnTimeout != 90*time.Second {
+		t.Errorf("got MaxIdleConns=%d MaxIdleConnsPerHost=%d IdleConnTimeout=%v, want 50, 50 and 90s",
+			transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.IdleConnTimeout)
+	}
+}
+
+func TestCollectPages(t *testing.T) {
+	// pages returns a fetch function serving total items, counting the requested pages
+	pages := func(total int, requested *int) func(page int, size int) ([]int, int, error) {
+		return func(page int, size int) ([]int, int, error) {
+			*requested++
+			var items []int
+			for i := (page - 1) * size; i < page*size && i < total; i++ {
+				items = append(items, i)
+			}
+			return items, total, nil
+		}
+	}
+
+	tests := []struct {
+		name          string
+		total         int
+		maxResults    int
+		wantItems     int
+		wantTruncated bool
+		wantPages     int
+	}{
+		{name: "single page", total: 3, wantItems: 3, wantPages: 1},
+		{name: "exact pages", total: 20, wantItems: 20, wantPages: 2},
+		{name: "several pages", total: 25, wantItems: 25, wantPages: 3},
+		{name: "no match", total: 0, wantItems: 0, wantPages: 1},
+		{name: "capped", total: 25, maxResults: 12, wantItems: 12, wantTruncated: true, wantPages: 2},
+		{name: "cap above total", total: 25, maxResults: 30, wantItems: 25, wantPages: 3},
+		{name: "cap equals total", total: 20, maxResults: 20, wantItems: 20, wantPages: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requested := 0
+			items, truncated, err := collectPages(10, tt.maxResults, pages(tt.total, &requested))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != tt.wantItems || truncated != tt.wantTruncated || requested != tt.wantPages {
+				t.Errorf("collectPages() = %d items, truncated %v in %d pages, want %d items, truncated %v in %d pages",
+					len(items), truncated, requested, tt.wantItems, tt.wantTruncated, tt.wantPages)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/openshift/osdctl/pkg/printer"
+)
+
+// ProgressTracker renders a single-line "N/M" counter for long running batch operations.
+// It redraws the line in place, so callers must Clear it before writing anything else to
+// the same stream. It is always disabled when the output is plain, see printer.PlainOutput.
+type ProgressTracker struct {
+	out     io.Writer
+	enabled bool
+	action  string
+	total   int
+	current int
+	start   time.Time
+}
+
+func StartProgressTracker(out io.Writer, enabled bool, action string, total int) *ProgressTracker {
+	pt := ProgressTracker{
+		out:     out,
+		enabled: enabled && !printer.PlainOutput(),
+		action:  action,
+		total:   total,
+		start:   time.Now(),
+	}
+	pt.draw()
+	return &pt
+}
+
+// Increment marks one more item as processed and redraws the line
+func (pt *ProgressTracker) Increment() {
+	pt.current++
+	pt.draw()
+}
+
+// Clear erases the progress line so that other output can be written without interleaving
+func (pt *ProgressTracker) Clear() {
+	if pt.enabled {
+		fmt.Fprint(pt.out, "\r\033[K")
+	}
+}
+
+// Redraw repaints the progress line after it was cleared
+func (pt *ProgressTracker) Redraw() {
+	pt.draw()
+}
+
+// End clears the progress line for good
+func (pt *ProgressTracker) End() {
+	pt.Clear()
+	pt.enabled = false
+}
+
+func (pt *ProgressTracker) draw() {
+	if !pt.enabled {
+		return
+	}
+	fmt.Fprintf(pt.out, "\r\033[K%s: %d/%d (elapsed %s)", pt.action, pt.current, pt.total, time.Since(pt.start).Round(time.Second))
+}
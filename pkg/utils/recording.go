@@ -0,0 +1,292 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	"github.com/spf13/viper"
+)
+
+const (
+	// OCMRecordDirKey is a directory where every OCM API response is saved, to be replayed with OCMReplayDirKey
+	OCMRecordDirKey = "ocm_record_dir"
+	// OCMReplayDirKey is a directory of responses saved with OCMRecordDirKey, they are served back in order
+	// instead of talking to OCM
+	OCMReplayDirKey = "ocm_replay_dir"
+
+	// recordedAPIPrefix selects the requests which are recorded. Token requests are never recorded, so that
+	// recordings don't contain credentials.
+	recordedAPIPrefix = "/api/"
+)
+
+// interaction is an OCM API request and its response, as saved in a recording directory
+type interaction struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query,omitempty"`
+	// RequestBody is only informative, replayed requests are matched on their method, path and query
+	RequestBody string `json:"request_body,omitempty"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body"`
+}
+
+func (i *interaction) request() string {
+	if i.Query == "" {
+		return i.Method + " " + i.Path
+	}
+	return i.Method + " " + i.Path + "?" + i.Query
+}
+
+// interactionFile returns the file of the interaction with the index, the names sort in the order of the requests
+func interactionFile(dir string, index int) string {
+	return filepath.Join(dir, fmt.Sprintf("%04d.json", index))
+}
+
+// recordingTransport saves the OCM API responses going through it, in order
+type recordingTransport struct {
+	dir     string
+	wrapped http.RoundTripper
+
+	lock  sync.Mutex
+	count int
+}
+
+// recordWrapper returns an OCM connection transport wrapper saving every API response to the directory. The
+// directory must not contain a recording already, as replays start from its first interaction.
+func recordWrapper(dir string) (func(http.RoundTripper) http.RoundTripper, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("cannot create the recording directory %s: %w", dir, err)
+	}
+	if _, err := os.Stat(interactionFile(dir, 1)); err == nil {
+		return nil, fmt.Errorf("%s already contains a recording, record to an empty directory", dir)
+	}
+	return func(wrapped http.RoundTripper) http.RoundTripper {
+		return &recordingTransport{dir: dir, wrapped: wrapped}
+	}, nil
+}
+
+func (t *recordingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if !strings.HasPrefix(request.URL.Path, recordedAPIPrefix) {
+		return t.wrapped.RoundTrip(request)
+	}
+
+	var requestBody []byte
+	if request.Body != nil {
+		var err error
+		if requestBody, err = io.ReadAll(request.Body); err != nil {
+			return nil, err
+		}
+		request.Body = io.NopCloser(bytes.NewReader(requestBody))
+	}
+	response, err := t.wrapped.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(body))
+
+	recorded := interaction{
+		Method:      request.Method,
+		Path:        request.URL.Path,
+		Query:       request.URL.RawQuery,
+		RequestBody: string(requestBody),
+		Status:      response.StatusCode,
+		ContentType: response.Header.Get("Content-Type"),
+		Body:        string(body),
+	}
+	if err := t.save(&recorded); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot record the response to %s: %v\n", recorded.request(), err)
+	}
+	return response, nil
+}
+
+func (t *recordingTransport) save(recorded *interaction) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	data, err := json.MarshalIndent(recorded, "", "  ")
+	if err != nil {
+		return err
+	}
+	t.count++
+	return os.WriteFile(interactionFile(t.dir, t.count), append(data, '\n'), 0600)
+}
+
+// replayTransport serves the interactions of a recording in order, it never talks to OCM
+type replayTransport struct {
+	dir string
+
+	lock         sync.Mutex
+	interactions []interaction
+	next         int
+}
+
+// newReplayTransport loads the recording of the directory
+func newReplayTransport(dir string) (*replayTransport, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "[0-9][0-9][0-9][0-9].json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("%s doesn't contain a recording", dir)
+	}
+	sort.Strings(paths)
+
+	t := &replayTransport{dir: dir}
+	for _, path := range paths {
+		data, err := os.ReadFile(filepath.Clean(path))
+		if err != nil {
+			return nil, err
+		}
+		var recorded interaction
+		if err := json.Unmarshal(data, &recorded); err != nil {
+			return nil, fmt.Errorf("cannot parse the recorded response %s: %w", path, err)
+		}
+		t.interactions = append(t.interactions, recorded)
+	}
+	return t, nil
+}
+
+func (t *replayTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	actual := interaction{Method: request.Method, Path: request.URL.Path, Query: request.URL.RawQuery}
+	if t.next >= len(t.interactions) {
+		return nil, fmt.Errorf("the recording of %s has no response left for %s", t.dir, actual.request())
+	}
+	recorded := t.interactions[t.next]
+	if recorded.request() != actual.request() {
+		return nil, fmt.Errorf("the command sent %s, but the recording of %s expects %s as request %d", actual.request(), t.dir, recorded.request(), t.next+1)
+	}
+	t.next++
+
+	header := http.Header{}
+	if recorded.ContentType != "" {
+		header.Set("Content-Type", recorded.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       request,
+	}, nil
+}
+
+// replayToken is an access token which never expires, so that replaying doesn't need OCM credentials.
+// It isn't signed, it is never sent outside of osdctl.
+var replayToken = "" +
+	base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`)) + "." +
+	base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"Bearer","sub":"osdctl-replay"}`)) + "."
+
+// createReplayConnection returns a connection serving the recording of the directory, without OCM credentials
+func createReplayConnection(dir string, opts connectionOptions) (*sdk.Connection, error) {
+	if viper.GetString(OCMRecordDirKey) != "" {
+		return nil, errors.New("responses cannot be recorded and replayed at once")
+	}
+	transport, err := newReplayTransport(dir)
+	if err != nil {
+		return nil, err
+	}
+	connectionBuilder := sdk.NewConnectionBuilder()
+	opts.apply(connectionBuilder)
+	connection, err := connectionBuilder.
+		URL(productionURL).
+		Tokens(replayToken).
+		TransportWrapper(BodyCaptureWrapper).
+		TransportWrapper(func(http.RoundTripper) http.RoundTripper { return transport }).
+		Agent(userAgent(Version, viper.GetString(OCMCommandPathKey))).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the replay connection: %v", err)
+	}
+	return connection, nil
+}
+
+// BodyCapture holds the bodies of the last API request sent with a context returned by WithBodyCapture, as
+// they were sent and received, for the requests whose typed response doesn't keep its body
+type BodyCapture struct {
+	lock     sync.Mutex
+	request  []byte
+	response []byte
+}
+
+type bodyCaptureKey struct{}
+
+// WithBodyCapture returns a context capturing the bodies of the API requests sent with it through a connection
+// of CreateConnection. Every attempt replaces the bodies, the capture holds those of the last one.
+func WithBodyCapture(ctx context.Context) (context.Context, *BodyCapture) {
+	capture := &BodyCapture{}
+	return context.WithValue(ctx, bodyCaptureKey{}, capture), capture
+}
+
+// Bodies returns the captured request and response bodies, nil when nothing was captured
+func (c *BodyCapture) Bodies() (request []byte, response []byte) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.request, c.response
+}
+
+// BodyCaptureWrapper is an OCM connection transport wrapper filling the captures of the request contexts
+func BodyCaptureWrapper(wrapped http.RoundTripper) http.RoundTripper {
+	return &bodyCaptureTransport{wrapped: wrapped}
+}
+
+type bodyCaptureTransport struct {
+	wrapped http.RoundTripper
+}
+
+func (t *bodyCaptureTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	capture, ok := request.Context().Value(bodyCaptureKey{}).(*BodyCapture)
+	if !ok || !strings.HasPrefix(request.URL.Path, recordedAPIPrefix) {
+		return t.wrapped.RoundTrip(request)
+	}
+
+	var requestBody []byte
+	if request.Body != nil {
+		var err error
+		if requestBody, err = io.ReadAll(request.Body); err != nil {
+			return nil, err
+		}
+		request.Body = io.NopCloser(bytes.NewReader(requestBody))
+	}
+	capture.lock.Lock()
+	capture.request, capture.response = requestBody, nil
+	capture.lock.Unlock()
+
+	response, err := t.wrapped.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(body))
+	capture.lock.Lock()
+	capture.response = body
+	capture.lock.Unlock()
+	return response, nil
+}
@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/auth/token" {
+			_, _ = w.Write([]byte(`{"access_token":"secret"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"kind":"Cluster","id":"abc","name":"my-cluster"}`))
+	}))
+	defer server.Close()
+
+	dir := filepath.Join(t.TempDir(), "recording")
+	wrapper, err := recordWrapper(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: wrapper(http.DefaultTransport)}
+	for _, path := range []string{"/auth/token", "/api/clusters_mgmt/v1/clusters/abc?fetchAccounts=true"} {
+		response, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = response.Body.Close()
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("recorded %v, want only the API request", files)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("the recording contains the token response: %s", data)
+	}
+	if _, err := recordWrapper(dir); err == nil {
+		t.Errorf("recordWrapper() expected an error for a directory with a recording")
+	}
+
+	viper.Set(OCMReplayDirKey, dir)
+	defer viper.Set(OCMReplayDirKey, "")
+	connection, err := CreateConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer connection.Close()
+
+	response, err := connection.ClustersMgmt().V1().Clusters().Cluster("abc").Get().Parameter("fetchAccounts", true).Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := response.Body().Name(); name != "my-cluster" {
+		t.Errorf("replayed cluster name = %q, want my-cluster", name)
+	}
+
+	// The recording has no response left
+	if _, err := connection.ClustersMgmt().V1().Clusters().Cluster("abc").Get().Send(); err == nil || !strings.Contains(err.Error(), "no response left") {
+		t.Errorf("replaying past the recording returned %v", err)
+	}
+}
+
+func TestReplayMismatch(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(interactionFile(dir, 1), []byte(`{"method":"GET","path":"/api/clusters_mgmt/v1/clusters/abc","status":200,"body":"{}"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	transport, err := newReplayTransport(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := httptest.NewRequest(http.MethodDelete, "https://api.openshift.com/api/clusters_mgmt/v1/clusters/abc", nil)
+	if _, err := transport.RoundTrip(request); err == nil || !strings.Contains(err.Error(), "expects GET /api/clusters_mgmt/v1/clusters/abc") {
+		t.Errorf("RoundTrip() = %v, want a mismatch error", err)
+	}
+}
+
+func TestBodyCaptureWrapper(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// The reply isn't formatted the way the SDK would marshal it back
+		_, _ = w.Write([]byte(`{ "kind": "LimitedSupportReason", "id": "reason-1", "unknown": true }`))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: BodyCaptureWrapper(http.DefaultTransport)}
+	ctx, capture := WithBodyCapture(context.Background())
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/api/clusters_mgmt/v1/clusters/abc/limited_support_reasons", strings.NewReader(`{"summary":"Summary"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent, received := capture.Bodies()
+	if string(sent) != `{"summary":"Summary"}` {
+		t.Errorf("captured request = %q, want the body sent", sent)
+	}
+	if string(received) != string(body) || !strings.Contains(string(received), `"unknown": true`) {
+		t.Errorf("captured response = %q, want the body received %q", received, body)
+	}
+
+	// Requests without a capture go through untouched
+	response, err = client.Get(server.URL + "/api/clusters_mgmt/v1/clusters/abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = response.Body.Close()
+	if again, _ := capture.Bodies(); string(again) != string(sent) {
+		t.Errorf("captured request = %q after a request without a capture", again)
+	}
+}
@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxRetryAfter caps the pause requested by OCM, so a bogus Retry-After cannot stall osdctl forever
+const maxRetryAfter = 5 * time.Minute
+
+// retryAfterGate is shared by every request sent through an OCM connection. When OCM answers 429 Too Many
+// Requests to any of them, the gate holds back all the requests, including the ones sent by other
+// goroutines, until the Retry-After period is over. The held back requests resume at random times spread over
+// the jitter fraction of the period after it, instead of all hitting OCM again at once.
+type retryAfterGate struct {
+	mu     sync.Mutex
+	until  time.Time
+	period time.Duration
+	jitter float64
+	random func() float64
+	out    io.Writer
+}
+
+// pause closes the gate for d, unless it is already closed for longer
+func (g *retryAfterGate) pause(d time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	until := time.Now().Add(d)
+	if until.After(g.until) {
+		g.until = until
+		g.period = d
+		fmt.Fprintf(g.out, "OCM is rate limiting requests, pausing for %s\n", d.Round(time.Second))
+	}
+}
+
+// resumeDelay returns how long a request sent at now is held back, zero when the gate is open
+func (g *retryAfterGate) resumeDelay(now time.Time) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delay := g.until.Sub(now)
+	if delay <= 0 {
+		return 0
+	}
+	jitter := math.Min(math.Max(g.jitter, 0), 1)
+	return delay + time.Duration(float64(g.period)*jitter*g.random())
+}
+
+// wait blocks until the gate is open, and the request's jittered resume time passed, or the request is cancelled
+func (g *retryAfterGate) wait(request *http.Request) error {
+	delay := g.resumeDelay(time.Now())
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-request.Context().Done():
+		return request.Context().Err()
+	}
+}
+
+// retryAfterWrapper returns an OCM connection transport wrapper sharing a single retryAfterGate, which spreads
+// the requests it held back over the jitter fraction of the Retry-After period
+func retryAfterWrapper(out io.Writer, jitter float64) func(http.RoundTripper) http.RoundTripper {
+	gate := &retryAfterGate{out: out, jitter: jitter, random: rand.Float64}
+	return func(wrapped http.RoundTripper) http.RoundTripper {
+		return &retryAfterTransport{gate: gate, wrapped: wrapped}
+	}
+}
+
+type retryAfterTransport struct {
+	gate    *retryAfterGate
+	wrapped http.RoundTripper
+}
+
+func (t *retryAfterTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if err := t.gate.wait(request); err != nil {
+		return nil, err
+	}
+
+	response, err := t.wrapped.RoundTrip(request)
+	if err == nil && response.StatusCode == http.StatusTooManyRequests {
+		if d, ok := parseRetryAfter(response.Header.Get("Retry-After"), time.Now()); ok {
+			t.gate.pause(d)
+		}
+	}
+	return response, err
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which is either a number of seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	var d time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		d = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		d = date.Sub(now)
+	} else {
+		return 0, false
+	}
+
+	if d <= 0 {
+		return 0, false
+	}
+	if d > maxRetryAfter {
+		d = maxRetryAfter
+	}
+	return d, true
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOk bool
+	}{
+		{name: "Missing", value: ""},
+		{name: "Seconds", value: "30", want: 30 * time.Second, wantOk: true},
+		{name: "HTTP date", value: now.Add(time.Minute).Format(http.TimeFormat), want: time.Minute, wantOk: true},
+		{name: "Date in the past", value: now.Add(-time.Minute).Format(http.TimeFormat)},
+		{name: "Capped", value: "86400", want: maxRetryAfter, wantOk: true},
+		{name: "Invalid", value: "soon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value, now)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+func TestRetryAfterTransportPausesEveryRequest(t *testing.T) {
+	wrapper := retryAfterWrapper(io.Discard, 0)
+	throttled := wrapper(roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"1"}}}, nil
+	})).(*retryAfterTransport)
+
+	request, err := http.NewRequest(http.MethodGet, "https://api.openshift.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := throttled.RoundTrip(request); err != nil {
+		t.Fatal(err)
+	}
+
+	// Any other request sent through the connection waits for the Retry-After period
+	other := &retryAfterTransport{gate: throttled.gate, wrapped: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	})}
+	start := time.Now()
+	if _, err := other.RoundTrip(request); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Errorf("expected the request to wait for the Retry-After period, it was sent after %s", elapsed)
+	}
+}
+
+func TestRetryAfterGateSpreadsResumes(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		jitter float64
+		random float64
+		want   time.Duration
+	}{
+		{name: "No jitter", jitter: 0, random: 0.5, want: 10 * time.Second},
+		{name: "Full jitter", jitter: 1, random: 0.5, want: 15 * time.Second},
+		{name: "Partial jitter", jitter: 0.5, random: 0.5, want: 12500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gate := &retryAfterGate{
+				until:  now.Add(10 * time.Second),
+				period: 10 * time.Second,
+				jitter: tt.jitter,
+				random: func() float64 { return tt.random },
+			}
+			if got := gate.resumeDelay(now); got != tt.want {
+				t.Errorf("resumeDelay() = %v, want %v", got, tt.want)
+			}
+			// Requests sent once the period is over aren't held back
+			if got := gate.resumeDelay(now.Add(time.Minute)); got != 0 {
+				t.Errorf("resumeDelay() after the period = %v, want 0", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Usage telemetry is strictly opt-in: nothing is recorded unless TelemetryEnabledKey is set to true in the
+// osdctl configuration file. Only the path of the command being run (eg. "osdctl cluster support post"), the
+// names of the flags set on the command line (eg. "--dry-run") and the osdctl version are collected. Flag values,
+// arguments, cluster identifiers, user names and host names are never collected.
+const (
+	// TelemetryEnabledKey opts in to counting which commands and flags are used, it is off by default
+	TelemetryEnabledKey = "telemetry_enabled"
+	// TelemetryFileKey changes where the usage counters are written, ~/.config/osdctl-usage.json by default
+	TelemetryFileKey = "telemetry_file"
+	// TelemetryEndpointKey is an optional URL every usage event is also sent to, as JSON
+	TelemetryEndpointKey = "telemetry_endpoint"
+
+	telemetryFileName = "osdctl-usage.json"
+	// telemetryTimeout bounds how long a command can be delayed by sending its usage event
+	telemetryTimeout = 2 * time.Second
+)
+
+// UsageEvent is the anonymous record of a command being run, it is sent to TelemetryEndpointKey as is
+type UsageEvent struct {
+	Command string   `json:"command"`
+	Flags   []string `json:"flags,omitempty"`
+	Version string   `json:"version"`
+}
+
+// usageCounters is the content of the local usage file
+type usageCounters struct {
+	// Commands counts the runs of every command
+	Commands map[string]int `json:"commands"`
+	// Flags counts the runs of every command with a flag, keyed by "<command> --<flag>"
+	Flags map[string]int `json:"flags"`
+}
+
+// NewUsageEvent returns the usage event of the command, with the names of the flags set on the command line
+func NewUsageEvent(cmd *cobra.Command) UsageEvent {
+	event := UsageEvent{Command: cmd.CommandPath(), Version: Version}
+	if event.Version == "" {
+		event.Version = "dev"
+	}
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		event.Flags = append(event.Flags, "--"+flag.Name)
+	})
+	sort.Strings(event.Flags)
+	return event
+}
+
+// RecordUsage counts the command in the local usage file and sends it to the telemetry endpoint, if any, when
+// telemetry was opted in to. Telemetry must never get in the way of the command, failures are ignored.
+func RecordUsage(cmd *cobra.Command) {
+	if !viper.GetBool(TelemetryEnabledKey) {
+		return
+	}
+	event := NewUsageEvent(cmd)
+
+	if path, err := telemetryFilePath(); err == nil {
+		_ = countUsage(path, event)
+	}
+	if endpoint := viper.GetString(TelemetryEndpointKey); endpoint != "" {
+		_ = sendUsage(&http.Client{Timeout: telemetryTimeout}, endpoint, event)
+	}
+}
+
+func telemetryFilePath() (string, error) {
+	if path := viper.GetString(TelemetryFileKey); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", telemetryFileName), nil
+}
+
+// countUsage adds the event to the counters of the usage file
+func countUsage(path string, event UsageEvent) error {
+	counters := usageCounters{}
+	if data, err := os.ReadFile(filepath.Clean(path)); err == nil {
+		// A corrupted file is started over
+		_ = json.Unmarshal(data, &counters)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if counters.Commands == nil {
+		counters.Commands = map[string]int{}
+	}
+	if counters.Flags == nil {
+		counters.Flags = map[string]int{}
+	}
+
+	counters.Commands[event.Command]++
+	for _, flag := range event.Flags {
+		counters.Flags[event.Command+" "+flag]++
+	}
+
+	data, err := json.MarshalIndent(counters, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0600)
+}
+
+// sendUsage posts the event to the telemetry endpoint
+func sendUsage(client *http.Client, endpoint string, event UsageEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	response, err := client.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("the telemetry endpoint replied with HTTP status %d", response.StatusCode)
+	}
+	return nil
+}
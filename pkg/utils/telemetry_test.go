@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestNewUsageEvent(t *testing.T) {
+	root := &cobra.Command{Use: "osdctl"}
+	root.PersistentFlags().Bool("skip-version-check", false, "")
+	post := &cobra.Command{Use: "post", Run: func(*cobra.Command, []string) {}}
+	post.Flags().String("template", "", "")
+	post.Flags().Bool("dry-run", false, "")
+	root.AddCommand(post)
+
+	var event UsageEvent
+	post.PreRun = func(cmd *cobra.Command, _ []string) { event = NewUsageEvent(cmd) }
+	root.SetArgs([]string{"post", "--template", "secret.json", "--dry-run", "--skip-version-check", "cluster-id"})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only flag names are collected, not their values nor the arguments
+	want := UsageEvent{Command: "osdctl post", Flags: []string{"--dry-run", "--skip-version-check", "--template"}, Version: "dev"}
+	if !reflect.DeepEqual(event, want) {
+		t.Errorf("NewUsageEvent() = %+v, want %+v", event, want)
+	}
+}
+
+func TestRecordUsage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	var received []UsageEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var event UsageEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Errorf("the endpoint received %q: %v", body, err)
+		}
+		received = append(received, event)
+	}))
+	defer server.Close()
+
+	viper.Set(TelemetryFileKey, path)
+	viper.Set(TelemetryEndpointKey, server.URL)
+	defer viper.Set(TelemetryFileKey, "")
+	defer viper.Set(TelemetryEndpointKey, "")
+	cmd := &cobra.Command{Use: "osdctl"}
+	cmd.Flags().Bool("dry-run", false, "")
+	_ = cmd.Flags().Set("dry-run", "true")
+
+	// Telemetry is off by default
+	RecordUsage(cmd)
+	if _, err := os.Stat(path); !os.IsNotExist(err) || len(received) != 0 {
+		t.Fatalf("usage was recorded without opting in")
+	}
+
+	viper.Set(TelemetryEnabledKey, true)
+	defer viper.Set(TelemetryEnabledKey, false)
+	RecordUsage(cmd)
+	RecordUsage(cmd)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var counters usageCounters
+	if err := json.Unmarshal(data, &counters); err != nil {
+		t.Fatal(err)
+	}
+	want := usageCounters{Commands: map[string]int{"osdctl": 2}, Flags: map[string]int{"osdctl --dry-run": 2}}
+	if !reflect.DeepEqual(counters, want) {
+		t.Errorf("usage counters = %+v, want %+v", counters, want)
+	}
+	if len(received) != 2 || received[0].Command != "osdctl" {
+		t.Errorf("the endpoint received %+v", received)
+	}
+}
@@ -2,9 +2,14 @@ package utils
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	sdk "github.com/openshift-online/ocm-sdk-go"
 	amv1 "github.com/openshift-online/ocm-sdk-go/accountsmgmt/v1"
@@ -61,7 +66,7 @@ func GetCluster(connection *sdk.Connection, key string) (cluster *cmv1.Cluster,
 		Size(1).
 		Send()
 	if err != nil {
-		err = fmt.Errorf("Can't retrieve subscription for key '%s': %v", key, err)
+		err = fmt.Errorf("Can't retrieve subscription for key '%s': %w", key, err)
 		return
 	}
 
@@ -75,7 +80,7 @@ func GetCluster(connection *sdk.Connection, key string) (cluster *cmv1.Cluster,
 				Send()
 			if err != nil {
 				err = fmt.Errorf(
-					"Can't retrieve cluster for key '%s': %v",
+					"Can't retrieve cluster for key '%s': %w",
 					key, err,
 				)
 				return
@@ -108,7 +113,7 @@ func GetCluster(connection *sdk.Connection, key string) (cluster *cmv1.Cluster,
 		Size(1).
 		Send()
 	if err != nil {
-		err = fmt.Errorf("Can't retrieve clusters for key '%s': %v", key, err)
+		err = fmt.Errorf("Can't retrieve clusters for key '%s': %w", key, err)
 		return
 	}
 
@@ -137,17 +142,22 @@ func GetCluster(connection *sdk.Connection, key string) (cluster *cmv1.Cluster,
 }
 
 func GetClusterLimitedSupportReasons(connection *sdk.Connection, clusterID string) ([]*cmv1.LimitedSupportReason, error) {
-	limitedSupportReasons, err := connection.ClustersMgmt().V1().
+	request := connection.ClustersMgmt().V1().
 		Clusters().
 		Cluster(clusterID).
 		LimitedSupportReasons().
-		List().
-		Send()
+		List()
+	items, _, err := collectPages(100, 0, func(page int, size int) ([]*cmv1.LimitedSupportReason, int, error) {
+		response, err := request.Page(page).Size(size).Send()
+		if err != nil {
+			return nil, 0, err
+		}
+		return response.Items().Slice(), response.Total(), nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get limited Support Reasons: %s", err)
 	}
-
-	return limitedSupportReasons.Items().Slice(), nil
+	return items, nil
 }
 
 // GetSubscription Function allows to get a single subscription with any identifier (displayname, ID, internal or external ID)
@@ -246,21 +256,148 @@ func GetRegistryCredentials(connection *sdk.Connection, accountId string) ([]*am
 	return registryCredentials.Items().Slice(), nil
 }
 
-func ConfirmPrompt() bool {
-	fmt.Print("Continue? (y/N): ")
-
-	var response string = "n"
-	_, _ = fmt.Scanln(&response) // Erroneous input will be handled by the default case below
-
-	switch strings.ToLower(response) {
-	case "y", "yes":
-		return true
-	case "n", "no":
-		return false
-	default:
-		fmt.Println("Invalid input. Expecting (y)es or (N)o")
-		return ConfirmPrompt()
+// ErrConfirmTimeout is returned by ConfirmPromptWithTimeout when the prompt isn't answered in time
+var ErrConfirmTimeout = errors.New("timed out waiting for confirmation")
+
+// ConfirmPromptWithTimeout is ConfirmPrompt giving up after timeout, which is treated as a "no".
+// A zero timeout waits forever.
+func ConfirmPromptWithTimeout(timeout time.Duration) (bool, error) {
+	return ConfirmMessagePromptWithTimeout(defaultConfirmMessage, timeout)
+}
+
+// ConfirmMessagePromptWithTimeout is ConfirmPromptWithTimeout asking message instead of the default question
+func ConfirmMessagePromptWithTimeout(message string, timeout time.Duration) (bool, error) {
+	return ConfirmMessagePromptWithTimeoutTo(os.Stdout, message, timeout)
+}
+
+// ConfirmMessagePromptWithTimeoutTo is ConfirmMessagePromptWithTimeout asking on out, eg. stderr when stdout
+// is reserved for a machine readable output. An empty message asks the default question.
+func ConfirmMessagePromptWithTimeoutTo(out io.Writer, message string, timeout time.Duration) (bool, error) {
+	if message == "" {
+		message = defaultConfirmMessage
+	}
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	for {
+		fmt.Fprintf(out, "%s (y/N): ", message)
+		line, err := promptInput().readLine(deadline)
+		if errors.Is(err, ErrConfirmTimeout) {
+			fmt.Fprintln(out)
+			return false, fmt.Errorf("%w after %s", ErrConfirmTimeout, timeout)
+		}
+		// Erroneous input is a "no", like an empty answer
+		response := "n"
+		if fields := strings.Fields(line); err == nil && len(fields) > 0 {
+			response = fields[0]
+		}
+		switch strings.ToLower(response) {
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		default:
+			fmt.Fprintln(out, "Invalid input. Expecting (y)es or (N)o")
+		}
+	}
+}
+
+// stdinLines reads the answers to the prompts from a file, one line at a time. A prompt giving up leaves its
+// read pending, the next prompt waits for it rather than racing it for the next line.
+type stdinLines struct {
+	mu      sync.Mutex
+	file    *os.File
+	lines   chan stdinLine
+	pending bool
+}
+
+type stdinLine struct {
+	line string
+	err  error
+}
+
+var (
+	promptInputMu sync.Mutex
+	promptLines   *stdinLines
+)
+
+// promptInput returns the reader of os.Stdin, which is replaced when os.Stdin is, eg. by the terminal once
+// cluster IDs were read from stdin
+func promptInput() *stdinLines {
+	promptInputMu.Lock()
+	defer promptInputMu.Unlock()
+	if promptLines == nil || promptLines.file != os.Stdin {
+		promptLines = &stdinLines{file: os.Stdin, lines: make(chan stdinLine, 1)}
 	}
+	return promptLines
+}
+
+// readLine returns the next line, without its line ending, or ErrConfirmTimeout once deadline fires.
+// A line typed after the previous prompt gave up is discarded, it doesn't answer this prompt.
+func (s *stdinLines) readLine(deadline <-chan time.Time) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending {
+		select {
+		case <-s.lines:
+			s.pending = false
+		default:
+		}
+	}
+	if !s.pending {
+		s.pending = true
+		go s.read()
+	}
+	select {
+	case read := <-s.lines:
+		s.pending = false
+		return read.line, read.err
+	case <-deadline:
+		return "", ErrConfirmTimeout
+	}
+}
+
+// read reads a line one byte at a time, so that nothing past it is taken from the file
+func (s *stdinLines) read() {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := s.file.Read(b)
+		if n == 1 && b[0] == '\n' {
+			break
+		}
+		if n == 1 {
+			line = append(line, b[0])
+		}
+		if err != nil {
+			if len(line) == 0 {
+				s.lines <- stdinLine{err: err}
+				return
+			}
+			break
+		}
+	}
+	s.lines <- stdinLine{line: strings.TrimSuffix(string(line), "\r")}
+}
+
+const defaultConfirmMessage = "Continue?"
+
+func ConfirmPrompt() bool {
+	return ConfirmMessagePrompt(defaultConfirmMessage)
+}
+
+// ConfirmMessagePrompt is ConfirmPrompt asking message instead of the default question
+func ConfirmMessagePrompt(message string) bool {
+	return ConfirmMessagePromptTo(os.Stdout, message)
+}
+
+// ConfirmMessagePromptTo is ConfirmMessagePrompt asking on out
+func ConfirmMessagePromptTo(out io.Writer, message string) bool {
+	confirmed, _ := ConfirmMessagePromptWithTimeoutTo(out, message, 0)
+	return confirmed
 }
 
 // StreamPrintln appends a newline then prints the given msg using the provided IOStreams
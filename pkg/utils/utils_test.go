@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestConfirmPromptWithTimeout(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+
+	confirmed, err := ConfirmPromptWithTimeout(10 * time.Millisecond)
+	if confirmed || !errors.Is(err, ErrConfirmTimeout) {
+		t.Errorf("ConfirmPromptWithTimeout() = %v, %v, want false, %v", confirmed, err, ErrConfirmTimeout)
+	}
+
+	// The prompt which gave up doesn't take the answer to the next one
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_, _ = w.Write([]byte("y\n"))
+	}()
+	confirmed, err = ConfirmPromptWithTimeout(time.Minute)
+	if !confirmed || err != nil {
+		t.Errorf("ConfirmPromptWithTimeout() after a timeout = %v, %v, want the answer to be confirmed", confirmed, err)
+	}
+}
+
+func TestConfirmPromptDiscardsLateAnswers(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+
+	if _, err := ConfirmPromptWithTimeout(10 * time.Millisecond); !errors.Is(err, ErrConfirmTimeout) {
+		t.Fatalf("ConfirmPromptWithTimeout() = %v, want %v", err, ErrConfirmTimeout)
+	}
+	// The answer typed once the prompt gave up is read before the next prompt
+	if _, err := w.Write([]byte("y\n")); err != nil {
+		t.Fatal(err)
+	}
+	for len(promptInput().lines) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_, _ = w.Write([]byte("n\n"))
+	}()
+	if confirmed, err := ConfirmPromptWithTimeout(time.Minute); confirmed || err != nil {
+		t.Errorf("ConfirmPromptWithTimeout() = %v, %v, want the late answer to be discarded", confirmed, err)
+	}
+}